@@ -23,24 +23,33 @@ const (
 
 	PackageUsing NodeType = "Package Using"
 
+	ImportDeclaration NodeType = "Import Declaration"
+	ImportSpec        NodeType = "Import Spec"
+
 	// General
 	// -------
 	Parameter  NodeType = "Parameter"
 	TypeClause NodeType = "Type Clause"
+	Receiver   NodeType = "Receiver"
 
 	// Statements
 	// ----------
-	BlockStatement      NodeType = "Block Statement"
-	VariableDeclaration NodeType = "Variable Declaration"
-	IfStatement         NodeType = "If Statement"
-	ElseClause          NodeType = "Else Clause"
-	ReturnStatement     NodeType = "Return Statement"
-	ForStatement        NodeType = "For Statement"
-	WhileStatement      NodeType = "While Statement"
-	BreakStatement      NodeType = "Break Statement"
-	ContinueStatement   NodeType = "Continue Statement"
-	FromToStatement     NodeType = "FromTo Statement"
-	ExpressionStatement NodeType = "Expression Statement"
+	BlockStatement       NodeType = "Block Statement"
+	VariableDeclaration  NodeType = "Variable Declaration"
+	CommaOkDeclaration   NodeType = "CommaOk Declaration"
+	IfStatement          NodeType = "If Statement"
+	ElseClause           NodeType = "Else Clause"
+	ReturnStatement      NodeType = "Return Statement"
+	ForStatement         NodeType = "For Statement"
+	WhileStatement       NodeType = "While Statement"
+	BreakStatement       NodeType = "Break Statement"
+	ContinueStatement    NodeType = "Continue Statement"
+	FromToStatement      NodeType = "FromTo Statement"
+	ForRangeStatement    NodeType = "ForRange Statement"
+	RangeArrayStatement  NodeType = "RangeArray Statement"
+	RangeStringStatement NodeType = "RangeString Statement"
+	RangeMapStatement    NodeType = "RangeMap Statement"
+	ExpressionStatement  NodeType = "Expression Statement"
 
 	// Expressions
 	// -----------
@@ -52,20 +61,27 @@ const (
 	PackageCallExpression          NodeType = "PackageCall Expression"
 	UnaryExpression                NodeType = "Unary Expression"
 	BinaryExpression               NodeType = "Binary Expression"
+	LogicalExpression              NodeType = "Logical Expression"
 	VariableEditorExpression       NodeType = "VariableEditor Expression"
 	TypeCallExpression             NodeType = "TypeCall Expression"
 	ClassFieldAccessExpression     NodeType = "ClassFieldAccess Expression"
 	ClassFieldAssignmentExpression NodeType = "ClassFieldAssignment Expression"
 	ArrayAccessExpression          NodeType = "ArrayAccess Expression"
 	ArrayAssignmentExpression      NodeType = "ArrayAssignment Expression"
+	SliceExpression                NodeType = "Slice Expression"
 	MakeExpression                 NodeType = "Make Expression"
 	MakeArrayExpression            NodeType = "MakeArray Expression"
 	ReferenceExpression            NodeType = "Reference Expression"
 	DereferenceExpression          NodeType = "Dereference Expression "
 
 	MakeStructExpression NodeType = "MakeStruct Expression"
+	MakeMapExpression    NodeType = "MakeMap Expression"
 
 	ThisExpression NodeType = "This Expression"
+
+	InterpolatedStringExpression NodeType = "InterpolatedString Expression"
+
+	MissingExpression NodeType = "Missing Expression"
 )
 
 type Node interface {
@@ -99,7 +115,7 @@ type TypeCallExpressionNode struct {
 func (TypeCallExpressionNode) NodeType() NodeType { return TypeCallExpression }
 
 func (node TypeCallExpressionNode) Span() print2.TextSpan {
-	closingSpan := node.ClosingToken.Span
+	closingSpan := node.ClosingToken.Span()
 	baseSpan := node.Base.Span()
 	return baseSpan.SpanBetween(closingSpan)
 }
@@ -137,7 +153,7 @@ type LiteralExpressionNode struct {
 func (LiteralExpressionNode) NodeType() NodeType { return LiteralExpression }
 
 func (node LiteralExpressionNode) Span() print2.TextSpan {
-	return node.LiteralToken.Span
+	return node.LiteralToken.Span()
 }
 
 func (node LiteralExpressionNode) Print(indent string) {
@@ -161,6 +177,102 @@ func CreateNativeLiteralExpressionNode(tok token.Token) LiteralExpressionNode {
 	}
 }
 
+// interpolated string node
+
+// "user ${name} has ${count} items" is made of the literal parts
+// "user ", " has ", " items" and the expressions name and count,
+// there's always one more part than there are expressions
+type InterpolatedStringExpressionNode struct {
+	Expression
+	Parts       []token.Token // INTERPOLATION_START, INTERPOLATION_MIDDLE..., INTERPOLATION_END
+	Expressions []Expression
+}
+
+func (InterpolatedStringExpressionNode) NodeType() NodeType { return InterpolatedStringExpression }
+
+func (node InterpolatedStringExpressionNode) Span() print2.TextSpan {
+	return node.Parts[0].Span().SpanBetween(node.Parts[len(node.Parts)-1].Span())
+}
+
+func (node InterpolatedStringExpressionNode) Print(indent string) {
+	print2.PrintC(print2.Green, indent+"└ InterpolatedStringExpressionNode")
+	fmt.Println(indent + "  └ Parts: ")
+	for i, part := range node.Parts {
+		fmt.Printf("%s    └ %q\n", indent, part.RealValue)
+		if i < len(node.Expressions) {
+			node.Expressions[i].Print(indent + "    ")
+		}
+	}
+}
+
+// Lower rewrites the interpolated string into a concatenation,
+// every expression is cast to a string: "a ${b} c" -> "a " + string(b) + " c".
+// The binder checks that the expressions can be converted and turns casts of structs into calls
+func (node InterpolatedStringExpressionNode) Lower() Expression {
+	var result Expression = createStringPart(node.Parts[0])
+
+	for i, expression := range node.Expressions {
+		part := node.Parts[i+1]
+
+		cast := CreateCallExpressionNode(
+			token.CreateTokenSpaced("string", token.IDENT, false, part.Pos, part.Pos),
+			[]Expression{expression},
+			TypeClauseNode{},
+			token.CreateTokenSpaced(")", token.RPAREN, false, part.Pos, part.Pos),
+		)
+
+		plus := token.CreateTokenSpaced("+", token.ADD, false, part.Pos, part.Pos)
+		result = CreateBinaryExpressionNode(plus, result, cast)
+
+		// empty parts don't need to be concatenated
+		if part.RealValue != "" {
+			result = CreateBinaryExpressionNode(plus, result, createStringPart(part))
+		}
+	}
+
+	return result
+}
+
+// createStringPart turns a literal part of an interpolated string into a string literal
+func createStringPart(part token.Token) LiteralExpressionNode {
+	str := token.CreateTokenSpaced(part.Literal, token.STRING, part.SpaceAfter, part.Pos, part.End)
+	str.RealValue = part.RealValue
+	return CreateLiteralExpressionNode(str)
+}
+
+func CreateInterpolatedStringExpressionNode(parts []token.Token, expressions []Expression) InterpolatedStringExpressionNode {
+	return InterpolatedStringExpressionNode{
+		Parts:       parts,
+		Expressions: expressions,
+	}
+}
+
+// missing expression node
+
+// stands in for an expression that couldn't be parsed,
+// the parser reports the error and carries on with this
+type MissingExpressionNode struct {
+	Expression
+	Token token.Token // what we found instead of an expression
+}
+
+func (MissingExpressionNode) NodeType() NodeType { return MissingExpression }
+
+func (node MissingExpressionNode) Span() print2.TextSpan {
+	return node.Token.Span()
+}
+
+func (node MissingExpressionNode) Print(indent string) {
+	print2.PrintC(print2.Red, indent+"└ MissingExpressionNode")
+	fmt.Printf("%s  └ Token: %s\n", indent, node.Token.Type)
+}
+
+func CreateMissingExpressionNode(tok token.Token) MissingExpressionNode {
+	return MissingExpressionNode{
+		Token: tok,
+	}
+}
+
 // parathesised expression node
 
 type ParanthesisedExpressionNode struct {
@@ -173,7 +285,7 @@ type ParanthesisedExpressionNode struct {
 func (ParanthesisedExpressionNode) NodeType() NodeType { return ParenthesisedExpression }
 
 func (node ParanthesisedExpressionNode) Span() print2.TextSpan {
-	return node.OpenParenthesis.Span.SpanBetween(node.ClosedParenthesis.Span)
+	return node.OpenParenthesis.Span().SpanBetween(node.ClosedParenthesis.Span())
 }
 
 func (node ParanthesisedExpressionNode) Print(indent string) {
@@ -202,7 +314,7 @@ type NameExpressNode struct {
 func (NameExpressNode) NodeType() NodeType { return NameExpression }
 
 func (node NameExpressNode) Span() print2.TextSpan {
-	return node.Identifier.Span
+	return node.Identifier.Span()
 }
 
 func (node NameExpressNode) Print(indent string) {
@@ -281,6 +393,41 @@ func CreateBinaryExpressionNode(op token.Token, left Expression, right Expressio
 	}
 }
 
+// logical expression
+
+// a && b and a || b, unlike other binary expressions
+// the right side is only evaluated if the left side doesn't decide the result
+type LogicalExpressionNode struct {
+	Expression
+
+	Left     Expression
+	Operator token.Token // LAND or LOR
+	Right    Expression
+}
+
+func (LogicalExpressionNode) NodeType() NodeType { return LogicalExpression }
+
+func (node LogicalExpressionNode) Span() print2.TextSpan {
+	return node.Left.Span().SpanBetween(node.Right.Span())
+}
+
+func (node LogicalExpressionNode) Print(indent string) {
+	print2.PrintC(print2.Green, indent+"└ LogicalExpressionNode")
+	fmt.Printf("%s  └ Operator: %s\n", indent, node.Operator.Type)
+	fmt.Println(indent + "  └ Left: ")
+	node.Left.Print(indent + "    ")
+	fmt.Println(indent + "  └ Right: ")
+	node.Right.Print(indent + "    ")
+}
+
+func CreateLogicalExpressionNode(op token.Token, left Expression, right Expression) LogicalExpressionNode {
+	return LogicalExpressionNode{
+		Left:     left,
+		Operator: op,
+		Right:    right,
+	}
+}
+
 // global
 
 type GlobalStatementMember struct {
@@ -311,12 +458,30 @@ type TypeClauseNode struct {
 	TypeIdentifier token.Token
 	SubClauses     []TypeClauseNode
 	ClosingBracket token.Token
+
+	// the N of [N]T, empty for everything else
+	Length token.Token
 }
 
 func (TypeClauseNode) NodeType() NodeType { return TypeClause }
 
+// IsSlice checks if this is a []T clause
+func (node TypeClauseNode) IsSlice() bool {
+	return node.TypeIdentifier.RealValue == "slice" && node.TypeIdentifier.Literal == "[]"
+}
+
+// IsArray checks if this is a [N]T clause
+func (node TypeClauseNode) IsArray() bool {
+	return node.Length.Type == token.INT
+}
+
+// IsMap checks if this is a map[K]V clause
+func (node TypeClauseNode) IsMap() bool {
+	return node.TypeIdentifier.RealValue == "map" && len(node.SubClauses) == 2
+}
+
 func (node TypeClauseNode) Span() print2.TextSpan {
-	return node.TypeIdentifier.Span
+	return node.TypeIdentifier.Span()
 
 }
 
@@ -342,6 +507,26 @@ func CreateTypeClauseNode(pack *token.Token, id token.Token, subtypes []TypeClau
 	}
 }
 
+// CreateSliceTypeClauseNode creates the clause of []T, it's named "slice" with T as its sub clause
+func CreateSliceTypeClauseNode(opening token.Token, closing token.Token, element TypeClauseNode) TypeClauseNode {
+	id := token.CreateTokenReal("[]", "slice", token.IDENT, opening.Pos, closing.End)
+	return CreateTypeClauseNode(nil, id, []TypeClauseNode{element}, closing)
+}
+
+// CreateArrayTypeClauseNode creates the clause of [N]T, it's named "array" with T as its sub clause
+func CreateArrayTypeClauseNode(opening token.Token, length token.Token, closing token.Token, element TypeClauseNode) TypeClauseNode {
+	id := token.CreateTokenReal("["+length.Literal+"]", "array", token.IDENT, opening.Pos, closing.End)
+	clause := CreateTypeClauseNode(nil, id, []TypeClauseNode{element}, closing)
+	clause.Length = length
+	return clause
+}
+
+// CreateMapTypeClauseNode creates the clause of map[K]V, it's named "map" with K and V as its sub clauses
+func CreateMapTypeClauseNode(keyword token.Token, key TypeClauseNode, closing token.Token, value TypeClauseNode) TypeClauseNode {
+	id := token.CreateTokenReal(keyword.Literal, "map", token.IDENT, keyword.Pos, keyword.End)
+	return CreateTypeClauseNode(nil, id, []TypeClauseNode{key, value}, closing)
+}
+
 // block statement Node
 
 type BlockStatementNode struct {
@@ -354,7 +539,7 @@ type BlockStatementNode struct {
 func (BlockStatementNode) NodeType() NodeType { return BlockStatement }
 
 func (node BlockStatementNode) Span() print2.TextSpan {
-	return node.OpenBrace.Span.SpanBetween(node.CloseBrace.Span)
+	return node.OpenBrace.Span().SpanBetween(node.CloseBrace.Span())
 }
 
 func (node BlockStatementNode) Print(indent string) {
@@ -387,17 +572,23 @@ type FunctionDeclarationMember struct {
 	TypeClause      TypeClauseNode
 	Body            BlockStatementNode
 	IsPublic        bool
+
+	// the receiver of a method, nil for normal functions
+	Receiver *ReceiverNode
+
+	// doc comments in front of the declaration
+	Doc []token.Token
 }
 
 func (FunctionDeclarationMember) NodeType() NodeType { return FunctionDeclaration }
 
 func (node FunctionDeclarationMember) Span() print2.TextSpan {
-	span := node.FunctionKeyword.Span
+	span := node.FunctionKeyword.Span()
 
 	if node.FunctionKeyword.Type == token.FN {
 		span = span.SpanBetween(node.Body.Span())
 	} else {
-		span = span.SpanBetween(node.Identifier.Span)
+		span = span.SpanBetween(node.Identifier.Span())
 	}
 
 	return span
@@ -407,6 +598,12 @@ func (node FunctionDeclarationMember) Print(indent string) {
 	print2.PrintC(print2.Cyan, indent+"- FunctionDeclarationMember")
 	fmt.Printf("%s  └ Identifier: %s\n", indent, node.Identifier.Type)
 	fmt.Printf("%s  └ IsPublic: %t\n", indent, node.IsPublic)
+
+	if node.Receiver != nil {
+		fmt.Println(indent + "  └ Receiver: ")
+		node.Receiver.Print(indent + "    ")
+	}
+
 	fmt.Println(indent + "  └ Parameters: ")
 
 	for _, param := range node.Parameters {
@@ -426,7 +623,7 @@ func (node FunctionDeclarationMember) Print(indent string) {
 
 }
 
-func CreateFunctionDeclarationMember(kw token.Token, id token.Token, params []ParameterNode, typeClause TypeClauseNode, body BlockStatementNode, public bool) FunctionDeclarationMember {
+func CreateFunctionDeclarationMember(kw token.Token, id token.Token, params []ParameterNode, typeClause TypeClauseNode, body BlockStatementNode, public bool, doc []token.Token) FunctionDeclarationMember {
 	return FunctionDeclarationMember{
 		FunctionKeyword: kw,
 		Identifier:      id,
@@ -434,6 +631,49 @@ func CreateFunctionDeclarationMember(kw token.Token, id token.Token, params []Pa
 		TypeClause:      typeClause,
 		Body:            body,
 		IsPublic:        public,
+		Doc:             doc,
+	}
+}
+
+func CreateMethodDeclarationMember(kw token.Token, receiver ReceiverNode, id token.Token, params []ParameterNode, typeClause TypeClauseNode, body BlockStatementNode, public bool, doc []token.Token) FunctionDeclarationMember {
+	method := CreateFunctionDeclarationMember(kw, id, params, typeClause, body, public, doc)
+	method.Receiver = &receiver
+	return method
+}
+
+// receiver
+
+// the value a method is called on: (p *Point) or (p Point)
+type ReceiverNode struct {
+	Node
+	Identifier token.Token
+	Pointer    token.Token // "*", or empty for a value receiver
+	TypeClause TypeClauseNode
+}
+
+func (ReceiverNode) NodeType() NodeType { return Receiver }
+
+func (node ReceiverNode) Span() print2.TextSpan {
+	return node.Identifier.Span().SpanBetween(node.TypeClause.Span())
+}
+
+func (node ReceiverNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"└ ReceiverNode")
+	fmt.Printf("%s  └ Identifier: %s\n", indent, node.Identifier.Literal)
+	fmt.Printf("%s  └ IsPointer: %t\n", indent, node.IsPointer())
+	node.TypeClause.Print(indent + "    ")
+}
+
+// IsPointer checks if the method gets a pointer to the value (and can change it)
+func (node ReceiverNode) IsPointer() bool {
+	return node.Pointer.Type == token.MUL
+}
+
+func CreateReceiverNode(id token.Token, pointer token.Token, typeClause TypeClauseNode) ReceiverNode {
+	return ReceiverNode{
+		Identifier: id,
+		Pointer:    pointer,
+		TypeClause: typeClause,
 	}
 }
 
@@ -453,7 +693,7 @@ type ExternalFunctionDeclarationMember struct {
 func (ExternalFunctionDeclarationMember) NodeType() NodeType { return ExternalFunctionDeclaration }
 
 func (node ExternalFunctionDeclarationMember) Span() print2.TextSpan {
-	span := node.FunctionKeyword.Span.SpanBetween(node.ClosingToken.Span)
+	span := node.FunctionKeyword.Span().SpanBetween(node.ClosingToken.Span())
 	if node.TypeClause.ClauseIsSet {
 		span = span.SpanBetween(node.TypeClause.Span())
 	}
@@ -499,17 +739,22 @@ type StructDeclarationMember struct {
 	Identifier    token.Token
 	Fields        []ParameterNode
 	ClosingToken  token.Token
+	IsPublic      bool
+
+	// doc comments in front of the declaration
+	Doc []token.Token
 }
 
 func (StructDeclarationMember) NodeType() NodeType { return StructDeclaration }
 
 func (node StructDeclarationMember) Span() print2.TextSpan {
-	return node.StructKeyword.Span.SpanBetween(node.ClosingToken.Span)
+	return node.StructKeyword.Span().SpanBetween(node.ClosingToken.Span())
 }
 
 func (node StructDeclarationMember) Print(indent string) {
 	print2.PrintC(print2.Cyan, indent+"- StructDeclarationMember")
 	fmt.Printf("%s  └ Identifier: %s\n", indent, node.Identifier.Type)
+	fmt.Printf("%s  └ IsPublic: %t\n", indent, node.IsPublic)
 	fmt.Println(indent + "  └ Fields: ")
 
 	for _, param := range node.Fields {
@@ -517,12 +762,14 @@ func (node StructDeclarationMember) Print(indent string) {
 	}
 }
 
-func CreateStructDeclarationMember(kw token.Token, id token.Token, fields []ParameterNode, closing token.Token) StructDeclarationMember {
+func CreateStructDeclarationMember(kw token.Token, id token.Token, fields []ParameterNode, closing token.Token, public bool, doc []token.Token) StructDeclarationMember {
 	return StructDeclarationMember{
 		StructKeyword: kw,
 		Identifier:    id,
 		Fields:        fields,
 		ClosingToken:  closing,
+		IsPublic:      public,
+		Doc:           doc,
 	}
 }
 
@@ -538,7 +785,7 @@ type ParameterNode struct {
 func (ParameterNode) NodeType() NodeType { return Parameter }
 
 func (node ParameterNode) Span() print2.TextSpan {
-	return node.Identifier.Span.SpanBetween(node.TypeClause.Span())
+	return node.Identifier.Span().SpanBetween(node.TypeClause.Span())
 }
 
 func (node ParameterNode) Print(indent string) {
@@ -572,7 +819,7 @@ type PackageReferenceMember struct {
 func (PackageReferenceMember) NodeType() NodeType { return PackageReference }
 
 func (node PackageReferenceMember) Span() print2.TextSpan {
-	return node.PackageKeyword.Span.SpanBetween(node.Package.Span)
+	return node.PackageKeyword.Span().SpanBetween(node.Package.Span())
 
 }
 
@@ -599,7 +846,7 @@ type PackageUseMember struct {
 func (PackageUseMember) NodeType() NodeType { return PackageUsing }
 
 func (node PackageUseMember) Span() print2.TextSpan {
-	return node.PackageKeyword.Span.SpanBetween(node.Package.Span)
+	return node.PackageKeyword.Span().SpanBetween(node.Package.Span())
 }
 
 func (node PackageUseMember) Print(indent string) {
@@ -614,6 +861,80 @@ func CreatePackageUseMember(kw token.Token, pkg token.Token) PackageUseMember {
 	}
 }
 
+// import declaration
+
+// import "math" or a group like import ( m "math"; "io" )
+type ImportDeclarationMember struct {
+	MemberNode
+	ImportKeyword token.Token
+	Imports       []ImportSpecNode
+	ClosingToken  token.Token // ")" of a group, the path otherwise
+}
+
+func (ImportDeclarationMember) NodeType() NodeType { return ImportDeclaration }
+
+func (node ImportDeclarationMember) Span() print2.TextSpan {
+	return node.ImportKeyword.Span().SpanBetween(node.ClosingToken.Span())
+}
+
+func (node ImportDeclarationMember) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- ImportDeclarationMember")
+	fmt.Println(indent + "  └ Imports: ")
+
+	for _, spec := range node.Imports {
+		spec.Print(indent + "    ")
+	}
+}
+
+func CreateImportDeclarationMember(kw token.Token, imports []ImportSpecNode, closing token.Token) ImportDeclarationMember {
+	return ImportDeclarationMember{
+		ImportKeyword: kw,
+		Imports:       imports,
+		ClosingToken:  closing,
+	}
+}
+
+// import spec
+
+// one imported package, with an optional alias: m "math"
+type ImportSpecNode struct {
+	Alias token.Token // IDENT, or empty if there's no alias
+	Path  token.Token // STRING
+}
+
+func (ImportSpecNode) NodeType() NodeType { return ImportSpec }
+
+func (node ImportSpecNode) Span() print2.TextSpan {
+	if node.HasAlias() {
+		return node.Alias.Span().SpanBetween(node.Path.Span())
+	}
+	return node.Path.Span()
+}
+
+func (node ImportSpecNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- ImportSpecNode")
+	fmt.Printf("%s  └ Alias: %s\n", indent, node.Alias.Literal)
+	fmt.Printf("%s  └ Path: %s\n", indent, node.Path.Literal)
+}
+
+// HasAlias checks if the package gets a different name in this file
+func (node ImportSpecNode) HasAlias() bool {
+	return node.Alias.Type == token.IDENT
+}
+
+// ImportPath returns the path of the package without the quotes
+func (node ImportSpecNode) ImportPath() string {
+	path, _ := node.Path.RealValue.(string)
+	return path
+}
+
+func CreateImportSpecNode(alias token.Token, path token.Token) ImportSpecNode {
+	return ImportSpecNode{
+		Alias: alias,
+		Path:  path,
+	}
+}
+
 // variable declaration
 
 type VariableDeclarationStatementNode struct {
@@ -630,7 +951,7 @@ type VariableDeclarationStatementNode struct {
 func (VariableDeclarationStatementNode) NodeType() NodeType { return VariableDeclaration }
 
 func (node VariableDeclarationStatementNode) Span() print2.TextSpan {
-	span := node.Keyword.Span.SpanBetween(node.Identifier.Span)
+	span := node.Keyword.Span().SpanBetween(node.Identifier.Span())
 
 	if node.Initializer != nil {
 		span = span.SpanBetween(node.Initializer.Span())
@@ -657,6 +978,41 @@ func (node VariableDeclarationStatementNode) Print(indent string) {
 	}
 }
 
+// comma ok declaration, v, ok := m[k]
+
+type CommaOkDeclarationStatementNode struct {
+	Statement
+
+	Value  token.Token
+	Ok     token.Token
+	Define token.Token
+
+	Initializer Expression
+}
+
+func (CommaOkDeclarationStatementNode) NodeType() NodeType { return CommaOkDeclaration }
+
+func (node CommaOkDeclarationStatementNode) Span() print2.TextSpan {
+	return node.Value.Span().SpanBetween(node.Initializer.Span())
+}
+
+func (node CommaOkDeclarationStatementNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- CommaOkDeclarationStatementNode")
+	fmt.Printf("%s  └ Value: %s\n", indent, node.Value.Literal)
+	fmt.Printf("%s  └ Ok: %s\n", indent, node.Ok.Literal)
+	fmt.Println(indent + "  └ Initializer: ")
+	node.Initializer.Print(indent + "    ")
+}
+
+func CreateCommaOkDeclarationStatementNode(value token.Token, ok token.Token, define token.Token, initializer Expression) CommaOkDeclarationStatementNode {
+	return CommaOkDeclarationStatementNode{
+		Value:       value,
+		Ok:          ok,
+		Define:      define,
+		Initializer: initializer,
+	}
+}
+
 func CreateVariableDeclarationStatementNode(kw token.Token, typeClause TypeClauseNode, id token.Token, initializer Expression) VariableDeclarationStatementNode {
 	return VariableDeclarationStatementNode{
 		Keyword:     kw,
@@ -682,7 +1038,7 @@ type IfStatementNode struct {
 func (IfStatementNode) NodeType() NodeType { return IfStatement }
 
 func (node IfStatementNode) Span() print2.TextSpan {
-	return node.IfKeyword.Span.SpanBetween(node.ThenStatement.Span().SpanBetween(node.ElseClause.Span()))
+	return node.IfKeyword.Span().SpanBetween(node.ThenStatement.Span().SpanBetween(node.ElseClause.Span()))
 
 }
 
@@ -721,7 +1077,7 @@ func (ElseClauseNode) NodeType() NodeType { return ElseClause }
 
 func (node ElseClauseNode) Span() print2.TextSpan {
 	if node.ClauseIsSet {
-		return node.ElseKeyword.Span.SpanBetween(node.ElseStatement.Span())
+		return node.ElseKeyword.Span().SpanBetween(node.ElseStatement.Span())
 	} else {
 		return print2.TextSpan{}
 	}
@@ -757,7 +1113,7 @@ type ReturnStatementNode struct {
 func (ReturnStatementNode) NodeType() NodeType { return ReturnStatement }
 
 func (node ReturnStatementNode) Span() print2.TextSpan {
-	return node.Keyword.Span.SpanBetween(node.Expression.Span())
+	return node.Keyword.Span().SpanBetween(node.Expression.Span())
 
 }
 
@@ -799,7 +1155,7 @@ type ForStatementNode struct {
 func (ForStatementNode) NodeType() NodeType { return ForStatement }
 
 func (node ForStatementNode) Span() print2.TextSpan {
-	return node.Keyword.Span.SpanBetween(node.Condition.Span())
+	return node.Keyword.Span().SpanBetween(node.StatementNode.Span())
 }
 
 func (node ForStatementNode) Print(indent string) {
@@ -818,16 +1174,16 @@ func (node ForStatementNode) Print(indent string) {
 	node.Updation.Print(indent + "    ")
 	fmt.Println(indent + "  └ Statement: ")
 
-	node.Statement.Print(indent + "    ")
+	node.StatementNode.Print(indent + "    ")
 }
 
 func CreateForStatementNode(keyword token.Token, initializer VariableDeclarationStatementNode, condition Expression, updation Statement, statement Statement) ForStatementNode {
 	return ForStatementNode{
-		Keyword:     keyword,
-		Initializer: initializer,
-		Condition:   condition,
-		Updation:    updation,
-		Statement:   statement,
+		Keyword:       keyword,
+		Initializer:   initializer,
+		Condition:     condition,
+		Updation:      updation,
+		StatementNode: statement,
 	}
 }
 
@@ -843,7 +1199,7 @@ type WhileStatementNode struct {
 func (WhileStatementNode) NodeType() NodeType { return WhileStatement }
 
 func (node WhileStatementNode) Span() print2.TextSpan {
-	return node.Keyword.Span.SpanBetween(node.Statement.Span())
+	return node.Keyword.Span().SpanBetween(node.StatementNode.Span())
 }
 
 func (node WhileStatementNode) Print(indent string) {
@@ -866,6 +1222,323 @@ func CreateWhileStatementNode(keyword token.Token, condition Expression, stateme
 	}
 }
 
+// for range
+
+// for i, v := range x, the parser doesn't know what x is so the binder turns
+// this into one of the range loops below (FromTo, RangeArray, RangeString or RangeMap)
+type ForRangeStatementNode struct {
+	Statement
+	Keyword       token.Token
+	Key           token.Token // not set for "for range x"
+	Value         token.Token // not set for "for range x" and "for i := range x"
+	RangeKeyword  token.Token
+	Expression    Expression
+	StatementNode Statement
+}
+
+func (ForRangeStatementNode) NodeType() NodeType { return ForRangeStatement }
+
+func (node ForRangeStatementNode) Span() print2.TextSpan {
+	return node.Keyword.Span().SpanBetween(node.StatementNode.Span())
+}
+
+func (node ForRangeStatementNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- ForRangeStatementNode")
+	fmt.Printf("%s  └ Key: %s\n", indent, node.Key.Literal)
+	fmt.Printf("%s  └ Value: %s\n", indent, node.Value.Literal)
+
+	fmt.Println(indent + "  └ Expression: ")
+	node.Expression.Print(indent + "    ")
+
+	fmt.Println(indent + "  └ Statement: ")
+	node.StatementNode.Print(indent + "    ")
+}
+
+// HasKey checks if the loop names its key (or index), "_" doesn't count
+func (node ForRangeStatementNode) HasKey() bool {
+	return node.Key.Type == token.IDENT && node.Key.Name() != "_"
+}
+
+// HasValue checks if the loop names its value, "_" doesn't count
+func (node ForRangeStatementNode) HasValue() bool {
+	return node.Value.Type == token.IDENT && node.Value.Name() != "_"
+}
+
+func CreateForRangeStatementNode(keyword token.Token, key token.Token, value token.Token, rangeKeyword token.Token, expression Expression, statement Statement) ForRangeStatementNode {
+	return ForRangeStatementNode{
+		Keyword:       keyword,
+		Key:           key,
+		Value:         value,
+		RangeKeyword:  rangeKeyword,
+		Expression:    expression,
+		StatementNode: statement,
+	}
+}
+
+// from to (for i := range 10)
+
+type FromToStatementNode struct {
+	Statement
+	Range ForRangeStatementNode
+}
+
+func (FromToStatementNode) NodeType() NodeType { return FromToStatement }
+
+func (node FromToStatementNode) Span() print2.TextSpan { return node.Range.Span() }
+
+func (node FromToStatementNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- FromToStatementNode")
+	node.Range.Print(indent + "  ")
+}
+
+// Lower rewrites the loop into a counting for loop. The count is evaluated once and the loop
+// variable is a copy, changing it doesn't change how often the loop runs:
+//
+//	{ var int $to; n; for (var int $i; 0; $i < $to; $i++) { var int i; $i; body } }
+func (node FromToStatementNode) Lower() Statement {
+	at := node.Range.Keyword
+	to := hiddenToken("to", at)
+	counter := hiddenToken("i", at)
+
+	body := make([]Statement, 0)
+	if node.Range.HasKey() {
+		body = append(body, createLoweredDeclaration(intClause(at), node.Range.Key, CreateNameExpressionNode(counter)))
+	}
+
+	loop := createCountingLoop(at, counter, CreateNameExpressionNode(to), append(body, node.Range.StatementNode))
+
+	return createLoweredBlock(at,
+		createLoweredDeclaration(intClause(at), to, node.Range.Expression),
+		loop,
+	)
+}
+
+func CreateFromToStatementNode(rng ForRangeStatementNode) FromToStatementNode {
+	return FromToStatementNode{Range: rng}
+}
+
+// range over an array or a slice
+
+type RangeArrayStatementNode struct {
+	Statement
+	Range ForRangeStatementNode
+}
+
+func (RangeArrayStatementNode) NodeType() NodeType { return RangeArrayStatement }
+
+func (node RangeArrayStatementNode) Span() print2.TextSpan { return node.Range.Span() }
+
+func (node RangeArrayStatementNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- RangeArrayStatementNode")
+	node.Range.Print(indent + "  ")
+}
+
+// Lower rewrites the loop into a counting for loop over the indices, the array (and its length)
+// is evaluated once:
+//
+//	{ var $a; arr; var int $n; len($a); for (var int $i; 0; $i < $n; $i++) { var int i; $i; var v; $a[$i]; body } }
+func (node RangeArrayStatementNode) Lower() Statement {
+	at := node.Range.Keyword
+	array := hiddenToken("a", at)
+	length := hiddenToken("n", at)
+	counter := hiddenToken("i", at)
+
+	body := make([]Statement, 0)
+	if node.Range.HasKey() {
+		body = append(body, createLoweredDeclaration(intClause(at), node.Range.Key, CreateNameExpressionNode(counter)))
+	}
+	if node.Range.HasValue() {
+		element := CreateArrayAccessExpressionNode(CreateNameExpressionNode(array), CreateNameExpressionNode(counter))
+		body = append(body, createLoweredDeclaration(TypeClauseNode{}, node.Range.Value, element))
+	}
+
+	loop := createCountingLoop(at, counter, CreateNameExpressionNode(length), append(body, node.Range.StatementNode))
+
+	return createLoweredBlock(at,
+		createLoweredDeclaration(TypeClauseNode{}, array, node.Range.Expression),
+		createLoweredDeclaration(intClause(at), length, createLoweredCall(at, "len", CreateNameExpressionNode(array))),
+		loop,
+	)
+}
+
+func CreateRangeArrayStatementNode(rng ForRangeStatementNode) RangeArrayStatementNode {
+	return RangeArrayStatementNode{Range: rng}
+}
+
+// range over the runes of a string
+
+type RangeStringStatementNode struct {
+	Statement
+	Range ForRangeStatementNode
+}
+
+func (RangeStringStatementNode) NodeType() NodeType { return RangeStringStatement }
+
+func (node RangeStringStatementNode) Span() print2.TextSpan { return node.Range.Span() }
+
+func (node RangeStringStatementNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- RangeStringStatementNode")
+	node.Range.Print(indent + "  ")
+}
+
+// Lower rewrites the loop into a for loop over the byte offsets of the runes, the offset moves
+// on by the length of the UTF-8 sequence at it (so continue moves on too):
+//
+//	{ var $s; str; var int $n; len($s)
+//	  for (var int $i; 0; $i < $n; $i += $runeLength($s, $i)) { var int i; $i; var int r; $decodeRune($s, $i); body } }
+func (node RangeStringStatementNode) Lower() Statement {
+	at := node.Range.Keyword
+	str := hiddenToken("s", at)
+	length := hiddenToken("n", at)
+	counter := hiddenToken("i", at)
+
+	body := make([]Statement, 0)
+	if node.Range.HasKey() {
+		body = append(body, createLoweredDeclaration(intClause(at), node.Range.Key, CreateNameExpressionNode(counter)))
+	}
+	if node.Range.HasValue() {
+		r := createLoweredCall(at, DecodeRuneFunction, CreateNameExpressionNode(str), CreateNameExpressionNode(counter))
+		body = append(body, createLoweredDeclaration(intClause(at), node.Range.Value, r))
+	}
+	body = append(body, node.Range.StatementNode)
+
+	width := createLoweredCall(at, RuneLengthFunction, CreateNameExpressionNode(str), CreateNameExpressionNode(counter))
+	step := CreateVariableEditorExpressionNode(counter, CreateLoweredToken("+", token.ADD, at), width, false)
+
+	loop := CreateForStatementNode(
+		CreateLoweredToken("for", token.FOR, at),
+		createLoweredDeclaration(intClause(at), counter, createLoweredInt(0, at)),
+		CreateBinaryExpressionNode(CreateLoweredToken("<", token.LT, at), CreateNameExpressionNode(counter), CreateNameExpressionNode(length)),
+		CreateExpressionStatementNode(step),
+		createLoweredBlock(at, body...),
+	)
+
+	return createLoweredBlock(at,
+		createLoweredDeclaration(TypeClauseNode{}, str, node.Range.Expression),
+		createLoweredDeclaration(intClause(at), length, createLoweredCall(at, "len", CreateNameExpressionNode(str))),
+		loop,
+	)
+}
+
+func CreateRangeStringStatementNode(rng ForRangeStatementNode) RangeStringStatementNode {
+	return RangeStringStatementNode{Range: rng}
+}
+
+// range over a map
+
+type RangeMapStatementNode struct {
+	Statement
+	Range ForRangeStatementNode
+}
+
+func (RangeMapStatementNode) NodeType() NodeType { return RangeMapStatement }
+
+func (node RangeMapStatementNode) Span() print2.TextSpan { return node.Range.Span() }
+
+func (node RangeMapStatementNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- RangeMapStatementNode")
+	node.Range.Print(indent + "  ")
+}
+
+// Lower rewrites the loop into a while loop driven by an iterator, moving on happens
+// in the condition so continue works without an update statement:
+//
+//	{ var $it; $mapIterator(m); while ($mapNext($it)) { var k; $mapKey($it); var v; $mapValue($it); body } }
+func (node RangeMapStatementNode) Lower() Statement {
+	at := node.Range.Keyword
+	iterator := hiddenToken("it", at)
+
+	body := make([]Statement, 0)
+	if node.Range.HasKey() {
+		key := createLoweredCall(at, MapKeyFunction, CreateNameExpressionNode(iterator))
+		body = append(body, createLoweredDeclaration(TypeClauseNode{}, node.Range.Key, key))
+	}
+	if node.Range.HasValue() {
+		value := createLoweredCall(at, MapValueFunction, CreateNameExpressionNode(iterator))
+		body = append(body, createLoweredDeclaration(TypeClauseNode{}, node.Range.Value, value))
+	}
+	body = append(body, node.Range.StatementNode)
+
+	loop := CreateWhileStatementNode(
+		CreateLoweredToken("while", token.WHILE, at),
+		createLoweredCall(at, MapNextFunction, CreateNameExpressionNode(iterator)),
+		createLoweredBlock(at, body...),
+	)
+
+	return createLoweredBlock(at,
+		createLoweredDeclaration(TypeClauseNode{}, iterator, createLoweredCall(at, MapIteratorFunction, node.Range.Expression)),
+		loop,
+	)
+}
+
+func CreateRangeMapStatementNode(rng ForRangeStatementNode) RangeMapStatementNode {
+	return RangeMapStatementNode{Range: rng}
+}
+
+// built-ins only lowered range loops call, their names can't be written in source code
+const (
+	DecodeRuneFunction  = "$decodeRune"  // the rune at a byte offset of a string
+	RuneLengthFunction  = "$runeLength"  // the number of bytes of the rune at a byte offset
+	MapIteratorFunction = "$mapIterator" // an iterator over a map, before its first entry
+	MapNextFunction     = "$mapNext"     // moves an iterator to the next entry, false when there is none
+	MapKeyFunction      = "$mapKey"      // the key of the current entry
+	MapValueFunction    = "$mapValue"    // the value of the current entry
+)
+
+// hiddenToken names a variable introduced by lowering. "$" can't start an identifier in source code,
+// so it can't clash with the user's names, the position keeps nested loops apart
+func hiddenToken(name string, at token.Token) token.Token {
+	return token.CreateTokenSpaced(fmt.Sprintf("$%s%d", name, int(at.Pos)), token.IDENT, false, at.Pos, at.End)
+}
+
+// CreateLoweredToken creates a token that isn't in the source (for code the compiler writes itself),
+// placed at another token for error messages
+func CreateLoweredToken(literal string, typ token.TokenType, at token.Token) token.Token {
+	return token.CreateTokenSpaced(literal, typ, false, at.Pos, at.End)
+}
+
+func createLoweredInt(value int, at token.Token) LiteralExpressionNode {
+	literal := CreateLoweredToken(fmt.Sprint(value), token.INT, at)
+	literal.RealValue = value
+	return CreateLiteralExpressionNode(literal)
+}
+
+// CreateLoweredString creates a string literal that isn't in the source
+func CreateLoweredString(value string, at token.Token) LiteralExpressionNode {
+	literal := CreateLoweredToken(value, token.STRING, at)
+	literal.RealValue = value
+	return CreateLiteralExpressionNode(literal)
+}
+
+func intClause(at token.Token) TypeClauseNode {
+	return CreateTypeClauseNode(nil, CreateLoweredToken("int", token.IDENT, at), nil, token.Token{})
+}
+
+func createLoweredDeclaration(clause TypeClauseNode, name token.Token, initializer Expression) VariableDeclarationStatementNode {
+	return CreateVariableDeclarationStatementNode(CreateLoweredToken("var", token.VAR, name), clause, name, initializer)
+}
+
+func createLoweredCall(at token.Token, name string, args ...Expression) CallExpressionNode {
+	return CreateCallExpressionNode(CreateLoweredToken(name, token.IDENT, at), args, TypeClauseNode{}, CreateLoweredToken(")", token.RPAREN, at))
+}
+
+func createLoweredBlock(at token.Token, statements ...Statement) BlockStatementNode {
+	return CreateBlockStatementNode(CreateLoweredToken("{", token.LBRACE, at), statements, CreateLoweredToken("}", token.RBRACE, at))
+}
+
+// createCountingLoop creates for (var int counter; 0; counter < limit; counter++) { body }
+func createCountingLoop(at token.Token, counter token.Token, limit Expression, body []Statement) ForStatementNode {
+	increment := CreateVariableEditorExpressionNode(counter, CreateLoweredToken("+", token.ADD, at), nil, true)
+
+	return CreateForStatementNode(
+		CreateLoweredToken("for", token.FOR, at),
+		createLoweredDeclaration(intClause(at), counter, createLoweredInt(0, at)),
+		CreateBinaryExpressionNode(CreateLoweredToken("<", token.LT, at), CreateNameExpressionNode(counter), limit),
+		CreateExpressionStatementNode(increment),
+		createLoweredBlock(at, body...),
+	)
+}
+
 // break
 
 type BreakStatementNode struct {
@@ -876,7 +1549,7 @@ type BreakStatementNode struct {
 func (BreakStatementNode) NodeType() NodeType { return BreakStatement }
 
 func (node BreakStatementNode) Span() print2.TextSpan {
-	return node.Keyword.Span
+	return node.Keyword.Span()
 }
 
 func (node BreakStatementNode) Print(indent string) {
@@ -900,7 +1573,7 @@ type ContinueStatementNode struct {
 func (ContinueStatementNode) NodeType() NodeType { return ContinueStatement }
 
 func (node ContinueStatementNode) Span() print2.TextSpan {
-	return node.Keyword.Span
+	return node.Keyword.Span()
 }
 
 func (node ContinueStatementNode) Print(indent string) {
@@ -926,7 +1599,7 @@ type AssignmentExpressionNode struct {
 func (AssignmentExpressionNode) NodeType() NodeType { return AssignmentExpression }
 
 func (node AssignmentExpressionNode) Span() print2.TextSpan {
-	return node.Identifier.Span.SpanBetween(node.ExpressionNode.Span())
+	return node.Identifier.Span().SpanBetween(node.ExpressionNode.Span())
 
 }
 
@@ -966,7 +1639,7 @@ type VariableEditorExpressionNode struct {
 func (VariableEditorExpressionNode) NodeType() NodeType { return VariableEditorExpression }
 
 func (node VariableEditorExpressionNode) Span() print2.TextSpan {
-	span := node.Identifier.Span.SpanBetween(node.Operator.Span)
+	span := node.Identifier.Span().SpanBetween(node.Operator.Span())
 	if !node.IsSingleStep {
 		span.SpanBetween(node.ExpressionNode.Span())
 	}
@@ -977,6 +1650,11 @@ func (node VariableEditorExpressionNode) Print(indent string) {
 	print2.PrintC(print2.Cyan, indent+"- VariableEditorExpressionNode")
 	fmt.Printf("%s  └ Identifier: %s\n", indent, node.Identifier.Type)
 	fmt.Printf("%s  └ Operator: %s\n", indent, node.Operator.Type)
+
+	// x++ and x-- don't have one
+	if node.IsSingleStep {
+		return
+	}
 	fmt.Println(indent + "  └ Expression: ")
 	node.ExpressionNode.Print(indent + "    ")
 }
@@ -1008,9 +1686,9 @@ func (CallExpressionNode) NodeType() NodeType { return CallExpression }
 
 func (node CallExpressionNode) Span() print2.TextSpan {
 	if node.CastingType.ClauseIsSet {
-		return node.CastingType.Span().SpanBetween(node.ClosingParenthesis.Span)
+		return node.CastingType.Span().SpanBetween(node.ClosingParenthesis.Span())
 	}
-	return node.Identifier.Span.SpanBetween(node.ClosingParenthesis.Span)
+	return node.Identifier.Span().SpanBetween(node.ClosingParenthesis.Span())
 
 }
 
@@ -1056,7 +1734,7 @@ type PackageCallExpressionNode struct {
 func (PackageCallExpressionNode) NodeType() NodeType { return PackageCallExpression }
 
 func (node PackageCallExpressionNode) Span() print2.TextSpan {
-	return node.Identifier.Span.SpanBetween(node.ClosingToken.Span)
+	return node.Identifier.Span().SpanBetween(node.ClosingToken.Span())
 }
 
 func (node PackageCallExpressionNode) Print(indent string) {
@@ -1092,7 +1770,7 @@ type MakeArrayExpressionNode struct {
 func (MakeArrayExpressionNode) NodeType() NodeType { return MakeArrayExpression }
 
 func (node MakeArrayExpressionNode) Span() print2.TextSpan {
-	return node.MakeKeyword.Span.SpanBetween(node.ClosingToken.Span)
+	return node.MakeKeyword.Span().SpanBetween(node.ClosingToken.Span())
 }
 
 func (node MakeArrayExpressionNode) Print(indent string) {
@@ -1139,18 +1817,30 @@ type MakeStructExpressionNode struct {
 	Type token.Token
 
 	LiteralValues []Expression
+
+	// the field names of a keyed literal (make Point{x: 1, y: 2}), one per value.
+	// Empty for positional literals (make Point{1, 2})
+	Keys []token.Token
 }
 
 func (node MakeStructExpressionNode) NodeType() NodeType { return MakeStructExpression }
 
+// IsKeyed checks if the values are given by field name
+func (node MakeStructExpressionNode) IsKeyed() bool {
+	return len(node.Keys) > 0
+}
+
 func (node MakeStructExpressionNode) Span() print2.TextSpan {
-	return node.MakeKeyword.Span.SpanBetween(node.ClosingToken.Span)
+	return node.MakeKeyword.Span().SpanBetween(node.ClosingToken.Span())
 }
 
 func (node MakeStructExpressionNode) Print(indent string) {
 	print2.PrintC(print2.Cyan, indent+"- MakeStructExpressionNode")
 	fmt.Println(indent + "  └ LiteralValues: ")
-	for _, val := range node.LiteralValues {
+	for i, val := range node.LiteralValues {
+		if node.IsKeyed() {
+			fmt.Printf("%s    └ Key: %s\n", indent, node.Keys[i].Literal)
+		}
 		val.Print(indent + "    ")
 	}
 
@@ -1165,6 +1855,54 @@ func CreateMakeStructExpressionNode(typ token.Token, literals []Expression, make
 	}
 }
 
+func CreateKeyedMakeStructExpressionNode(typ token.Token, keys []token.Token, literals []Expression, makeKw token.Token, closing token.Token) MakeStructExpressionNode {
+	node := CreateMakeStructExpressionNode(typ, literals, makeKw, closing)
+	node.Keys = keys
+	return node
+}
+
+// make map
+
+// make map[K]V{key: value, ...}, the braces can be left out for an empty map
+type MakeMapExpressionNode struct {
+	Expression
+	MakeKeyword  token.Token
+	ClosingToken token.Token // the "}", not set without braces
+
+	Type TypeClauseNode
+
+	Keys   []Expression
+	Values []Expression
+}
+
+func (MakeMapExpressionNode) NodeType() NodeType { return MakeMapExpression }
+
+func (node MakeMapExpressionNode) Span() print2.TextSpan {
+	if node.ClosingToken.Type == token.RBRACE {
+		return node.MakeKeyword.Span().SpanBetween(node.ClosingToken.Span())
+	}
+	return node.MakeKeyword.Span().SpanBetween(node.Type.SubClauses[1].Span())
+}
+
+func (node MakeMapExpressionNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- MakeMapExpressionNode")
+	fmt.Println(indent + "  └ Entries: ")
+	for i, key := range node.Keys {
+		key.Print(indent + "    ")
+		node.Values[i].Print(indent + "    ")
+	}
+}
+
+func CreateMakeMapExpressionNode(typ TypeClauseNode, keys []Expression, values []Expression, makeKw token.Token, closing token.Token) MakeMapExpressionNode {
+	return MakeMapExpressionNode{
+		Type:         typ,
+		Keys:         keys,
+		Values:       values,
+		MakeKeyword:  makeKw,
+		ClosingToken: closing,
+	}
+}
+
 // make expression
 
 type MakeExpressionNode struct {
@@ -1181,7 +1919,7 @@ type MakeExpressionNode struct {
 func (node MakeExpressionNode) NodeType() NodeType { return MakeExpression }
 
 func (node MakeExpressionNode) Span() print2.TextSpan {
-	return node.MakeKeyword.Span.SpanBetween(node.ClosingToken.Span)
+	return node.MakeKeyword.Span().SpanBetween(node.ClosingToken.Span())
 }
 
 func (node MakeExpressionNode) Print(indent string) {
@@ -1236,6 +1974,68 @@ func CreateArrayAssignmentExpressionNode(base Expression, index Expression, valu
 	}
 }
 
+// class field access expression
+
+// p.x, the base can be any expression (like another field access in a.b.c)
+type ClassFieldAccessExpressionNode struct {
+	Expression
+	Base            Expression
+	FieldIdentifier token.Token
+}
+
+func (ClassFieldAccessExpressionNode) NodeType() NodeType { return ClassFieldAccessExpression }
+
+func (node ClassFieldAccessExpressionNode) Span() print2.TextSpan {
+	return node.Base.Span().SpanBetween(node.FieldIdentifier.Span())
+}
+
+func (node ClassFieldAccessExpressionNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"└ ClassFieldAccessExpressionNode")
+	fmt.Println(indent + "  └ Base: ")
+	node.Base.Print(indent + "    ")
+	fmt.Printf("%s  └ Field: %s\n", indent, node.FieldIdentifier.Literal)
+}
+
+func CreateClassFieldAccessExpressionNode(base Expression, field token.Token) ClassFieldAccessExpressionNode {
+	return ClassFieldAccessExpressionNode{
+		Base:            base,
+		FieldIdentifier: field,
+	}
+}
+
+// class field assignment expression
+
+// p.x = value
+type ClassFieldAssignmentExpressionNode struct {
+	Expression
+	Base            Expression
+	FieldIdentifier token.Token
+	Value           Expression
+}
+
+func (ClassFieldAssignmentExpressionNode) NodeType() NodeType { return ClassFieldAssignmentExpression }
+
+func (node ClassFieldAssignmentExpressionNode) Span() print2.TextSpan {
+	return node.Base.Span().SpanBetween(node.Value.Span())
+}
+
+func (node ClassFieldAssignmentExpressionNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"- ClassFieldAssignmentExpressionNode")
+	fmt.Println(indent + "  └ Base: ")
+	node.Base.Print(indent + "    ")
+	fmt.Printf("%s  └ Field: %s\n", indent, node.FieldIdentifier.Literal)
+	fmt.Println(indent + "  └ Value: ")
+	node.Value.Print(indent + "    ")
+}
+
+func CreateClassFieldAssignmentExpressionNode(base Expression, field token.Token, value Expression) ClassFieldAssignmentExpressionNode {
+	return ClassFieldAssignmentExpressionNode{
+		Base:            base,
+		FieldIdentifier: field,
+		Value:           value,
+	}
+}
+
 // array access expression
 
 type ArrayAccessExpressionNode struct {
@@ -1253,7 +2053,7 @@ func (ArrayAccessExpressionNode) NodeType() NodeType { return ArrayAccessExpress
 // The starting line and column aren't always the absolute beginning of the statement just what's most
 // convenient.
 func (node ArrayAccessExpressionNode) Span() print2.TextSpan {
-	return node.Base.Span().SpanBetween(node.ClosingBracket.Span)
+	return node.Base.Span().SpanBetween(node.ClosingBracket.Span())
 }
 
 // node print function
@@ -1273,18 +2073,70 @@ func CreateArrayAccessExpressionNode(base Expression, index Expression) ArrayAcc
 	}
 }
 
+// slice expression
+
+// a[low:high] or a[low:high:max], low and high may be left out in the first form (they're nil then)
+type SliceExpressionNode struct {
+	Expression
+	Base           Expression
+	Low            Expression
+	High           Expression
+	Max            Expression
+	ClosingBracket token.Token
+}
+
+func (SliceExpressionNode) NodeType() NodeType { return SliceExpression }
+
+func (node SliceExpressionNode) Span() print2.TextSpan {
+	return node.Base.Span().SpanBetween(node.ClosingBracket.Span())
+}
+
+func (node SliceExpressionNode) Print(indent string) {
+	print2.PrintC(print2.Cyan, indent+"└ SliceExpressionNode")
+	fmt.Println(indent + "  └ Base: ")
+	node.Base.Print(indent + "    ")
+
+	fmt.Println(indent + "  └ Low: ")
+	if node.Low != nil {
+		node.Low.Print(indent + "    ")
+	}
+	fmt.Println(indent + "  └ High: ")
+	if node.High != nil {
+		node.High.Print(indent + "    ")
+	}
+	fmt.Println(indent + "  └ Max: ")
+	if node.Max != nil {
+		node.Max.Print(indent + "    ")
+	}
+}
+
+// IsFull checks if this is the three index form a[low:high:max]
+func (node SliceExpressionNode) IsFull() bool {
+	return node.Max != nil
+}
+
+func CreateSliceExpressionNode(base Expression, low Expression, high Expression, max Expression, closing token.Token) SliceExpressionNode {
+	return SliceExpressionNode{
+		Base:           base,
+		Low:            low,
+		High:           high,
+		Max:            max,
+		ClosingBracket: closing,
+	}
+}
+
 // reference expression
 
 type ReferenceExpressionNode struct {
 	Expression
-	ExpressionNode NameExpressNode
+	ExpressionNode Expression
 	Reference      token.Token
 }
 
 func (ReferenceExpressionNode) NodeType() NodeType { return ReferenceExpression }
 
 func (node ReferenceExpressionNode) Span() print2.TextSpan {
-	return node.Reference.Span.SpanBetween(node.Expression.Span())
+	return node.Reference.Span().SpanBetween(node.Expression.Span())
 }
 
 func (node ReferenceExpressionNode) Print(indent string) {
@@ -1293,10 +2145,11 @@ func (node ReferenceExpressionNode) Print(indent string) {
 	node.Expression.Print(indent + "    ")
 }
 
-func CreateReferenceExpressionNode(kw token.Token, expr NameExpressNode) ReferenceExpressionNode {
+func CreateReferenceExpressionNode(kw token.Token, expr Expression) ReferenceExpressionNode {
 	return ReferenceExpressionNode{
-		Reference:  kw,
-		Expression: expr,
+		Reference:      kw,
+		Expression:     expr,
+		ExpressionNode: expr,
 	}
 }
 
@@ -1313,7 +2166,7 @@ type DereferenceExpressionNode struct {
 func (DereferenceExpressionNode) NodeType() NodeType { return DereferenceExpression }
 
 func (node DereferenceExpressionNode) Span() print2.TextSpan {
-	return node.DerefKeyword.Span.SpanBetween(node.Expression.Span())
+	return node.DerefKeyword.Span().SpanBetween(node.Expression.Span())
 }
 
 // node print function
@@ -1326,8 +2179,9 @@ func (node DereferenceExpressionNode) Print(indent string) {
 // "constructor" / ooga booga OOP cave man brain
 func CreateDereferenceExpressionNode(kw token.Token, expr Expression) DereferenceExpressionNode {
 	return DereferenceExpressionNode{
-		DerefKeyword: kw,
-		Expression:   expr,
+		DerefKeyword:   kw,
+		Expression:     expr,
+		ExpressionNode: expr,
 	}
 }
 
@@ -1343,7 +2197,7 @@ type UnaryExpressionNode struct {
 func (UnaryExpressionNode) NodeType() NodeType { return UnaryExpression }
 
 func (node UnaryExpressionNode) Span() print2.TextSpan {
-	return node.Operator.Span.SpanBetween(node.Operand.Span())
+	return node.Operator.Span().SpanBetween(node.Operand.Span())
 }
 
 func (node UnaryExpressionNode) Print(indent string) {
@@ -0,0 +1,104 @@
+package irtools
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// DeclareExternalFunction adds the declaration of an external function to the module,
+// the function is implemented in C so it uses the C calling convention
+func DeclareExternalFunction(module *ir.Module, function objects.FunctionObject) *ir.Func {
+	// declared already (like puts being used in two files)
+	if fnc := TryFindFunction(module, function.Name); fnc != nil {
+		return fnc
+	}
+
+	params := make([]*ir.Param, 0, len(function.Parameters))
+	for _, param := range function.Parameters {
+		p := ir.NewParam(param.Name, CType(param.Type))
+		if attribute, ok := paramExtensions[param.Type.Name]; ok {
+			p.Attrs = append(p.Attrs, attribute)
+		}
+		params = append(params, p)
+	}
+
+	fnc := module.NewFunc(function.Name, CType(function.TypeObject), params...)
+	fnc.CallingConv = enum.CallingConvC
+	if attribute, ok := returnExtensions[function.TypeObject.Name]; ok {
+		fnc.ReturnAttrs = append(fnc.ReturnAttrs, attribute)
+	}
+
+	return fnc
+}
+
+// CType returns the LLVM type of a value passed to (or returned from) C.
+// int and uint are C's int and unsigned int (32 bit), so "external fn abs(x int) int" is just abs.
+// Our ints are 64 bit, they are truncated on the way to C and extended on the way back.
+// A char is a 32 bit rune already, which is what C's character functions (putchar, toupper, ...)
+// take as an int. bool is a C _Bool, a zero extended byte. See EmitToC and EmitFromC
+func CType(typ objects.TypeObject) types.Type {
+	switch typ.Name {
+	case objects.VoidType.Name:
+		return types.Void
+	case objects.BoolType.Name:
+		return types.I8
+	case objects.IntType.Name, objects.UIntType.Name, objects.CharType.Name:
+		return types.I32
+	case objects.FloatType.Name:
+		return types.Float
+	case objects.DoubleType.Name:
+		return types.Double
+	case objects.StringType.Name:
+		return types.I8Ptr // char*
+	}
+
+	// structs are passed by pointer
+	if sym, ok := typ.SourceObject.(objects.StructObject); ok && sym.IRType != nil {
+		return types.NewPointer(sym.IRType)
+	}
+	return types.I8Ptr
+}
+
+// how values narrower than a register are extended to one,
+// ints and chars are signed, uints and bools aren't
+var paramExtensions = map[string]enum.ParamAttr{
+	objects.IntType.Name:  enum.ParamAttrSignExt,
+	objects.CharType.Name: enum.ParamAttrSignExt,
+	objects.UIntType.Name: enum.ParamAttrZeroExt,
+	objects.BoolType.Name: enum.ParamAttrZeroExt,
+}
+
+var returnExtensions = map[string]enum.ReturnAttr{
+	objects.IntType.Name:  enum.ReturnAttrSignExt,
+	objects.CharType.Name: enum.ReturnAttrSignExt,
+	objects.UIntType.Name: enum.ReturnAttrZeroExt,
+	objects.BoolType.Name: enum.ReturnAttrZeroExt,
+}
+
+// EmitToC converts a value to the type C expects it as
+func EmitToC(block *ir.Block, val value.Value, typ objects.TypeObject) value.Value {
+	switch typ.Name {
+	case objects.BoolType.Name:
+		return block.NewZExt(val, types.I8)
+	case objects.IntType.Name, objects.UIntType.Name:
+		return block.NewTrunc(val, types.I32)
+	}
+	return val
+}
+
+// EmitFromC converts a value returned from C back, any byte that isn't 0 is true
+func EmitFromC(block *ir.Block, val value.Value, typ objects.TypeObject) value.Value {
+	switch typ.Name {
+	case objects.BoolType.Name:
+		return block.NewICmp(enum.IPredNE, val, constant.NewInt(types.I8, 0))
+	case objects.IntType.Name:
+		return block.NewSExt(val, types.I64)
+	case objects.UIntType.Name:
+		return block.NewZExt(val, types.I64)
+	}
+	return val
+}
@@ -0,0 +1,126 @@
+package irtools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/lexer"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/parser"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/semantic"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// externals binds the external functions in code and declares them in a new module
+func externals(t *testing.T, code string) (*ir.Module, map[string]objects.FunctionObject) {
+	t.Helper()
+	print2.OutputErrorMessages = false
+	print2.ErrorList = print2.ErrorList[:0]
+
+	lxr := lexer.New(strings.NewReader(code), t.Name()+".tod")
+	lxr.InsertSemicolons = true
+	scope := semantic.CreateScope(nil)
+
+	module := ir.NewModule()
+	functions := make(map[string]objects.FunctionObject)
+	for _, member := range parser.ParseFrom(lxr) {
+		function, ok := scope.BindExternalFunction(member.(ast.ExternalFunctionDeclarationMember))
+		if !ok {
+			t.Fatalf("couldn't bind %s: %v", function.Name, print2.ErrorList)
+		}
+		function.IRFunction = DeclareExternalFunction(module, function)
+		functions[function.Name] = function
+	}
+	return module, functions
+}
+
+// TestExternalDeclarations checks the C types the arguments and results are passed as
+func TestExternalDeclarations(t *testing.T) {
+	module, functions := externals(t, `
+external fn puts(s string) int
+external fn putchar(c char) int
+external fn labs(x int) int
+external fn isatty(fd uint) bool
+external fn srand(seed uint)
+external fn sqrtf(x float) float
+`)
+
+	expected := []string{
+		"declare ccc signext i32 @puts(i8* %s)",
+		"declare ccc signext i32 @putchar(i32 signext %c)",
+		"declare ccc signext i32 @labs(i32 signext %x)",
+		"declare ccc zeroext i8 @isatty(i32 zeroext %fd)",
+		"declare ccc void @srand(i32 zeroext %seed)",
+		"declare ccc float @sqrtf(float %x)",
+	}
+
+	// using puts in another file doesn't declare it again
+	if DeclareExternalFunction(module, functions["puts"]) != functions["puts"].IRFunction {
+		t.Errorf("puts has been declared twice")
+	}
+
+	if len(module.Funcs) != len(expected) {
+		t.Errorf("declared %d functions, expected %d", len(module.Funcs), len(expected))
+	}
+	for i, fnc := range module.Funcs {
+		if i < len(expected) && fnc.LLString() != expected[i] {
+			t.Errorf("declared as %q, expected %q", fnc.LLString(), expected[i])
+		}
+	}
+	validate(t, module)
+}
+
+// TestExternalCalls calls into libc and checks that the values make it there and back
+func TestExternalCalls(t *testing.T) {
+	module, functions := externals(t, `
+external fn abs(x int) int
+external fn toupper(c char) char
+external fn isdigit(c char) int
+external fn puts(s string) int
+`)
+
+	// calls a function the way the compiler would, converting the arguments and the result
+	call := func(block *ir.Block, name string, args ...value.Value) value.Value {
+		function := functions[name]
+		converted := make([]value.Value, 0, len(args))
+		for i, arg := range args {
+			converted = append(converted, EmitToC(block, arg, function.Parameters[i].Type))
+		}
+		return EmitFromC(block, block.NewCall(function.IRFunction, converted...), function.TypeObject)
+	}
+
+	main := module.NewFunc("main", types.I32)
+	block := main.NewBlock("")
+
+	message := module.NewGlobalDef("message", constant.NewCharArrayFromString("hello from C\x00"))
+	call(block, "puts", constant.NewGetElementPtr(message.ContentType, message, i64(0), i64(0)))
+
+	// every check that passes sets a bit of the exit code
+	checks := []value.Value{
+		block.NewICmp(enum.IPredEQ, call(block, "abs", i64(-7)), i64(7)),
+		block.NewICmp(enum.IPredEQ, call(block, "toupper", constant.NewInt(types.I32, 'a')), constant.NewInt(types.I32, 'A')),
+		block.NewICmp(enum.IPredNE, call(block, "isdigit", constant.NewInt(types.I32, '5')), i64(0)),
+		block.NewICmp(enum.IPredEQ, call(block, "isdigit", constant.NewInt(types.I32, 'x')), i64(0)),
+	}
+
+	var code value.Value = constant.NewInt(types.I32, 0)
+	for i, check := range checks {
+		bit := block.NewShl(block.NewZExt(check, types.I32), constant.NewInt(types.I32, int64(i)))
+		code = block.NewOr(code, bit)
+	}
+	block.NewRet(code)
+
+	output, exit := run(t, module)
+	if output != "hello from C\n" {
+		t.Errorf("printed %q", output)
+	}
+	if exit != 1<<len(checks)-1 {
+		t.Errorf("exit code is %04b, every bit should be set", exit)
+	}
+}
@@ -0,0 +1,102 @@
+package irtools
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// validate checks that the module is valid IR: llir has to be able to read it back
+// and, if LLVM is installed, its verifier has to accept it
+func validate(t *testing.T, module *ir.Module) {
+	t.Helper()
+
+	code := module.String()
+	if _, err := asm.ParseString(t.Name()+".ll", code); err != nil {
+		t.Fatalf("llir can't read the module back: %s\n%s", err, code)
+	}
+
+	opt, err := exec.LookPath("opt")
+	if err != nil {
+		return
+	}
+
+	cmd := exec.Command(opt, "-verify", "-disable-output")
+	cmd.Stdin = strings.NewReader(code)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("the module doesn't verify: %s\n%s\n%s", err, output, code)
+	}
+}
+
+// run validates the module and runs its main with lli,
+// it returns what the program printed and its exit code
+func run(t *testing.T, module *ir.Module) (string, int) {
+	t.Helper()
+	validate(t, module)
+
+	lli, err := exec.LookPath("lli")
+	if err != nil {
+		t.Skip("lli isn't installed, can't run the module")
+	}
+
+	var stdout, stderr bytes.Buffer
+	// the default JIT of LLVM 14 crashes on some modules, MCJIT doesn't
+	cmd := exec.Command(lli, "-jit-kind=mcjit", "-")
+	cmd.Stdin = strings.NewReader(module.String())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return stdout.String() + stderr.String(), exit.ExitCode()
+	} else if err != nil {
+		t.Fatalf("couldn't run lli: %s", err)
+	}
+	return stdout.String() + stderr.String(), 0
+}
+
+// i64 is shorthand for an i64 constant
+func i64(v int64) *constant.Int {
+	return constant.NewInt(types.I64, v)
+}
+
+// i64s is shorthand for a list of i64 constants
+func i64s(values ...int64) []value.Value {
+	result := make([]value.Value, 0, len(values))
+	for _, v := range values {
+		result = append(result, i64(v))
+	}
+	return result
+}
+
+// emitString adds a C string to the module and returns a pointer to it
+func emitString(module *ir.Module, s string) constant.Constant {
+	global := module.NewGlobalDef(fmt.Sprintf("string.%d", len(module.Globals)), constant.NewCharArrayFromString(s+"\x00"))
+	global.Immutable = true
+	return constant.NewGetElementPtr(global.ContentType, global, i64(0), i64(0))
+}
+
+// emitPrint prints i64 values on a line, separated by spaces
+func emitPrint(block *ir.Block, values ...value.Value) {
+	module := block.Parent.Parent
+
+	printf := TryFindFunction(module, "printf")
+	if printf == nil {
+		printf = cFunction(module, "printf", types.I32, types.I8Ptr)
+		printf.Sig.Variadic = true
+	}
+
+	format := strings.TrimSpace(strings.Repeat("%lld ", len(values))) + "\n\x00"
+	global := module.NewGlobalDef(fmt.Sprintf("format.%d", len(module.Globals)), constant.NewCharArrayFromString(format))
+
+	args := []value.Value{constant.NewGetElementPtr(global.ContentType, global, i64(0), i64(0))}
+	block.NewCall(printf, append(args, values...)...)
+}
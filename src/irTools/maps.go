@@ -0,0 +1,495 @@
+package irtools
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// a map is a pointer to a header of { i8** buckets, i64 count, i64 size }. Every bucket is a linked list of
+// entries { i8* next, i64 hash, K key, V value }, the number of buckets is a power of two and doubles
+// once there are as many entries as buckets. A null map can be read from but not written to
+const (
+	mapBuckets = 0
+	mapCount   = 1
+	mapSize    = 2
+
+	entryNext  = 0
+	entryHash  = 1
+	entryKey   = 2
+	entryValue = 3
+
+	initialMapSize = 8
+)
+
+// MapHeaderType is the header every map points to
+var MapHeaderType = types.NewStruct(types.NewPointer(types.I8Ptr), types.I64, types.I64)
+
+// MapType is the LLVM type of a map value
+var MapType = types.NewPointer(MapHeaderType)
+
+// all entries start with next and hash, so growing works the same for every map
+var entryPrefixType = types.NewStruct(types.I8Ptr, types.I64)
+
+// MapEntryType returns the type of an entry of a map from key to value
+func MapEntryType(key types.Type, value types.Type) *types.StructType {
+	return types.NewStruct(types.I8Ptr, types.I64, key, value)
+}
+
+// EmitMakeMap creates a map and fills in the given keys and values
+func EmitMakeMap(block *ir.Block, keys []value.Value, values []value.Value) value.Value {
+	module := block.Parent.Parent
+	m := block.NewCall(mapNew(module))
+
+	for i, key := range keys {
+		slot := block.NewCall(mapAssign(module, key.Type(), values[i].Type()), m, key)
+		block.NewStore(values[i], slot)
+	}
+	return m
+}
+
+// EmitMapLookup is m[key] (and v, ok := m[key]), it returns the value (zero if the key is missing),
+// whether the key was found and the block to continue in
+func EmitMapLookup(fnc *ir.Func, block *ir.Block, m value.Value, key value.Value, valueType types.Type) (value.Value, value.Value, *ir.Block) {
+	entry := block.NewCall(mapFind(fnc.Parent, key.Type(), valueType), m, key)
+	found := block.NewICmp(enum.IPredNE, entry, constant.NewNull(entry.Type().(*types.PointerType)))
+
+	load := fnc.NewBlock("")
+	next := fnc.NewBlock("")
+	block.NewCondBr(found, load, next)
+
+	zero := constant.NewInt(types.I32, 0)
+	slot := load.NewGetElementPtr(entry.Type().(*types.PointerType).ElemType, entry, zero, constant.NewInt(types.I32, entryValue))
+	loaded := load.NewLoad(valueType, slot)
+	load.NewBr(next)
+
+	result := next.NewPhi(ir.NewIncoming(loaded, load), ir.NewIncoming(constant.NewZeroInitializer(valueType), block))
+	return result, found, next
+}
+
+// EmitMapAssign is m[key] = val, it adds the key if it's missing
+func EmitMapAssign(block *ir.Block, m value.Value, key value.Value, val value.Value) {
+	slot := block.NewCall(mapAssign(block.Parent.Parent, key.Type(), val.Type()), m, key)
+	block.NewStore(val, slot)
+}
+
+// EmitMapDelete is delete(m, key), missing keys are ignored
+func EmitMapDelete(block *ir.Block, m value.Value, key value.Value, valueType types.Type) {
+	block.NewCall(mapDelete(block.Parent.Parent, key.Type(), valueType), m, key)
+}
+
+// EmitMapLength is len(m)
+func EmitMapLength(block *ir.Block, m value.Value) value.Value {
+	return block.NewCall(mapLength(block.Parent.Parent), m)
+}
+
+// mapNew defines tod_map_new(), it allocates an empty map
+func mapNew(module *ir.Module) *ir.Func {
+	if fnc := TryFindFunction(module, "tod_map_new"); fnc != nil {
+		return fnc
+	}
+
+	fnc := module.NewFunc("tod_map_new", MapType)
+	body := fnc.NewBlock("")
+
+	header := body.NewBitCast(body.NewCall(cFunction(module, "malloc", types.I8Ptr, types.I64), SizeOf(MapHeaderType)), MapType)
+	buckets := body.NewCall(cFunction(module, "calloc", types.I8Ptr, types.I64, types.I64), constant.NewInt(types.I64, initialMapSize), SizeOf(types.I8Ptr))
+
+	body.NewStore(body.NewBitCast(buckets, types.NewPointer(types.I8Ptr)), headerField(body, header, mapBuckets))
+	body.NewStore(constant.NewInt(types.I64, 0), headerField(body, header, mapCount))
+	body.NewStore(constant.NewInt(types.I64, initialMapSize), headerField(body, header, mapSize))
+	body.NewRet(header)
+
+	return fnc
+}
+
+// mapLength defines tod_map_len(m), a null map is empty
+func mapLength(module *ir.Module) *ir.Func {
+	if fnc := TryFindFunction(module, "tod_map_len"); fnc != nil {
+		return fnc
+	}
+
+	m := ir.NewParam("map", MapType)
+	fnc := module.NewFunc("tod_map_len", types.I64, m)
+
+	entry := fnc.NewBlock("")
+	empty := fnc.NewBlock("")
+	count := fnc.NewBlock("")
+
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, m, constant.NewNull(MapType)), empty, count)
+	empty.NewRet(constant.NewInt(types.I64, 0))
+	count.NewRet(count.NewLoad(types.I64, headerField(count, m, mapCount)))
+
+	return fnc
+}
+
+// mapFind defines tod_map_find.K.V(m, key), it returns the entry of key or null
+func mapFind(module *ir.Module, key types.Type, val types.Type) *ir.Func {
+	name := "tod_map_find." + typeID(key, val)
+	if fnc := TryFindFunction(module, name); fnc != nil {
+		return fnc
+	}
+
+	entryType := MapEntryType(key, val)
+	entryPointer := types.NewPointer(entryType)
+
+	m := ir.NewParam("map", MapType)
+	k := ir.NewParam("key", key)
+	fnc := module.NewFunc(name, entryPointer, m, k)
+
+	entry := fnc.NewBlock("")
+	start := fnc.NewBlock("")
+	search := fnc.NewBlock("")
+	check := fnc.NewBlock("")
+	next := fnc.NewBlock("")
+	found := fnc.NewBlock("")
+	missing := fnc.NewBlock("")
+
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, m, constant.NewNull(MapType)), missing, start)
+
+	hash := hashValue(module, start, k)
+	first := start.NewBitCast(start.NewLoad(types.I8Ptr, bucketAddress(start, m, hash)), entryPointer)
+	start.NewBr(search)
+
+	current := search.NewPhi(ir.NewIncoming(first, start))
+	search.NewCondBr(search.NewICmp(enum.IPredEQ, current, constant.NewNull(entryPointer)), missing, check)
+
+	sameHash := check.NewICmp(enum.IPredEQ, check.NewLoad(types.I64, entryField(check, current, entryHash)), hash)
+	sameKey := equalValues(module, check, check.NewLoad(key, entryField(check, current, entryKey)), k)
+	check.NewCondBr(check.NewAnd(sameHash, sameKey), found, next)
+
+	following := next.NewBitCast(next.NewLoad(types.I8Ptr, entryField(next, current, entryNext)), entryPointer)
+	current.Incs = append(current.Incs, ir.NewIncoming(following, next))
+	next.NewBr(search)
+
+	found.NewRet(current)
+	missing.NewRet(constant.NewNull(entryPointer))
+
+	return fnc
+}
+
+// mapAssign defines tod_map_assign.K.V(m, key), it returns the address of the value of key
+// and adds an entry (with a zero value) if there is none
+func mapAssign(module *ir.Module, key types.Type, val types.Type) *ir.Func {
+	name := "tod_map_assign." + typeID(key, val)
+	if fnc := TryFindFunction(module, name); fnc != nil {
+		return fnc
+	}
+
+	entryType := MapEntryType(key, val)
+	entryPointer := types.NewPointer(entryType)
+
+	m := ir.NewParam("map", MapType)
+	k := ir.NewParam("key", key)
+	fnc := module.NewFunc(name, types.NewPointer(val), m, k)
+
+	entry := fnc.NewBlock("")
+	nilMap := fnc.NewBlock("")
+	lookup := fnc.NewBlock("")
+	found := fnc.NewBlock("")
+	insert := fnc.NewBlock("")
+	grow := fnc.NewBlock("")
+	add := fnc.NewBlock("")
+
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, m, constant.NewNull(MapType)), nilMap, lookup)
+
+	nilMap.NewCall(panicFunction(module, "tod_panic_nil_map", "panic: assignment to entry in nil map\n"))
+	nilMap.NewUnreachable()
+
+	existing := lookup.NewCall(mapFind(module, key, val), m, k)
+	lookup.NewCondBr(lookup.NewICmp(enum.IPredEQ, existing, constant.NewNull(entryPointer)), insert, found)
+
+	found.NewRet(entryField(found, existing, entryValue))
+
+	count := insert.NewLoad(types.I64, headerField(insert, m, mapCount))
+	size := insert.NewLoad(types.I64, headerField(insert, m, mapSize))
+	insert.NewCondBr(insert.NewICmp(enum.IPredUGE, count, size), grow, add)
+
+	grow.NewCall(mapGrow(module), m)
+	grow.NewBr(add)
+
+	hash := hashValue(module, add, k)
+	bucket := bucketAddress(add, m, hash)
+
+	raw := add.NewCall(cFunction(module, "calloc", types.I8Ptr, types.I64, types.I64), constant.NewInt(types.I64, 1), SizeOf(entryType))
+	created := add.NewBitCast(raw, entryPointer)
+
+	add.NewStore(add.NewLoad(types.I8Ptr, bucket), entryField(add, created, entryNext))
+	add.NewStore(hash, entryField(add, created, entryHash))
+	add.NewStore(k, entryField(add, created, entryKey))
+	add.NewStore(raw, bucket)
+	add.NewStore(add.NewAdd(count, constant.NewInt(types.I64, 1)), headerField(add, m, mapCount))
+	add.NewRet(entryField(add, created, entryValue))
+
+	return fnc
+}
+
+// mapDelete defines tod_map_delete.K.V(m, key), it unlinks and frees the entry of key
+func mapDelete(module *ir.Module, key types.Type, val types.Type) *ir.Func {
+	name := "tod_map_delete." + typeID(key, val)
+	if fnc := TryFindFunction(module, name); fnc != nil {
+		return fnc
+	}
+
+	entryPointer := types.NewPointer(MapEntryType(key, val))
+
+	m := ir.NewParam("map", MapType)
+	k := ir.NewParam("key", key)
+	fnc := module.NewFunc(name, types.Void, m, k)
+
+	entry := fnc.NewBlock("")
+	start := fnc.NewBlock("")
+	search := fnc.NewBlock("")
+	check := fnc.NewBlock("")
+	next := fnc.NewBlock("")
+	remove := fnc.NewBlock("")
+	done := fnc.NewBlock("")
+
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, m, constant.NewNull(MapType)), done, start)
+
+	hash := hashValue(module, start, k)
+	first := bucketAddress(start, m, hash)
+	start.NewBr(search)
+
+	// link is the pointer that points to the current entry, the bucket or the next field of the entry before
+	link := search.NewPhi(ir.NewIncoming(first, start))
+	raw := search.NewLoad(types.I8Ptr, link)
+	search.NewCondBr(search.NewICmp(enum.IPredEQ, raw, constant.NewNull(types.I8Ptr)), done, check)
+
+	current := check.NewBitCast(raw, entryPointer)
+	sameHash := check.NewICmp(enum.IPredEQ, check.NewLoad(types.I64, entryField(check, current, entryHash)), hash)
+	sameKey := equalValues(module, check, check.NewLoad(key, entryField(check, current, entryKey)), k)
+	check.NewCondBr(check.NewAnd(sameHash, sameKey), remove, next)
+
+	link.Incs = append(link.Incs, ir.NewIncoming(entryField(next, current, entryNext), next))
+	next.NewBr(search)
+
+	remove.NewStore(remove.NewLoad(types.I8Ptr, entryField(remove, current, entryNext)), link)
+	remove.NewCall(cFunction(module, "free", types.Void, types.I8Ptr), raw)
+	count := remove.NewLoad(types.I64, headerField(remove, m, mapCount))
+	remove.NewStore(remove.NewSub(count, constant.NewInt(types.I64, 1)), headerField(remove, m, mapCount))
+	remove.NewBr(done)
+
+	done.NewRet(nil)
+
+	return fnc
+}
+
+// mapGrow defines tod_map_grow(m), it doubles the buckets and moves every entry into its new bucket
+func mapGrow(module *ir.Module) *ir.Func {
+	if fnc := TryFindFunction(module, "tod_map_grow"); fnc != nil {
+		return fnc
+	}
+
+	prefixPointer := types.NewPointer(entryPrefixType)
+	bucketsType := types.NewPointer(types.I8Ptr)
+
+	m := ir.NewParam("map", MapType)
+	fnc := module.NewFunc("tod_map_grow", types.Void, m)
+
+	entry := fnc.NewBlock("")
+	outer := fnc.NewBlock("")
+	bucket := fnc.NewBlock("")
+	inner := fnc.NewBlock("")
+	move := fnc.NewBlock("")
+	outerNext := fnc.NewBlock("")
+	done := fnc.NewBlock("")
+
+	old := entry.NewLoad(bucketsType, headerField(entry, m, mapBuckets))
+	size := entry.NewLoad(types.I64, headerField(entry, m, mapSize))
+	newSize := entry.NewMul(size, constant.NewInt(types.I64, 2))
+	raw := entry.NewCall(cFunction(module, "calloc", types.I8Ptr, types.I64, types.I64), newSize, SizeOf(types.I8Ptr))
+	buckets := entry.NewBitCast(raw, bucketsType)
+	mask := entry.NewSub(newSize, constant.NewInt(types.I64, 1))
+	entry.NewBr(outer)
+
+	// for every old bucket
+	index := outer.NewPhi(ir.NewIncoming(constant.NewInt(types.I64, 0), entry))
+	outer.NewCondBr(outer.NewICmp(enum.IPredULT, index, size), bucket, done)
+
+	first := bucket.NewLoad(types.I8Ptr, bucket.NewGetElementPtr(types.I8Ptr, old, index))
+	bucket.NewBr(inner)
+
+	// for every entry in it
+	current := inner.NewPhi(ir.NewIncoming(first, bucket))
+	inner.NewCondBr(inner.NewICmp(enum.IPredEQ, current, constant.NewNull(types.I8Ptr)), outerNext, move)
+
+	zero := constant.NewInt(types.I32, 0)
+	prefix := move.NewBitCast(current, prefixPointer)
+	nextField := move.NewGetElementPtr(entryPrefixType, prefix, zero, constant.NewInt(types.I32, entryNext))
+	following := move.NewLoad(types.I8Ptr, nextField)
+	hash := move.NewLoad(types.I64, move.NewGetElementPtr(entryPrefixType, prefix, zero, constant.NewInt(types.I32, entryHash)))
+	slot := move.NewGetElementPtr(types.I8Ptr, buckets, move.NewAnd(hash, mask))
+	move.NewStore(move.NewLoad(types.I8Ptr, slot), nextField)
+	move.NewStore(current, slot)
+	current.Incs = append(current.Incs, ir.NewIncoming(following, move))
+	move.NewBr(inner)
+
+	index.Incs = append(index.Incs, ir.NewIncoming(outerNext.NewAdd(index, constant.NewInt(types.I64, 1)), outerNext))
+	outerNext.NewBr(outer)
+
+	done.NewCall(cFunction(module, "free", types.Void, types.I8Ptr), done.NewBitCast(old, types.I8Ptr))
+	done.NewStore(buckets, headerField(done, m, mapBuckets))
+	done.NewStore(newSize, headerField(done, m, mapSize))
+	done.NewRet(nil)
+
+	return fnc
+}
+
+// hashValue hashes a key: integers (int, uint, char, bool) are mixed, strings go through FNV-1a
+// and structs combine the hashes of their fields
+func hashValue(module *ir.Module, block *ir.Block, val value.Value) value.Value {
+	switch typ := val.Type().(type) {
+	case *types.IntType:
+		var wide value.Value = val
+		if typ.BitSize < 64 {
+			wide = block.NewZExt(val, types.I64)
+		}
+
+		mixed := block.NewMul(wide, constant.NewInt(types.I64, -7046029254386353131)) // 0x9E3779B97F4A7C15
+		return block.NewXor(mixed, block.NewLShr(mixed, constant.NewInt(types.I64, 29)))
+
+	case *types.StructType:
+		var hash value.Value = constant.NewInt(types.I64, 17)
+		for i := range typ.Fields {
+			field := hashValue(module, block, block.NewExtractValue(val, uint64(i)))
+			hash = block.NewAdd(block.NewMul(hash, constant.NewInt(types.I64, 31)), field)
+		}
+		return hash
+	}
+
+	// the only pointers that can be keys are strings
+	return block.NewCall(hashString(module), val)
+}
+
+// equalValues compares two keys of the same type
+func equalValues(module *ir.Module, block *ir.Block, a value.Value, b value.Value) value.Value {
+	switch typ := a.Type().(type) {
+	case *types.IntType:
+		return block.NewICmp(enum.IPredEQ, a, b)
+
+	case *types.StructType:
+		var equal value.Value = constant.True
+		for i := range typ.Fields {
+			field := equalValues(module, block, block.NewExtractValue(a, uint64(i)), block.NewExtractValue(b, uint64(i)))
+			equal = block.NewAnd(equal, field)
+		}
+		return equal
+	}
+
+	return block.NewCall(equalStrings(module), a, b)
+}
+
+// hashString defines tod_hash_string(s), FNV-1a over the bytes of s (null hashes like "")
+func hashString(module *ir.Module) *ir.Func {
+	if fnc := TryFindFunction(module, "tod_hash_string"); fnc != nil {
+		return fnc
+	}
+
+	str := ir.NewParam("str", types.I8Ptr)
+	fnc := module.NewFunc("tod_hash_string", types.I64, str)
+
+	entry := fnc.NewBlock("")
+	loop := fnc.NewBlock("")
+	step := fnc.NewBlock("")
+	done := fnc.NewBlock("")
+
+	offset := constant.NewInt(types.I64, -3750763034362895579) // 0xcbf29ce484222325
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, str, constant.NewNull(types.I8Ptr)), done, loop)
+
+	hash := loop.NewPhi(ir.NewIncoming(offset, entry))
+	index := loop.NewPhi(ir.NewIncoming(constant.NewInt(types.I64, 0), entry))
+	char := loop.NewLoad(types.I8, loop.NewGetElementPtr(types.I8, str, index))
+	loop.NewCondBr(loop.NewICmp(enum.IPredEQ, char, constant.NewInt(types.I8, 0)), done, step)
+
+	mixed := step.NewMul(step.NewXor(hash, step.NewZExt(char, types.I64)), constant.NewInt(types.I64, 1099511628211))
+	hash.Incs = append(hash.Incs, ir.NewIncoming(mixed, step))
+	index.Incs = append(index.Incs, ir.NewIncoming(step.NewAdd(index, constant.NewInt(types.I64, 1)), step))
+	step.NewBr(loop)
+
+	result := done.NewPhi(ir.NewIncoming(offset, entry), ir.NewIncoming(hash, loop))
+	done.NewRet(result)
+
+	return fnc
+}
+
+// equalStrings defines tod_string_equal(a, b), null is treated like ""
+func equalStrings(module *ir.Module) *ir.Func {
+	if fnc := TryFindFunction(module, "tod_string_equal"); fnc != nil {
+		return fnc
+	}
+
+	a := ir.NewParam("a", types.I8Ptr)
+	b := ir.NewParam("b", types.I8Ptr)
+	fnc := module.NewFunc("tod_string_equal", types.I1, a, b)
+
+	empty := module.NewGlobalDef("tod_empty_string", constant.NewCharArrayFromString("\x00"))
+	empty.Immutable = true
+
+	zero := constant.NewInt(types.I64, 0)
+	emptyString := constant.NewGetElementPtr(empty.ContentType, empty, zero, zero)
+
+	body := fnc.NewBlock("")
+	null := constant.NewNull(types.I8Ptr)
+	left := body.NewSelect(body.NewICmp(enum.IPredEQ, a, null), emptyString, a)
+	right := body.NewSelect(body.NewICmp(enum.IPredEQ, b, null), emptyString, b)
+
+	strcmp := cFunction(module, "strcmp", types.I32, types.I8Ptr, types.I8Ptr)
+	body.NewRet(body.NewICmp(enum.IPredEQ, body.NewCall(strcmp, left, right), constant.NewInt(types.I32, 0)))
+
+	return fnc
+}
+
+// headerField returns the address of a field of a map header
+func headerField(block *ir.Block, m value.Value, field int64) value.Value {
+	zero := constant.NewInt(types.I32, 0)
+	return block.NewGetElementPtr(MapHeaderType, m, zero, constant.NewInt(types.I32, field))
+}
+
+// entryField returns the address of a field of an entry
+func entryField(block *ir.Block, entry value.Value, field int64) value.Value {
+	zero := constant.NewInt(types.I32, 0)
+	return block.NewGetElementPtr(entry.Type().(*types.PointerType).ElemType, entry, zero, constant.NewInt(types.I32, field))
+}
+
+// bucketAddress returns the address of the bucket a hash belongs in
+func bucketAddress(block *ir.Block, m value.Value, hash value.Value) value.Value {
+	buckets := block.NewLoad(types.NewPointer(types.I8Ptr), headerField(block, m, mapBuckets))
+	size := block.NewLoad(types.I64, headerField(block, m, mapSize))
+	index := block.NewAnd(hash, block.NewSub(size, constant.NewInt(types.I64, 1)))
+	return block.NewGetElementPtr(types.I8Ptr, buckets, index)
+}
+
+// typeID names the runtime functions of a map type, like tod_map_find.i64.i32
+func typeID(key types.Type, val types.Type) string {
+	return mangle(key) + "." + mangle(val)
+}
+
+// mangle turns a type into something that can be part of a symbol name
+func mangle(typ types.Type) string {
+	switch typ := typ.(type) {
+	case *types.IntType:
+		return fmt.Sprintf("i%d", typ.BitSize)
+	case *types.FloatType:
+		return typ.Kind.String()
+	case *types.PointerType:
+		return "p" + mangle(typ.ElemType)
+	case *types.ArrayType:
+		return fmt.Sprintf("a%d%s", typ.Len, mangle(typ.ElemType))
+	case *types.StructType:
+		if typ.Name() != "" {
+			return typ.Name()
+		}
+
+		id := fmt.Sprintf("s%d", len(typ.Fields))
+		for _, field := range typ.Fields {
+			id += "_" + mangle(field)
+		}
+		return id
+	}
+	return "t"
+}
@@ -0,0 +1,169 @@
+package irtools
+
+import (
+	"testing"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// mapProgram creates a main function, build adds the rest of the program
+// and returns the block main returns 0 from
+func mapProgram(build func(fnc *ir.Func, block *ir.Block) *ir.Block) *ir.Module {
+	module := ir.NewModule()
+	main := module.NewFunc("main", types.I32)
+	block := build(main, main.NewBlock(""))
+	block.NewRet(constant.NewInt(types.I32, 0))
+	return module
+}
+
+// emitPrintLookup prints the value of m[key] (as an i64) and if it has been found
+func emitPrintLookup(fnc *ir.Func, block *ir.Block, m value.Value, key value.Value, valueType types.Type) *ir.Block {
+	val, found, block := EmitMapLookup(fnc, block, m, key, valueType)
+	if valueType != types.I64 {
+		val = block.NewSExt(val, types.I64)
+	}
+	emitPrint(block, val, block.NewZExt(found, types.I64))
+	return block
+}
+
+// TestMapIntegerKeys fills a map far past its initial size, so it has to grow a couple of times
+func TestMapIntegerKeys(t *testing.T) {
+	module := mapProgram(func(fnc *ir.Func, block *ir.Block) *ir.Block {
+		m := EmitMakeMap(block, nil, nil)
+
+		// for i := 0; i < 1000; i++ { m[i] = i * 3 }
+		loop := fnc.NewBlock("")
+		body := fnc.NewBlock("")
+		after := fnc.NewBlock("")
+		block.NewBr(loop)
+
+		i := loop.NewPhi(ir.NewIncoming(i64(0), block))
+		loop.NewCondBr(loop.NewICmp(enum.IPredSLT, i, i64(1000)), body, after)
+		EmitMapAssign(body, m, i, body.NewMul(i, i64(3)))
+		i.Incs = append(i.Incs, ir.NewIncoming(body.NewAdd(i, i64(1)), body))
+		body.NewBr(loop)
+
+		block = after
+		emitPrint(block, EmitMapLength(block, m))
+		block = emitPrintLookup(fnc, block, m, i64(777), types.I64)
+		block = emitPrintLookup(fnc, block, m, i64(5000), types.I64)
+
+		// overwriting doesn't add an entry, deleting twice only deletes once
+		EmitMapAssign(block, m, i64(777), i64(1))
+		EmitMapDelete(block, m, i64(3), types.I64)
+		EmitMapDelete(block, m, i64(3), types.I64)
+		emitPrint(block, EmitMapLength(block, m))
+		block = emitPrintLookup(fnc, block, m, i64(777), types.I64)
+		return emitPrintLookup(fnc, block, m, i64(3), types.I64)
+	})
+
+	output, exit := run(t, module)
+	expected := "1000\n" +
+		"2331 1\n" +
+		"0 0\n" +
+		"999\n" +
+		"1 1\n" +
+		"0 0\n"
+	if exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
+
+// TestMapStringKeys checks that strings are compared by their characters, not their address
+func TestMapStringKeys(t *testing.T) {
+	module := mapProgram(func(fnc *ir.Func, block *ir.Block) *ir.Block {
+		module := fnc.Parent
+		keys := []value.Value{emitString(module, "a"), emitString(module, "b")}
+		values := []value.Value{constant.NewInt(types.I32, 1), constant.NewInt(types.I32, 2)}
+		m := EmitMakeMap(block, keys, values)
+
+		// another copy of "b"
+		block = emitPrintLookup(fnc, block, m, emitString(module, "b"), types.I32)
+		block = emitPrintLookup(fnc, block, m, emitString(module, "c"), types.I32)
+
+		// a null string is the empty string
+		EmitMapAssign(block, m, constant.NewNull(types.I8Ptr), constant.NewInt(types.I32, 9))
+		block = emitPrintLookup(fnc, block, m, emitString(module, ""), types.I32)
+		emitPrint(block, EmitMapLength(block, m))
+		return block
+	})
+
+	output, exit := run(t, module)
+	expected := "2 1\n" +
+		"0 0\n" +
+		"9 1\n" +
+		"3\n"
+	if exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
+
+// TestMapStructKeys checks that struct keys are equal if all of their fields are
+func TestMapStructKeys(t *testing.T) {
+	module := mapProgram(func(fnc *ir.Func, block *ir.Block) *ir.Block {
+		module := fnc.Parent
+		keyType := types.NewStruct(types.I64, types.I8Ptr, types.I1)
+		key := func(n int64, s string, b bool) value.Value {
+			return constant.NewStruct(keyType, i64(n), emitString(module, s), constant.NewBool(b))
+		}
+
+		m := EmitMakeMap(block, []value.Value{key(1, "x", true), key(1, "x", false)}, i64s(10, 20))
+		block = emitPrintLookup(fnc, block, m, key(1, "x", false), types.I64)
+		block = emitPrintLookup(fnc, block, m, key(1, "x", true), types.I64)
+		return emitPrintLookup(fnc, block, m, key(1, "y", false), types.I64)
+	})
+
+	output, exit := run(t, module)
+	expected := "20 1\n" +
+		"10 1\n" +
+		"0 0\n"
+	if exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
+
+// TestNilMap checks that a null map can be read from and deleted from
+func TestNilMap(t *testing.T) {
+	module := mapProgram(func(fnc *ir.Func, block *ir.Block) *ir.Block {
+		m := constant.NewNull(MapType)
+		block = emitPrintLookup(fnc, block, m, i64(1), types.I64)
+		EmitMapDelete(block, m, i64(1), types.I64)
+		emitPrint(block, EmitMapLength(block, m))
+		return block
+	})
+
+	output, exit := run(t, module)
+	if expected := "0 0\n0\n"; exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
+
+// TestMapFunctionsPerType checks that every key and value type gets its own functions,
+// which are only defined once, and that the module stays valid with all of them
+func TestMapFunctionsPerType(t *testing.T) {
+	module := mapProgram(func(fnc *ir.Func, block *ir.Block) *ir.Block {
+		ints := EmitMakeMap(block, i64s(1, 2), i64s(3, 4))
+		doubles := EmitMakeMap(block, i64s(1), []value.Value{constant.NewFloat(types.Double, 0.5)})
+		strings := EmitMakeMap(block, []value.Value{emitString(fnc.Parent, "a")}, i64s(1))
+
+		_, _, block = EmitMapLookup(fnc, block, ints, i64(1), types.I64)
+		_, _, block = EmitMapLookup(fnc, block, ints, i64(2), types.I64)
+		_, _, block = EmitMapLookup(fnc, block, doubles, i64(1), types.Double)
+		_, _, block = EmitMapLookup(fnc, block, strings, emitString(fnc.Parent, "a"), types.I64)
+		return block
+	})
+	validate(t, module)
+
+	finds := FindFunctionsWithPrefix(module, "tod_map_find")
+	if len(finds) != 3 {
+		names := make([]string, 0, len(finds))
+		for _, find := range finds {
+			names = append(names, find.Name())
+		}
+		t.Errorf("expected a find function for each of the 3 maps, got %v", names)
+	}
+}
@@ -0,0 +1,219 @@
+package irtools
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// a map iterator is { map, i64 bucket, i8* entry, i8* next }, entry is the current entry and next
+// the one after it. Reading next before the body runs means the current entry can be deleted
+// while iterating. Adding keys while iterating may grow the map, which moves entries between
+// buckets, so those loops may see entries twice or not at all
+const (
+	iteratorMap    = 0
+	iteratorBucket = 1
+	iteratorEntry  = 2
+	iteratorNext   = 3
+)
+
+// MapIteratorType is what a range loop over a map walks it with
+var MapIteratorType = types.NewStruct(MapType, types.I64, types.I8Ptr, types.I8Ptr)
+
+// RuneType is what tod_decode_rune returns, { i64 rune, i64 width }
+var RuneType = types.NewStruct(types.I64, types.I64)
+
+// the rune invalid UTF-8 decodes to, one byte at a time
+const replacementRune = 0xFFFD
+
+// EmitDecodeRune is the rune at byte offset index of str
+func EmitDecodeRune(block *ir.Block, str value.Value, index value.Value) value.Value {
+	return block.NewExtractValue(block.NewCall(decodeRune(block.Parent.Parent), str, index), 0)
+}
+
+// EmitRuneLength is the number of bytes of the rune at byte offset index of str
+func EmitRuneLength(block *ir.Block, str value.Value, index value.Value) value.Value {
+	return block.NewExtractValue(block.NewCall(decodeRune(block.Parent.Parent), str, index), 1)
+}
+
+// EmitMapIterator creates an iterator over m, before its first entry. It lives on the stack
+// of fnc, allocated in the entry block so loops don't grow the stack
+func EmitMapIterator(fnc *ir.Func, block *ir.Block, m value.Value) value.Value {
+	iterator := ir.NewAlloca(MapIteratorType)
+	fnc.Blocks[0].Insts = append([]ir.Instruction{iterator}, fnc.Blocks[0].Insts...)
+
+	zero := constant.NewInt(types.I32, 0)
+	field := func(index int64) value.Value {
+		return block.NewGetElementPtr(MapIteratorType, iterator, zero, constant.NewInt(types.I32, index))
+	}
+
+	block.NewStore(m, field(iteratorMap))
+	block.NewStore(constant.NewInt(types.I64, -1), field(iteratorBucket))
+	block.NewStore(constant.NewNull(types.I8Ptr), field(iteratorEntry))
+	block.NewStore(constant.NewNull(types.I8Ptr), field(iteratorNext))
+	return iterator
+}
+
+// EmitMapNext moves an iterator to the next entry, it returns false once there are none left
+func EmitMapNext(block *ir.Block, iterator value.Value) value.Value {
+	return block.NewCall(mapIteratorNext(block.Parent.Parent), iterator)
+}
+
+// EmitMapIteratorKey is the key of the entry an iterator is at
+func EmitMapIteratorKey(block *ir.Block, iterator value.Value, key types.Type, val types.Type) value.Value {
+	return block.NewLoad(key, entryField(block, currentEntry(block, iterator, key, val), entryKey))
+}
+
+// EmitMapIteratorValue is the value of the entry an iterator is at
+func EmitMapIteratorValue(block *ir.Block, iterator value.Value, key types.Type, val types.Type) value.Value {
+	return block.NewLoad(val, entryField(block, currentEntry(block, iterator, key, val), entryValue))
+}
+
+// currentEntry loads the entry an iterator is at
+func currentEntry(block *ir.Block, iterator value.Value, key types.Type, val types.Type) value.Value {
+	raw := block.NewLoad(types.I8Ptr, iteratorField(block, iterator, iteratorEntry))
+	return block.NewBitCast(raw, types.NewPointer(MapEntryType(key, val)))
+}
+
+// mapIteratorNext defines tod_map_iter_next(iterator), it follows next or else
+// looks for the next bucket that isn't empty. A null map has no entries
+func mapIteratorNext(module *ir.Module) *ir.Func {
+	if fnc := TryFindFunction(module, "tod_map_iter_next"); fnc != nil {
+		return fnc
+	}
+
+	iterator := ir.NewParam("iterator", types.NewPointer(MapIteratorType))
+	fnc := module.NewFunc("tod_map_iter_next", types.I1, iterator)
+
+	entry := fnc.NewBlock("")
+	follow := fnc.NewBlock("")
+	scan := fnc.NewBlock("")
+	bucket := fnc.NewBlock("")
+	take := fnc.NewBlock("")
+	done := fnc.NewBlock("")
+
+	m := entry.NewLoad(MapType, iteratorField(entry, iterator, iteratorMap))
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, m, constant.NewNull(MapType)), done, follow)
+
+	following := follow.NewLoad(types.I8Ptr, iteratorField(follow, iterator, iteratorNext))
+	follow.NewCondBr(follow.NewICmp(enum.IPredEQ, following, constant.NewNull(types.I8Ptr)), scan, take)
+
+	// move on to the next bucket
+	index := scan.NewAdd(scan.NewLoad(types.I64, iteratorField(scan, iterator, iteratorBucket)), constant.NewInt(types.I64, 1))
+	scan.NewStore(index, iteratorField(scan, iterator, iteratorBucket))
+	size := scan.NewLoad(types.I64, headerField(scan, m, mapSize))
+	scan.NewCondBr(scan.NewICmp(enum.IPredSLT, index, size), bucket, done)
+
+	buckets := bucket.NewLoad(types.NewPointer(types.I8Ptr), headerField(bucket, m, mapBuckets))
+	first := bucket.NewLoad(types.I8Ptr, bucket.NewGetElementPtr(types.I8Ptr, buckets, index))
+	bucket.NewCondBr(bucket.NewICmp(enum.IPredEQ, first, constant.NewNull(types.I8Ptr)), scan, take)
+
+	current := take.NewPhi(ir.NewIncoming(following, follow), ir.NewIncoming(first, bucket))
+	zero := constant.NewInt(types.I32, 0)
+	prefix := take.NewBitCast(current, types.NewPointer(entryPrefixType))
+	after := take.NewLoad(types.I8Ptr, take.NewGetElementPtr(entryPrefixType, prefix, zero, constant.NewInt(types.I32, entryNext)))
+	take.NewStore(current, iteratorField(take, iterator, iteratorEntry))
+	take.NewStore(after, iteratorField(take, iterator, iteratorNext))
+	take.NewRet(constant.True)
+
+	done.NewRet(constant.False)
+
+	return fnc
+}
+
+// decodeRune defines tod_decode_rune(str, index), it decodes the UTF-8 sequence at index.
+// Bad sequences (stray continuation bytes, overlong forms, surrogates, cut off sequences)
+// decode to U+FFFD one byte at a time, so the loop always moves on
+func decodeRune(module *ir.Module) *ir.Func {
+	if fnc := TryFindFunction(module, "tod_decode_rune"); fnc != nil {
+		return fnc
+	}
+
+	str := ir.NewParam("str", types.I8Ptr)
+	index := ir.NewParam("index", types.I64)
+	fnc := module.NewFunc("tod_decode_rune", RuneType, str, index)
+
+	entry := fnc.NewBlock("")
+	ascii := fnc.NewBlock("")
+	lead := fnc.NewBlock("")
+	invalid := fnc.NewBlock("")
+
+	first := entry.NewZExt(entry.NewLoad(types.I8, entry.NewGetElementPtr(types.I8, str, index)), types.I64)
+	entry.NewCondBr(entry.NewICmp(enum.IPredULT, first, constant.NewInt(types.I64, 0x80)), ascii, lead)
+
+	returnRune(ascii, first, 1)
+	returnRune(invalid, constant.NewInt(types.I64, replacementRune), 1)
+
+	// the lead byte tells us the length: 110xxxxx, 1110xxxx, 11110xxx
+	// (0xC0, 0xC1 and everything above 0xF4 can only start overlong or too large sequences)
+	sequences := []struct {
+		below   int64 // the lead byte is below this
+		width   int64
+		payload int64 // mask of the bits of the lead byte that are part of the rune
+		min     int64 // smaller runes have a shorter form
+	}{
+		{0xE0, 2, 0x1F, 0x80},
+		{0xF0, 3, 0x0F, 0x800},
+		{0xF5, 4, 0x07, 0x10000},
+	}
+
+	lowest := lead.NewICmp(enum.IPredULT, first, constant.NewInt(types.I64, 0xC2))
+	check := fnc.NewBlock("")
+	lead.NewCondBr(lowest, invalid, check)
+
+	for _, sequence := range sequences {
+		decode := fnc.NewBlock("")
+		next := fnc.NewBlock("")
+		check.NewCondBr(check.NewICmp(enum.IPredULT, first, constant.NewInt(types.I64, sequence.below)), decode, next)
+
+		// strings end in 0, which isn't a continuation byte, so this never reads past the end
+		var r value.Value = decode.NewAnd(first, constant.NewInt(types.I64, sequence.payload))
+		for i := int64(1); i < sequence.width; i++ {
+			position := decode.NewAdd(index, constant.NewInt(types.I64, i))
+			b := decode.NewZExt(decode.NewLoad(types.I8, decode.NewGetElementPtr(types.I8, str, position)), types.I64)
+
+			continued := fnc.NewBlock("")
+			isContinuation := decode.NewICmp(enum.IPredEQ, decode.NewAnd(b, constant.NewInt(types.I64, 0xC0)), constant.NewInt(types.I64, 0x80))
+			decode.NewCondBr(isContinuation, continued, invalid)
+
+			r = continued.NewOr(continued.NewShl(r, constant.NewInt(types.I64, 6)), continued.NewAnd(b, constant.NewInt(types.I64, 0x3F)))
+			decode = continued
+		}
+
+		var valid value.Value = decode.NewICmp(enum.IPredUGE, r, constant.NewInt(types.I64, sequence.min))
+		switch sequence.width {
+		case 3:
+			// surrogates only exist in UTF-16
+			surrogate := decode.NewICmp(enum.IPredEQ, decode.NewAnd(r, constant.NewInt(types.I64, 0x1FF800)), constant.NewInt(types.I64, 0xD800))
+			valid = decode.NewAnd(valid, decode.NewXor(surrogate, constant.True))
+		case 4:
+			valid = decode.NewAnd(valid, decode.NewICmp(enum.IPredULE, r, constant.NewInt(types.I64, 0x10FFFF)))
+		}
+
+		good := fnc.NewBlock("")
+		decode.NewCondBr(valid, good, invalid)
+		returnRune(good, r, sequence.width)
+
+		check = next
+	}
+
+	check.NewBr(invalid)
+
+	return fnc
+}
+
+// returnRune ends block by returning { r, width } from tod_decode_rune
+func returnRune(block *ir.Block, r value.Value, width int64) {
+	var result value.Value = constant.NewUndef(RuneType)
+	result = block.NewInsertValue(result, r, 0)
+	result = block.NewInsertValue(result, constant.NewInt(types.I64, width), 1)
+	block.NewRet(result)
+}
+
+// iteratorField returns the address of a field of a map iterator
+func iteratorField(block *ir.Block, iterator value.Value, field int64) value.Value {
+	zero := constant.NewInt(types.I32, 0)
+	return block.NewGetElementPtr(MapIteratorType, iterator, zero, constant.NewInt(types.I32, field))
+}
@@ -0,0 +1,163 @@
+package irtools
+
+import (
+	"testing"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// forLoop emits the for loop range loops over slices, strings and ints are lowered to:
+// for (i = 0; i < limit; i += step(i)) { body }. body gets the counter and the blocks continue and break
+// go to, it returns the block it ends in. forLoop returns the block after the loop
+func forLoop(fnc *ir.Func, block *ir.Block, limit value.Value, step func(block *ir.Block, i value.Value) value.Value,
+	body func(block *ir.Block, i value.Value, next *ir.Block, exit *ir.Block) *ir.Block) *ir.Block {
+	counter := block.NewAlloca(types.I64)
+	block.NewStore(i64(0), counter)
+
+	condition := fnc.NewBlock("")
+	loop := fnc.NewBlock("")
+	next := fnc.NewBlock("")
+	exit := fnc.NewBlock("")
+	block.NewBr(condition)
+
+	i := condition.NewLoad(types.I64, counter)
+	condition.NewCondBr(condition.NewICmp(enum.IPredSLT, i, limit), loop, exit)
+
+	body(loop, i, next, exit).NewBr(next)
+
+	current := next.NewLoad(types.I64, counter)
+	next.NewStore(next.NewAdd(current, step(next, current)), counter)
+	next.NewBr(condition)
+
+	return exit
+}
+
+// mapLoop emits the while loop range loops over maps are lowered to: while (next(it)) { body },
+// continue goes back to the condition
+func mapLoop(fnc *ir.Func, block *ir.Block, m value.Value,
+	body func(block *ir.Block, key value.Value, val value.Value, next *ir.Block, exit *ir.Block) *ir.Block) *ir.Block {
+	iterator := EmitMapIterator(fnc, block, m)
+
+	condition := fnc.NewBlock("")
+	loop := fnc.NewBlock("")
+	exit := fnc.NewBlock("")
+	block.NewBr(condition)
+
+	condition.NewCondBr(EmitMapNext(condition, iterator), loop, exit)
+
+	key := EmitMapIteratorKey(loop, iterator, types.I64, types.I64)
+	val := EmitMapIteratorValue(loop, iterator, types.I64, types.I64)
+	body(loop, key, val, condition, exit).NewBr(condition)
+
+	return exit
+}
+
+// jumpIf goes to target if a == b, it returns the block to go on in otherwise
+func jumpIf(fnc *ir.Func, block *ir.Block, a value.Value, b value.Value, target *ir.Block) *ir.Block {
+	rest := fnc.NewBlock("")
+	block.NewCondBr(block.NewICmp(enum.IPredEQ, a, b), target, rest)
+	return rest
+}
+
+// one is the step of loops over slices and ints
+func one(*ir.Block, value.Value) value.Value {
+	return i64(1)
+}
+
+// TestRangeSlice runs for i, v := range s { if (i == 1) continue; if (v == 4) break; print(i, v) }
+func TestRangeSlice(t *testing.T) {
+	module := sliceProgram(5, func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block {
+		return forLoop(fnc, block, SliceLength(block, slice), one, func(block *ir.Block, i value.Value, next *ir.Block, exit *ir.Block) *ir.Block {
+			address, block := EmitIndexAddress(fnc, block, slice, i)
+			v := block.NewLoad(types.I64, address)
+
+			block = jumpIf(fnc, block, i, i64(1), next)
+			block = jumpIf(fnc, block, v, i64(4), exit)
+			emitPrint(block, i, v)
+			return block
+		})
+	})
+
+	output, exit := run(t, module)
+	if expected := "0 1\n2 3\n"; exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
+
+// TestRangeString runs for i, r := range "aé€b!c" { if (r == 'é') continue; if (r == '!') break; print(i, r) },
+// the offsets move on by the length of every rune, continue included
+func TestRangeString(t *testing.T) {
+	module := mapProgram(func(fnc *ir.Func, block *ir.Block) *ir.Block {
+		str := emitString(fnc.Parent, "aé€b!c")
+
+		step := func(block *ir.Block, i value.Value) value.Value {
+			return EmitRuneLength(block, str, i)
+		}
+		return forLoop(fnc, block, i64(int64(len("aé€b!c"))), step, func(block *ir.Block, i value.Value, next *ir.Block, exit *ir.Block) *ir.Block {
+			r := EmitDecodeRune(block, str, i)
+
+			block = jumpIf(fnc, block, r, i64('é'), next)
+			block = jumpIf(fnc, block, r, i64('!'), exit)
+			emitPrint(block, i, r)
+			return block
+		})
+	})
+
+	output, exit := run(t, module)
+	if expected := "0 97\n3 8364\n6 98\n"; exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
+
+// TestRangeInt runs for i := range 5 { if (i == 1) continue; if (i == 3) break; print(i) }
+func TestRangeInt(t *testing.T) {
+	module := mapProgram(func(fnc *ir.Func, block *ir.Block) *ir.Block {
+		return forLoop(fnc, block, i64(5), one, func(block *ir.Block, i value.Value, next *ir.Block, exit *ir.Block) *ir.Block {
+			block = jumpIf(fnc, block, i, i64(1), next)
+			block = jumpIf(fnc, block, i, i64(3), exit)
+			emitPrint(block, i)
+			return block
+		})
+	})
+
+	output, exit := run(t, module)
+	if expected := "0\n2\n"; exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
+
+// TestRangeMap walks a map twice, the order of the entries isn't fixed so
+// the first loop skips an entry with continue and sums up the rest,
+// the second one counts entries and breaks after two of them
+func TestRangeMap(t *testing.T) {
+	module := mapProgram(func(fnc *ir.Func, block *ir.Block) *ir.Block {
+		m := EmitMakeMap(block, i64s(1, 2, 3, 4), i64s(10, 20, 30, 40))
+
+		sum := block.NewAlloca(types.I64)
+		block.NewStore(i64(0), sum)
+		block = mapLoop(fnc, block, m, func(block *ir.Block, key value.Value, val value.Value, next *ir.Block, exit *ir.Block) *ir.Block {
+			block = jumpIf(fnc, block, key, i64(2), next)
+			block.NewStore(block.NewAdd(block.NewLoad(types.I64, sum), val), sum)
+			return block
+		})
+
+		count := block.NewAlloca(types.I64)
+		block.NewStore(i64(0), count)
+		block = mapLoop(fnc, block, m, func(block *ir.Block, key value.Value, val value.Value, next *ir.Block, exit *ir.Block) *ir.Block {
+			counted := block.NewAdd(block.NewLoad(types.I64, count), i64(1))
+			block.NewStore(counted, count)
+			return jumpIf(fnc, block, counted, i64(2), exit)
+		})
+
+		emitPrint(block, block.NewLoad(types.I64, sum), block.NewLoad(types.I64, count))
+		return block
+	})
+
+	output, exit := run(t, module)
+	if expected := "80 2\n"; exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
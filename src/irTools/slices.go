@@ -0,0 +1,248 @@
+package irtools
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// a slice is a header of { element*, i64 len, i64 cap } pointing into a buffer,
+// slicing shares the buffer and append only copies it when it runs out of room
+const (
+	slicePointer  = 0
+	sliceLength   = 1
+	sliceCapacity = 2
+)
+
+// runtime functions, defined in the module the first time they're needed
+const (
+	indexPanicFunction = "tod_panic_index"
+	slicePanicFunction = "tod_panic_slice"
+)
+
+// SliceHeaderType returns the LLVM type of a slice of element
+func SliceHeaderType(element types.Type) *types.StructType {
+	return types.NewStruct(types.NewPointer(element), types.I64, types.I64)
+}
+
+// CreateSlice builds a slice header out of its three parts
+func CreateSlice(block *ir.Block, pointer value.Value, length value.Value, capacity value.Value) value.Value {
+	element := pointer.Type().(*types.PointerType).ElemType
+
+	var header value.Value = constant.NewUndef(SliceHeaderType(element))
+	header = block.NewInsertValue(header, pointer, slicePointer)
+	header = block.NewInsertValue(header, length, sliceLength)
+	header = block.NewInsertValue(header, capacity, sliceCapacity)
+	return header
+}
+
+// SliceOfArray turns a pointer to an [N x T] array into a slice of all of it
+func SliceOfArray(block *ir.Block, array value.Value) value.Value {
+	typ := array.Type().(*types.PointerType).ElemType.(*types.ArrayType)
+	zero := constant.NewInt(types.I64, 0)
+	length := constant.NewInt(types.I64, int64(typ.Len))
+
+	pointer := block.NewGetElementPtr(typ, array, zero, zero)
+	return CreateSlice(block, pointer, length, length)
+}
+
+// SlicePointer returns the buffer a slice points into
+func SlicePointer(block *ir.Block, slice value.Value) value.Value {
+	return block.NewExtractValue(slice, slicePointer)
+}
+
+// SliceLength is len(slice)
+func SliceLength(block *ir.Block, slice value.Value) value.Value {
+	return block.NewExtractValue(slice, sliceLength)
+}
+
+// SliceCapacity is cap(slice)
+func SliceCapacity(block *ir.Block, slice value.Value) value.Value {
+	return block.NewExtractValue(slice, sliceCapacity)
+}
+
+// EmitBoundsCheck panics if index isn't in [0, length). Negative indices are caught by comparing unsigned.
+// Code after the check goes into the returned block
+func EmitBoundsCheck(fnc *ir.Func, block *ir.Block, index value.Value, length value.Value) *ir.Block {
+	inBounds := block.NewICmp(enum.IPredULT, index, length)
+
+	fail := fnc.NewBlock("")
+	fail.NewCall(indexPanic(fnc.Parent), index, length)
+	fail.NewUnreachable()
+
+	next := fnc.NewBlock("")
+	block.NewCondBr(inBounds, next, fail)
+	return next
+}
+
+// EmitIndexAddress returns the address of slice[index] after checking the bounds
+func EmitIndexAddress(fnc *ir.Func, block *ir.Block, slice value.Value, index value.Value) (value.Value, *ir.Block) {
+	block = EmitBoundsCheck(fnc, block, index, SliceLength(block, slice))
+
+	pointer := SlicePointer(block, slice)
+	element := pointer.Type().(*types.PointerType).ElemType
+	return block.NewGetElementPtr(element, pointer, index), block
+}
+
+// EmitSlice is slice[low:high:max], it panics unless 0 <= low <= high <= max <= cap(slice).
+// For slice[low:high] pass cap(slice) as max
+func EmitSlice(fnc *ir.Func, block *ir.Block, slice value.Value, low value.Value, high value.Value, max value.Value) (value.Value, *ir.Block) {
+	capacity := SliceCapacity(block, slice)
+
+	// unsigned, so a negative low fails low <= high
+	inBounds := block.NewAnd(
+		block.NewICmp(enum.IPredULE, low, high),
+		block.NewAnd(
+			block.NewICmp(enum.IPredULE, high, max),
+			block.NewICmp(enum.IPredULE, max, capacity),
+		),
+	)
+
+	fail := fnc.NewBlock("")
+	fail.NewCall(slicePanic(fnc.Parent), low, high, max, capacity)
+	fail.NewUnreachable()
+
+	next := fnc.NewBlock("")
+	block.NewCondBr(inBounds, next, fail)
+
+	pointer := SlicePointer(next, slice)
+	element := pointer.Type().(*types.PointerType).ElemType
+
+	return CreateSlice(
+		next,
+		next.NewGetElementPtr(element, pointer, low),
+		next.NewSub(high, low),
+		next.NewSub(max, low),
+	), next
+}
+
+// EmitAppend is append(slice, values...). If the values don't fit the buffer is copied into a new one
+// with double the capacity (or just enough room, if that's more)
+func EmitAppend(fnc *ir.Func, block *ir.Block, slice value.Value, values ...value.Value) (value.Value, *ir.Block) {
+	pointer := SlicePointer(block, slice)
+	length := SliceLength(block, slice)
+	capacity := SliceCapacity(block, slice)
+	element := pointer.Type().(*types.PointerType).ElemType
+
+	newLength := block.NewAdd(length, constant.NewInt(types.I64, int64(len(values))))
+
+	grow := fnc.NewBlock("")
+	next := fnc.NewBlock("")
+	block.NewCondBr(block.NewICmp(enum.IPredUGT, newLength, capacity), grow, next)
+
+	// new capacity = max(2 * cap, new length)
+	doubled := grow.NewMul(capacity, constant.NewInt(types.I64, 2))
+	newCapacity := grow.NewSelect(grow.NewICmp(enum.IPredUGT, doubled, newLength), doubled, newLength)
+
+	size := SizeOf(element)
+	buffer := grow.NewCall(cFunction(fnc.Parent, "malloc", types.I8Ptr, types.I64), grow.NewMul(newCapacity, size))
+	grow.NewCall(
+		cFunction(fnc.Parent, "memmove", types.I8Ptr, types.I8Ptr, types.I8Ptr, types.I64),
+		buffer,
+		grow.NewBitCast(pointer, types.I8Ptr),
+		grow.NewMul(length, size),
+	)
+	grown := grow.NewBitCast(buffer, pointer.Type())
+	grow.NewBr(next)
+
+	finalPointer := next.NewPhi(ir.NewIncoming(pointer, block), ir.NewIncoming(grown, grow))
+	finalCapacity := next.NewPhi(ir.NewIncoming(capacity, block), ir.NewIncoming(newCapacity, grow))
+
+	for i, val := range values {
+		index := next.NewAdd(length, constant.NewInt(types.I64, int64(i)))
+		next.NewStore(val, next.NewGetElementPtr(element, finalPointer, index))
+	}
+
+	return CreateSlice(next, finalPointer, newLength, finalCapacity), next
+}
+
+// EmitCopy is copy(dst, src), it copies min(len(dst), len(src)) elements and returns how many.
+// The slices may overlap
+func EmitCopy(fnc *ir.Func, block *ir.Block, dst value.Value, src value.Value) value.Value {
+	dstLength := SliceLength(block, dst)
+	srcLength := SliceLength(block, src)
+	count := block.NewSelect(block.NewICmp(enum.IPredULT, dstLength, srcLength), dstLength, srcLength)
+
+	dstPointer := SlicePointer(block, dst)
+	element := dstPointer.Type().(*types.PointerType).ElemType
+
+	block.NewCall(
+		cFunction(fnc.Parent, "memmove", types.I8Ptr, types.I8Ptr, types.I8Ptr, types.I64),
+		block.NewBitCast(dstPointer, types.I8Ptr),
+		block.NewBitCast(SlicePointer(block, src), types.I8Ptr),
+		block.NewMul(count, SizeOf(element)),
+	)
+	return count
+}
+
+// SizeOf is the size of a type in bytes, as an i64 constant (the offset of element 1 from null)
+func SizeOf(typ types.Type) constant.Constant {
+	null := constant.NewNull(types.NewPointer(typ))
+	return constant.NewPtrToInt(constant.NewGetElementPtr(typ, null, constant.NewInt(types.I64, 1)), types.I64)
+}
+
+// cFunction finds (or declares) a function of the C library
+func cFunction(module *ir.Module, name string, ret types.Type, params ...types.Type) *ir.Func {
+	if fnc := TryFindFunction(module, name); fnc != nil {
+		return fnc
+	}
+
+	irParams := make([]*ir.Param, 0, len(params))
+	for _, param := range params {
+		irParams = append(irParams, ir.NewParam("", param))
+	}
+
+	fnc := module.NewFunc(name, ret, irParams...)
+	fnc.CallingConv = enum.CallingConvC
+	return fnc
+}
+
+// indexPanic defines tod_panic_index(index, length), it prints the bad index and exits
+func indexPanic(module *ir.Module) *ir.Func {
+	return panicFunction(module, indexPanicFunction, "panic: index %lld out of range [0:%lld]\n", "index", "length")
+}
+
+// slicePanic defines tod_panic_slice(low, high, max, cap), it prints the bad bounds and exits
+func slicePanic(module *ir.Module) *ir.Func {
+	return panicFunction(module, slicePanicFunction, "panic: slice bounds [%lld:%lld:%lld] out of range with capacity %lld\n", "low", "high", "max", "cap")
+}
+
+// panicFunction defines a function printing format with its i64 parameters, then exiting with code 2
+func panicFunction(module *ir.Module, name string, format string, params ...string) *ir.Func {
+	if fnc := TryFindFunction(module, name); fnc != nil {
+		return fnc
+	}
+
+	message := module.NewGlobalDef(name+".message", constant.NewCharArrayFromString(format+"\x00"))
+	message.Immutable = true
+
+	printf := TryFindFunction(module, "printf")
+	if printf == nil {
+		printf = cFunction(module, "printf", types.I32, types.I8Ptr)
+		printf.Sig.Variadic = true
+	}
+
+	irParams := make([]*ir.Param, 0, len(params))
+	for _, param := range params {
+		irParams = append(irParams, ir.NewParam(param, types.I64))
+	}
+
+	fnc := module.NewFunc(name, types.Void, irParams...)
+	fnc.FuncAttrs = append(fnc.FuncAttrs, enum.FuncAttrNoReturn, enum.FuncAttrCold)
+
+	body := fnc.NewBlock("")
+	zero := constant.NewInt(types.I64, 0)
+
+	args := []value.Value{constant.NewGetElementPtr(message.ContentType, message, zero, zero)}
+	for _, param := range irParams {
+		args = append(args, param)
+	}
+
+	body.NewCall(printf, args...)
+	body.NewCall(cFunction(module, "exit", types.Void, types.I32), constant.NewInt(types.I32, 2))
+	body.NewUnreachable()
+
+	return fnc
+}
@@ -0,0 +1,146 @@
+package irtools
+
+import (
+	"testing"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// sliceProgram creates a main function that starts out with a slice of an [n x i64] array holding 1 to n.
+// build adds the rest of the program and returns the block main returns 0 from
+func sliceProgram(n int64, build func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block) *ir.Module {
+	module := ir.NewModule()
+	main := module.NewFunc("main", types.I32)
+	block := main.NewBlock("")
+
+	array := block.NewAlloca(types.NewArray(uint64(n), types.I64))
+	for i := int64(0); i < n; i++ {
+		block.NewStore(i64(i+1), block.NewGetElementPtr(array.ElemType, array, i64(0), i64(i)))
+	}
+
+	block = build(main, block, SliceOfArray(block, array))
+	block.NewRet(constant.NewInt(types.I32, 0))
+	return module
+}
+
+// emitPrintSlice prints len, cap and the elements of a slice of i64
+func emitPrintSlice(fnc *ir.Func, block *ir.Block, slice value.Value, length int64) *ir.Block {
+	values := []value.Value{SliceLength(block, slice), SliceCapacity(block, slice)}
+	for i := int64(0); i < length; i++ {
+		var address value.Value
+		address, block = EmitIndexAddress(fnc, block, slice, i64(i))
+		values = append(values, block.NewLoad(types.I64, address))
+	}
+	emitPrint(block, values...)
+	return block
+}
+
+// TestAppend checks that append only grows the buffer when it's full, to double its capacity
+// (or just enough room), and that the elements make it into the new buffer
+func TestAppend(t *testing.T) {
+	module := sliceProgram(3, func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block {
+		block = emitPrintSlice(fnc, block, slice, 3)
+
+		// 3 -> 6
+		slice, block = EmitAppend(fnc, block, slice, i64(4), i64(5))
+		block = emitPrintSlice(fnc, block, slice, 5)
+
+		// still fits
+		grown := slice
+		slice, block = EmitAppend(fnc, block, slice, i64(6))
+		block = emitPrintSlice(fnc, block, slice, 6)
+
+		// 6 -> 12, then shares the buffer with grown
+		slice, block = EmitAppend(fnc, block, slice, i64(7))
+		block = emitPrintSlice(fnc, block, slice, 7)
+		block = emitPrintSlice(fnc, block, grown, 5)
+
+		// more than double at once: 3 -> 10
+		var long value.Value
+		long, block = EmitAppend(fnc, block, SliceOfArray(block, block.NewAlloca(types.NewArray(3, types.I64))), i64s(1, 2, 3, 4, 5, 6, 7)...)
+		emitPrint(block, SliceLength(block, long), SliceCapacity(block, long))
+		return block
+	})
+
+	output, exit := run(t, module)
+	expected := "3 3 1 2 3\n" +
+		"5 6 1 2 3 4 5\n" +
+		"6 6 1 2 3 4 5 6\n" +
+		"7 12 1 2 3 4 5 6 7\n" +
+		"5 6 1 2 3 4 5\n" +
+		"10 10\n"
+	if exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
+
+// TestSliceAndCopy checks len and cap of a[low:high:max] and that slices share their buffer
+func TestSliceAndCopy(t *testing.T) {
+	module := sliceProgram(5, func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block {
+		var middle, limited value.Value
+		middle, block = EmitSlice(fnc, block, slice, i64(1), i64(3), SliceCapacity(block, slice))
+		block = emitPrintSlice(fnc, block, middle, 2)
+
+		limited, block = EmitSlice(fnc, block, slice, i64(1), i64(2), i64(3))
+		block = emitPrintSlice(fnc, block, limited, 1)
+
+		// copies 2 elements, from 2 3 to where 1 2 are
+		copied := EmitCopy(fnc, block, slice, middle)
+		emitPrint(block, copied)
+		return emitPrintSlice(fnc, block, slice, 5)
+	})
+
+	output, exit := run(t, module)
+	expected := "2 4 2 3\n" +
+		"1 2 2\n" +
+		"2\n" +
+		"5 5 2 3 3 4 5\n"
+	if exit != 0 || output != expected {
+		t.Errorf("exit code %d, printed\n%s\nexpected\n%s", exit, output, expected)
+	}
+}
+
+// TestOutOfRange checks that bad indices and slice bounds panic with exit code 2
+func TestOutOfRange(t *testing.T) {
+	cases := []struct {
+		name    string
+		build   func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block
+		message string
+	}{
+		{"index == len", func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block {
+			_, block = EmitIndexAddress(fnc, block, slice, i64(3))
+			return block
+		}, "panic: index 3 out of range [0:3]\n"},
+
+		{"negative index", func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block {
+			_, block = EmitIndexAddress(fnc, block, slice, i64(-1))
+			return block
+		}, "panic: index -1 out of range [0:3]\n"},
+
+		{"low > high", func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block {
+			_, block = EmitSlice(fnc, block, slice, i64(2), i64(1), i64(3))
+			return block
+		}, "panic: slice bounds [2:1:3] out of range with capacity 3\n"},
+
+		{"max > cap", func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block {
+			_, block = EmitSlice(fnc, block, slice, i64(0), i64(1), i64(4))
+			return block
+		}, "panic: slice bounds [0:1:4] out of range with capacity 3\n"},
+
+		{"index past a slice, inside its buffer", func(fnc *ir.Func, block *ir.Block, slice value.Value) *ir.Block {
+			slice, block = EmitSlice(fnc, block, slice, i64(0), i64(1), SliceCapacity(block, slice))
+			_, block = EmitIndexAddress(fnc, block, slice, i64(1))
+			return block
+		}, "panic: index 1 out of range [0:1]\n"},
+	}
+
+	for _, c := range cases {
+		output, exit := run(t, sliceProgram(3, c.build))
+		if exit != 2 || output != c.message {
+			t.Errorf("%s: exit code %d, printed %q, expected %q", c.name, exit, output, c.message)
+		}
+	}
+}
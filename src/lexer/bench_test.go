@@ -0,0 +1,50 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// this file only uses Lex, so it can be copied into older versions of the lexer
+// (before New and Next existed) to compare them with the same input
+
+// benchmarkSource is about 190 KB of code using most of the lexer
+var benchmarkSource = strings.Repeat(`# a line comment
+/* a block comment
+   spanning lines */
+fn fib(int n) int {
+	if (n <= 1) {
+		return n
+	}
+	return fib(n - 1) + fib(n - 2)
+}
+
+fn main() {
+	var int count; 0x_FF + 0b1010 + 0o17 + 1_000_000
+	var float ratio; 3.25e-2f + .5
+	var uint mask; 42u &^ 7u
+	var string greeting; "hello\tworld\né\x41 日本"
+	var string raw; `+"`"+`raw \n string
+spanning lines`+"`"+`
+	var char c; '\''
+	while (count < 10 && !(ratio >= 1.0)) {
+		count += 1
+		mask <<= 2
+	}
+	print(greeting, raw, c, fib(count))
+}
+
+`, 360)
+
+// BenchmarkLex is the []rune API: the whole file as runes in, all of its tokens out
+func BenchmarkLex(b *testing.B) {
+	print2.OutputErrorMessages = false
+	b.SetBytes(int64(len(benchmarkSource)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		Lex([]rune(benchmarkSource), "bench.tod")
+	}
+}
@@ -0,0 +1,66 @@
+package lexer
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Identifiers follow the default identifier syntax of UAX #31 (Unicode Identifier and Pattern Syntax):
+//
+//	identifier = start continue*
+//	start      = ID_Start | "_"
+//	continue   = ID_Continue
+//
+// Names are normalized to NFC, so an "é" typed as one code point and one typed
+// as "e" + combining accent are the same name
+
+// isIdentifierStart checks if c can start an identifier (ID_Start or '_')
+func isIdentifierStart(c rune) bool {
+	if c == '_' {
+		return true
+	}
+
+	if isPatternCharacter(c) {
+		return false
+	}
+
+	return unicode.In(c, unicode.L, unicode.Nl, unicode.Other_ID_Start)
+}
+
+// isIdentifierContinue checks if c can be part of an identifier after the first character (ID_Continue)
+func isIdentifierContinue(c rune) bool {
+	if isIdentifierStart(c) {
+		return true
+	}
+
+	if isPatternCharacter(c) {
+		return false
+	}
+
+	return unicode.In(c, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue)
+}
+
+// isPatternCharacter checks if c is reserved for syntax (like operators and whitespace), these are never part of an identifier
+func isPatternCharacter(c rune) bool {
+	return unicode.In(c, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+// normalizeIdentifier returns the NFC form of an identifier.
+// ASCII is always in NFC, checking that is cheaper than asking norm (which allocates)
+func normalizeIdentifier(name string) string {
+	if isASCII(name) || norm.NFC.IsNormalString(name) {
+		return name
+	}
+	return norm.NFC.String(name)
+}
+
+func isASCII(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -1,396 +1,1239 @@
 package lexer
 
 import (
+	"io"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/NikoMalik/Tod-go-compiler/src/print2"
 	"github.com/NikoMalik/Tod-go-compiler/src/token"
 )
 
+// how many bytes are read from the reader at once
+const chunkSize = 4096
+
 // Lexer : Lexer struct for lexing :GentlemenSphere:
+// The lexer pulls the code from a reader as it goes and hands out one token at a time (see Next())
 type Lexer struct {
-	Code                  []rune
-	File                  string
-	Line                  int
-	Column                int
-	Index                 int
-	Tokens                []token.Token
+	Options
+	File  *token.File // the file in token.Files, this is where token positions come from
+	Index int         // byte offset into the file
+
+	reader  io.Reader
+	readErr error
+	source  []byte // all the code read so far
+
+	lines      []string // the lines handed over for error messages so far
+	remembered int      // where the first line that hasn't been handed over starts
+
+	pending  []token.Token // lexed but not yet handed out (from head on)
+	head     int
+	last     token.TokenType // type of the last token (comments don't count)
+	finished bool
+
+	leading  triviaList // trivia waiting for the next token
+	trailing int        // index of the pending token collecting trailing trivia, -1 if none
+	after    triviaList // the trailing trivia collected for that token
+
+	conditionals []conditional   // the #if blocks we are in
+	defines      map[string]bool // names defined with #define
+
+	interpolations []interpolation // the interpolated strings we're in the embedded expressions of
+}
+
+// interpolation is an interpolated string we're in an embedded expression of
+type interpolation struct {
+	start  token.Pos // where the string starts, for errors
+	braces int       // how many braces are open in the expression
+}
+
+// Options : optional behaviour of the lexer
+type Options struct {
+	// treat '#' as the start of a line comment
 	TreatHashtagAsComment bool
+
+	// emit doc comments ("/// ..." and "/** ... */") as COMMENT tokens instead of skipping them
+	EmitDocComments bool
+
+	// insert semicolons at line ends like go does, see insertSemicolon()
+	InsertSemicolons bool
+
+	// names that count as defined in #if and #elif conditions
+	Defines map[string]bool
+}
+
+// New creates a lexer for the code of the given file, the code is read from r
+// bit by bit while tokens are being asked for
+func New(r io.Reader, filename string) *Lexer {
+	return &Lexer{
+		Options:  Options{TreatHashtagAsComment: true},
+		File:     token.Files.AddFile(filename),
+		Index:    0,
+		reader:   r,
+		source:   make([]byte, 0, chunkSize),
+		pending:  make([]token.Token, 0, 4),
+		trailing: -1,
+	}
 }
 
 // Lex takes a filename and converts it into its respective lexical tokens
 func Lex(code []rune, filename string) []token.Token {
-	return LexInternal(code, filename, true)
+	return LexInternal(code, filename, Options{TreatHashtagAsComment: true})
+}
+
+// LexInternal converts code into tokens based on the filename and the given lexer options,
+// the options also carry the names defined for #if directives
+func LexInternal(code []rune, filename string, options Options) []token.Token {
+	scanner := New(strings.NewReader(string(code)), filename)
+	scanner.Options = options
+
+	tokens := make([]token.Token, 0)
+	for {
+		tok := scanner.Next()
+		tokens = append(tokens, tok)
+
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
 }
 
-// LexInternal converts code into tokens based on the filename and treatment of hashtags as comments
-func LexInternal(code []rune, filename string, treatHashtagsAsComments bool) []token.Token {
-	scanner := &Lexer{
-		Code:                  code,
-		File:                  filename,
-		Line:                  1,
-		Column:                1,
-		Index:                 0,
-		Tokens:                make([]token.Token, 0),
-		TreatHashtagAsComment: treatHashtagsAsComments,
+// Next lexes and returns the next token.
+// Once the end of the file is reached it keeps returning EOF tokens
+func (lxr *Lexer) Next() token.Token {
+	for lxr.head == len(lxr.pending) {
+		// everything has been handed out, reuse the queue
+		lxr.pending = lxr.pending[:0]
+		lxr.head = 0
+		lxr.scan()
 	}
 
-	RememberSourceFile(code, filename)
+	tok := lxr.pending[lxr.head]
+	lxr.head++
+	return tok
+}
+
+// scan lexes whatever comes next, this may produce any number of tokens
+// (whitespace produces none, a line break may produce an inserted semicolon)
+func (lxr *Lexer) scan() {
+	if lxr.done() {
+		lxr.insertSemicolon()
+		lxr.emit(token.CreateTokenReal("\000", nil, token.EOF, lxr.pos(), lxr.pos()))
 
-	for scanner.Index < len(scanner.Code) {
-		c := scanner.Code[scanner.Index]
+		// now that we've seen everything, offload it for error messages
+		if !lxr.finished {
+			lxr.finished = true
+			lxr.rememberLines(len(lxr.source))
+			lxr.closeConditionals()
 
-		peek := func(offset int) rune {
-			if scanner.Index+offset < len(scanner.Code) {
-				return scanner.Code[scanner.Index+offset]
+			for _, open := range lxr.interpolations {
+				lxr.reportError(
+					print2.UnexpectedCharacterError,
+					lxr.File.Span(open.start, open.start+1),
+					"interpolated string is not terminated! Expected } closing the embedded expression (StringToken)",
+				)
 			}
-			return '\000'
+		}
+		return
+	}
+
+	if lxr.atDirective() {
+		lxr.getDirective()
+		return
+	}
+
+	c := lxr.peek(0)
+
+	// code left out by #if/#elif/#else doesn't make any tokens
+	if lxr.skipping() {
+		if isWhitespace(c) {
+			lxr.getWhitespace()
+		} else {
+			lxr.skipLine()
+		}
+		return
+	}
+
+	peek := lxr.peek
+	count := len(lxr.pending)
+
+	if isIdentifierStart(c) {
+		lxr.getId()
+	} else if isDecimalDigit(c) || (c == '.' && isDecimalDigit(peek(1))) {
+		lxr.getNumber()
+	} else if c == '"' {
+		lxr.getString()
+	} else if lxr.closesInterpolation() {
+		lxr.resumeString()
+	} else if c == '\'' {
+		lxr.getChar()
+	} else if c == '`' {
+		lxr.getRawString()
+	} else if c == '/' && peek(1) == '/' ||
+		(lxr.TreatHashtagAsComment && c == '#') {
+		lxr.getComment()
+	} else if c == '/' && peek(1) == '*' {
+		lxr.getBlockComment()
+	} else if c == '\n' {
+		lxr.getNewline()
+	} else if isWhitespace(c) {
+		lxr.getWhitespace()
+	} else {
+		lxr.getOperator()
+	}
+
+	// whatever follows a token on its line belongs to it
+	if len(lxr.pending) > count {
+		lxr.getTrailingTrivia()
+	}
+}
+
+// getTrailingTrivia collects the whitespace and comments after a token up to
+// (and including) the end of the line, or up to the next token
+func (lxr *Lexer) getTrailingTrivia() {
+	lxr.trailing = len(lxr.pending) - 1
+	defer func() { lxr.trailing = -1 }()
+
+	line := lxr.File.LineCount()
+	for !lxr.done() && lxr.File.LineCount() == line {
+		c, peek := lxr.peek(0), lxr.peek
+
+		// doc comments are tokens, not trivia
+		if lxr.EmitDocComments && lxr.isDocComment() {
+			break
 		}
 
-		if unicode.IsLetter(c) {
-			scanner.getId()
-		} else if unicode.IsNumber(c) {
-			scanner.getNumber()
-		} else if c == '"' || c == '\'' {
-			scanner.getString()
-		} else if c == '/' && peek(1) == '/' ||
-			(scanner.TreatHashtagAsComment && c == '#') {
-			scanner.getComment()
-		} else if c != ' ' && c != '\n' && c != '\t' && c != '\v' {
-			scanner.getOperator()
+		if c == '/' && peek(1) == '/' || (lxr.TreatHashtagAsComment && c == '#') {
+			lxr.getComment()
+		} else if c == '/' && peek(1) == '*' {
+			lxr.getBlockComment()
+		} else if c == '\n' {
+			lxr.getNewline()
+		} else if isWhitespace(c) {
+			lxr.getWhitespace()
 		} else {
-			scanner.Increment()
+			break
 		}
 	}
 
-	scanner.Tokens = append(scanner.Tokens, token.CreateToken("\000", token.EOF))
-	return scanner.Tokens
+	tok := &lxr.pending[lxr.trailing]
+	tok.TrailingTrivia = lxr.after.take()
+	tok.SpaceAfter = len(tok.TrailingTrivia) > 0
 }
 
-// getNumber keeps getting bytes until it finds a non-number
+// emit hands a finished token to the queue of tokens waiting for Next()
+func (lxr *Lexer) emit(tok token.Token) {
+	tok.LeadingTrivia = lxr.leading.take()
+
+	if tok.Type != token.COMMENT {
+		lxr.last = tok.Type
+	}
+
+	if depth := len(lxr.interpolations) - 1; depth >= 0 && tok.Type == token.LBRACE {
+		lxr.interpolations[depth].braces++
+	} else if depth >= 0 && tok.Type == token.RBRACE {
+		lxr.interpolations[depth].braces--
+	}
+	lxr.pending = append(lxr.pending, tok)
+}
+
+// getNumber reads a full numeric literal, this includes base prefixes (0x, 0b, 0o),
+// fractions, exponents (1e9, 0x1p-2), digit separators and type suffixes (u, f)
 // then it generates an integer (or a float) token and slaps it back to the lexer.
+//
+// Without a suffix integers are INT and anything with a fraction or exponent is FLOAT64,
+// a "u" suffix makes an integer UINT and an "f" suffix makes any number FLOAT32.
 func (lxr *Lexer) getNumber() {
-	buffer := string(lxr.Code[lxr.Index])
-	lxr.Increment()
+	start := lxr.pos()
+	base := 10
+	isFloat := false
+	hasExponent := false
+
+	// base prefix
+	if lxr.peek(0) == '0' {
+		switch unicode.ToLower(lxr.peek(1)) {
+		case 'x':
+			base = 16
+		case 'b':
+			base = 2
+		case 'o':
+			base = 8
+		}
 
-	if lxr.Code[lxr.Index] == 'x' {
+		if base != 10 {
+			lxr.Increment()
+			lxr.Increment()
+		}
+	}
+
+	// integer part
+	digitsStart := lxr.Index
+	invalidDigit := lxr.getDigits(base)
+	hasDigits := lxr.Index > digitsStart
+
+	// fraction
+	if lxr.peek(0) == '.' && (base == 10 || base == 16) {
+		isFloat = true
 		lxr.Increment()
-		lxr.getNumberHex()
-		return
+
+		fractionStart := lxr.Index
+		if digit := lxr.getDigits(base); invalidDigit == 0 {
+			invalidDigit = digit
+		}
+		hasDigits = hasDigits || lxr.Index > fractionStart
 	}
 
-	if lxr.Code[lxr.Index] == 'b' {
+	// exponent
+	exponent := unicode.ToLower(lxr.peek(0))
+	if (base == 10 && exponent == 'e') || (base == 16 && exponent == 'p') {
+		isFloat = true
+		hasExponent = true
+		lxr.Increment()
+
+		if lxr.peek(0) == '+' || lxr.peek(0) == '-' {
+			lxr.Increment()
+		}
+
+		exponentStart := lxr.Index
+		lxr.getDigits(10)
+		if lxr.Index == exponentStart {
+			lxr.numberError(start, "exponent has no digits!")
+		}
+	}
+
+	literal := lxr.text(start)
+
+	// suffix, anything glued to the number counts as one
+	suffixStart := lxr.pos()
+	for !lxr.done() && (unicode.IsLetter(lxr.peek(0)) || isDecimalDigit(lxr.peek(0)) || lxr.peek(0) == '_') {
 		lxr.Increment()
-		lxr.getNumberBinary()
-		return
 	}
+	suffix := lxr.text(suffixStart)
+	buffer := literal + suffix
 
-	isDigitOrDotOrUnderScore := func(c rune) bool {
-		return unicode.IsDigit(c) || c == '.' || c == '_'
+	if !hasDigits {
+		lxr.numberError(start, "value \"%s\" has no digits!", buffer)
+	} else if invalidDigit != 0 {
+		lxr.numberError(start, "invalid digit '%c' in base %d literal \"%s\"!", invalidDigit, base, buffer)
+	} else if base == 16 && isFloat && !hasExponent {
+		lxr.numberError(start, "hexadecimal float \"%s\" needs a 'p' exponent!", buffer)
+	} else if index := invalidSeparator(literal, base); index >= 0 {
+		lxr.numberError(start, "'_' must separate successive digits in \"%s\"!", buffer)
 	}
 
-	for lxr.Index < len(lxr.Code) && isDigitOrDotOrUnderScore(lxr.Code[lxr.Index]) {
-		if lxr.Code[lxr.Index] != '_' {
-			buffer += string(lxr.Code[lxr.Index])
+	tokenType := token.INT
+	if isFloat {
+		tokenType = token.FLOAT64
+	}
+
+	switch suffix {
+	case "":
+	case "u":
+		if isFloat {
+			lxr.numberError(start, "float value \"%s\" can't have the unsigned suffix 'u'!", buffer)
 		}
-		lxr.Increment()
+		tokenType = token.UINT
+	case "f":
+		if base == 2 || base == 8 {
+			lxr.numberError(start, "base %d value \"%s\" can't have the float suffix 'f'!", base, buffer)
+		}
+		tokenType = token.FLOAT32
+	default:
+		lxr.numberError(start, "invalid suffix \"%s\" on number \"%s\"!", suffix, buffer)
 	}
 
-	if strings.Contains(buffer, ".") {
-		realValueBuffer, err := strconv.ParseFloat(strings.ReplaceAll(buffer, "_", ""), 32)
-		if err != nil {
-			print2.Error(
-				"LEXER",
-				print2.RealValueConversionError,
-				lxr.GetCurrentTextSpan(len(buffer)),
-				"value \"%s\" could not be converted to real value [float] (NumberToken)!",
-				buffer,
-			)
+	lxr.emit(token.CreateTokenReal(buffer, lxr.getNumberValue(literal, base, tokenType, lxr.spanSince(start)), tokenType, start, lxr.pos()))
+}
+
+// getDigits reads digits and separators of the given base
+// digits that are too big for the base are still read, the first of them is returned
+func (lxr *Lexer) getDigits(base int) rune {
+	var invalid rune
+	limit := 10
+	if base == 16 {
+		limit = 16
+	}
+
+	for !lxr.done() {
+		c := lxr.peek(0)
+		if c != '_' && digitValue(c) >= limit {
+			break
 		}
 
-		lxr.Tokens = append(lxr.Tokens, token.CreateTokenReal(buffer, float32(realValueBuffer), token.FLOAT32, lxr.GetCurrentTextSpan(len(buffer))))
+		if c != '_' && digitValue(c) >= base && invalid == 0 {
+			invalid = c
+		}
+		lxr.Increment()
+	}
+	return invalid
+}
 
-	} else {
-		realValueBuffer, err := strconv.Atoi(strings.ReplaceAll(buffer, "_", ""))
-		if err != nil {
-			realerValueBuffer, err := strconv.ParseInt(strings.ReplaceAll(buffer, "_", ""), 10, 64)
-			if err != nil {
-				print2.Error(
-					"LEXER",
-					print2.RealValueConversionError,
-					lxr.GetCurrentTextSpan(len(buffer)),
-					"value \"%s\" could not be converted to real value [int] (NumberToken)!",
-					buffer,
-				)
-			}
-			lxr.Tokens = append(lxr.Tokens, token.CreateTokenReal(buffer, realerValueBuffer, token.INT, lxr.GetCurrentTextSpan(len(buffer))))
+// getNumberValue converts a validated literal into the real value of the given token type
+// values that don't fit their type are reported as errors
+func (lxr *Lexer) getNumberValue(literal string, base int, tokenType token.TokenType, span print2.TextSpan) interface{} {
+	clean := strings.ReplaceAll(literal, "_", "")
+	if base != 10 && tokenType != token.FLOAT32 && tokenType != token.FLOAT64 {
+		clean = clean[2:] // strip the prefix
+	}
+
+	var realValue interface{}
+	var err error
+
+	switch tokenType {
+	case token.INT:
+		var value int64
+		value, err = strconv.ParseInt(clean, base, 64)
+		realValue = int(value)
+	case token.UINT:
+		var value uint64
+		value, err = strconv.ParseUint(clean, base, 64)
+		realValue = uint(value)
+	case token.FLOAT32:
+		var value float64
+		value, err = strconv.ParseFloat(clean, 32)
+		realValue = float32(value)
+	case token.FLOAT64:
+		var value float64
+		value, err = strconv.ParseFloat(clean, 64)
+		realValue = value
+	}
+
+	if numError, ok := err.(*strconv.NumError); ok && numError.Err == strconv.ErrRange {
+		lxr.reportError(
+			print2.RealValueConversionError,
+			span,
+			"value \"%s\" is out of range for %s (NumberToken)!",
+			literal,
+			tokenType,
+		)
+	}
+
+	// syntax errors have already been reported while reading the literal
+
+	return realValue
+}
+
+// numberError reports a malformed number literal starting at the given position
+func (lxr *Lexer) numberError(start token.Pos, message string, fargs ...interface{}) {
+	lxr.reportError(
+		print2.RealValueConversionError,
+		lxr.spanSince(start),
+		message+" (NumberToken)",
+		fargs...,
+	)
+}
+
+// invalidSeparator returns the index of the first '_' in a number literal
+// that doesn't sit between two digits (or right after a base prefix), or -1
+func invalidSeparator(literal string, base int) int {
+	isDigit := func(i int) bool {
+		return i >= 0 && i < len(literal) && digitValue(rune(literal[i])) < max(base, 10)
+	}
+
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '_' {
+			continue
+		}
+
+		afterPrefix := i == 2 && base != 10
+		if (!isDigit(i-1) && !afterPrefix) || !isDigit(i+1) {
+			return i
 		}
-		lxr.Tokens = append(lxr.Tokens, token.CreateTokenReal(buffer, realValueBuffer, token.INT, lxr.GetCurrentTextSpan(len(buffer))))
 	}
+	return -1
 }
 
-// getNumberHex keeps getting bytes until it finds a character that isn't 0-9 or A-F
-// then it generates an integer token and slaps it back to the lexer.
-func (lxr *Lexer) getNumberHex() {
-	allowedChars := "abcdefABCDEF"
+// getString keeps getting bytes until it finds the end of the string
+// then it generates a string token and slaps it back to the lexer.
+// The literal keeps the raw source text, the real value has all escapes decoded.
+func (lxr *Lexer) getString() {
+	start := lxr.pos()
+	lxr.Increment() // "
+	lxr.getStringPart(start, token.NoPos)
+}
 
-	buffer := string(lxr.Code[lxr.Index])
-	lxr.Increment()
+// resumeString continues an interpolated string after the "}" closing an embedded expression
+func (lxr *Lexer) resumeString() {
+	stringStart := lxr.interpolations[len(lxr.interpolations)-1].start
+	lxr.interpolations = lxr.interpolations[:len(lxr.interpolations)-1]
 
-	isDigitOrAllowedLetter := func(c rune) bool {
-		return unicode.IsDigit(c) || strings.ContainsRune(allowedChars, c)
+	start := lxr.pos()
+	lxr.Increment() // }
+	lxr.getStringPart(start, stringStart)
+}
+
+// getStringPart reads a string up to the closing quote or up to the next "${".
+// A "${" starts an embedded expression, the expression is lexed like any other code
+// until the matching "}" continues the string (see resumeString()).
+// If we're continuing an interpolated string stringStart is where it started.
+func (lxr *Lexer) getStringPart(start token.Pos, stringStart token.Pos) {
+	resumed := stringStart.IsValid()
+	if !resumed {
+		stringStart = start
 	}
 
-	for lxr.Index < len(lxr.Code) && isDigitOrAllowedLetter(lxr.Code[lxr.Index]) {
-		if lxr.Code[lxr.Index] != '_' {
-			buffer += string(lxr.Code[lxr.Index])
+	var value strings.Builder
+	terminated := false
+	interpolating := false
+
+	for !lxr.done() {
+		c := lxr.peek(0)
+
+		if c == '"' {
+			lxr.Increment()
+			terminated = true
+			break
+		}
+
+		// strings can't span multiple lines
+		if c == '\n' {
+			break
+		}
+
+		if c == '$' && lxr.peek(1) == '{' {
+			lxr.Increment()
+			lxr.Increment()
+			interpolating = true
+			break
+		}
+
+		if c == '\\' {
+			escaped, isByte, ok := lxr.getEscapeSequence('"')
+			if ok && isByte {
+				value.WriteByte(byte(escaped))
+			} else if ok {
+				value.WriteRune(escaped)
+			}
+			continue
 		}
+
+		value.WriteRune(c)
 		lxr.Increment()
 	}
 
-	realValueBuffer, err := strconv.ParseInt(strings.ReplaceAll(buffer, "_", ""), 16, 32)
-	if err != nil {
-		print2.Error(
-			"LEXER",
-			print2.RealValueConversionError,
-			lxr.GetCurrentTextSpan(len(buffer)),
-			"hex value \"%s\" could not be converted to real value [int] (NumberToken)!",
+	buffer := lxr.text(start)
+
+	if !terminated && !interpolating {
+		lxr.reportError(
+			print2.UnexpectedCharacterError,
+			lxr.spanSince(start),
+			"string literal %s is not terminated! Expected closing \" (StringToken)",
 			buffer,
 		)
 	}
-	lxr.Tokens = append(lxr.Tokens, token.CreateTokenReal(buffer, int(realValueBuffer), token.INT, lxr.GetCurrentTextSpan(len(buffer))))
+
+	tokenType := token.STRING
+	if interpolating && !resumed {
+		tokenType = token.INTERPOLATION_START
+	} else if interpolating {
+		tokenType = token.INTERPOLATION_MIDDLE
+	} else if resumed {
+		tokenType = token.INTERPOLATION_END
+	}
+
+	// keep track of the braces in the embedded expression, so we know which "}" ends it
+	if interpolating {
+		lxr.interpolations = append(lxr.interpolations, interpolation{start: stringStart})
+	}
+
+	lxr.emit(token.CreateTokenReal(buffer, value.String(), tokenType, start, lxr.pos()))
 }
 
-// getNumberBinary keeps getting bytes until it finds a character that isn't 0 or 1
-// then it generates an integer token and slaps it back to the lexer.
-func (lxr *Lexer) getNumberBinary() {
-	buffer := string(lxr.Code[lxr.Index])
+// closesInterpolation checks if a "}" here ends an embedded expression
+func (lxr *Lexer) closesInterpolation() bool {
+	return lxr.peek(0) == '}' &&
+		len(lxr.interpolations) > 0 && lxr.interpolations[len(lxr.interpolations)-1].braces == 0
+}
+
+// getChar reads a character literal like 'a' or '\n'
+// then it generates a char token holding a rune and slaps it back to the lexer.
+func (lxr *Lexer) getChar() {
+	start := lxr.pos()
 	lxr.Increment()
 
-	for lxr.Index < len(lxr.Code) && (lxr.Code[lxr.Index] == '0' ||
-		lxr.Code[lxr.Index] == '1') {
-		if lxr.Code[lxr.Index] != '_' {
-			buffer += string(lxr.Code[lxr.Index])
+	var value rune
+	characters := 0
+	terminated := false
+
+	for !lxr.done() {
+		c := lxr.peek(0)
+
+		if c == '\'' {
+			lxr.Increment()
+			terminated = true
+			break
 		}
-		lxr.Increment()
+
+		// chars can't span multiple lines
+		if c == '\n' {
+			break
+		}
+
+		if c == '\\' {
+			escaped, _, ok := lxr.getEscapeSequence('\'')
+			if ok {
+				value = escaped
+			}
+		} else {
+			value = c
+			lxr.Increment()
+		}
+		characters++
 	}
 
-	realValueBuffer, err := strconv.ParseInt(strings.ReplaceAll(buffer, "_", ""), 2, 32)
-	if err != nil {
-		print2.Error(
-			"LEXER",
-			print2.RealValueConversionError,
-			lxr.GetCurrentTextSpan(len(buffer)),
-			"binary value \"%s\" could not be converted to real value [int] (NumberToken)!",
+	buffer := lxr.text(start)
+
+	if !terminated {
+		lxr.reportError(
+			print2.UnexpectedCharacterError,
+			lxr.spanSince(start),
+			"char literal %s is not terminated! Expected closing ' (CharToken)",
 			buffer,
 		)
+	} else if characters != 1 {
+		lxr.reportError(
+			print2.UnexpectedCharacterError,
+			lxr.spanSince(start),
+			"char literal %s must contain exactly one character, found %d! (CharToken)",
+			buffer,
+			characters,
+		)
 	}
-	lxr.Tokens = append(lxr.Tokens, token.CreateTokenReal(buffer, int(realValueBuffer), token.INT, lxr.GetCurrentTextSpan(len(buffer))))
+
+	lxr.emit(token.CreateTokenReal(buffer, value, token.CHAR, start, lxr.pos()))
 }
 
-// getString keeps getting bytes until it finds the end of the string
+// getRawString keeps getting bytes until it finds the closing backtick
 // then it generates a string token and slaps it back to the lexer.
-func (lxr *Lexer) getString() {
-	buffer := string(lxr.Code[lxr.Index])
-	startingCharacter := buffer
+// Raw strings may span multiple lines and don't process any escapes.
+func (lxr *Lexer) getRawString() {
+	start := lxr.pos()
 	lxr.Increment()
 
-	for lxr.Index < len(lxr.Code) && string(lxr.Code[lxr.Index]) != startingCharacter {
-		buffer += string(lxr.Code[lxr.Index])
+	var value strings.Builder
+	terminated := false
+
+	for !lxr.done() {
+		c := lxr.peek(0)
 		lxr.Increment()
+
+		if c == '`' {
+			terminated = true
+			break
+		}
+
+		// carriage returns are dropped so the value doesn't depend on line endings
+		if c != '\r' {
+			value.WriteRune(c)
+		}
+	}
+
+	buffer := lxr.text(start)
+	span := lxr.spanSince(start)
+
+	if !terminated {
+		lxr.reportError(
+			print2.UnexpectedCharacterError,
+			span,
+			"raw string literal is not terminated! Expected closing ` (StringToken)",
+		)
 	}
-	buffer += string(lxr.Code[lxr.Index])
+
+	lxr.emit(token.CreateTokenReal(buffer, value.String(), token.STRING, start, lxr.pos()))
+}
+
+// getEscapeSequence decodes the escape sequence starting at the current backslash.
+// It's shared between string and char literals, quote is the delimiter that may be escaped.
+// isByte is set for \x and octal escapes, those stand for a single byte and not for a code point.
+// If the escape is invalid an error is reported and ok is false.
+func (lxr *Lexer) getEscapeSequence(quote rune) (value rune, isByte bool, ok bool) {
+	start := lxr.pos()
+	lxr.Increment() // \
+
+	if lxr.done() || lxr.peek(0) == '\n' {
+		lxr.escapeError(start, "escape sequence is not terminated! (StringToken)")
+		return 0, false, false
+	}
+
+	c := lxr.peek(0)
+
+	// \NNN - exactly three octal digits
+	if '0' <= c && c <= '7' {
+		digits, valid := lxr.getEscapeDigits(8, 3)
+		if !valid || digits > 255 {
+			lxr.escapeError(start, "octal escape sequence needs exactly 3 octal digits and must be below \\400! (StringToken)")
+			return 0, false, false
+		}
+		return rune(digits), true, true
+	}
+
 	lxr.Increment()
 
-	realValueBuffer := buffer[1 : len(buffer)-1]
+	switch c {
+	case 'a':
+		return '\a', false, true
+	case 'b':
+		return '\b', false, true
+	case 'f':
+		return '\f', false, true
+	case 'n':
+		return '\n', false, true
+	case 'r':
+		return '\r', false, true
+	case 't':
+		return '\t', false, true
+	case 'v':
+		return '\v', false, true
+	case '\\':
+		return '\\', false, true
+	case '$':
+		return '$', false, true
+	case '"', '\'':
+		if c != quote {
+			lxr.escapeError(start, "unknown escape sequence \"\\%c\"! (StringToken)", c)
+			return 0, false, false
+		}
+		return c, false, true
+
+	case 'x':
+		// \xNN - exactly two hex digits
+		digits, valid := lxr.getEscapeDigits(16, 2)
+		if !valid {
+			lxr.escapeError(start, "hex escape sequence needs exactly 2 hex digits! (StringToken)")
+			return 0, false, false
+		}
+		return rune(digits), true, true
+
+	case 'u':
+		// \u{N...} - one to six hex digits making up a code point
+		if lxr.peek(0) != '{' {
+			lxr.escapeError(start, "unicode escape sequence must look like \\u{...}! (StringToken)")
+			return 0, false, false
+		}
+		lxr.Increment()
+
+		digitStart := lxr.pos()
+		for !lxr.done() && isHexDigit(lxr.peek(0)) {
+			lxr.Increment()
+		}
+		digits := lxr.text(digitStart)
+
+		if lxr.peek(0) != '}' {
+			lxr.escapeError(start, "unicode escape sequence is missing its closing \"}\"! (StringToken)")
+			return 0, false, false
+		}
+		lxr.Increment()
+
+		if len(digits) == 0 || len(digits) > 6 {
+			lxr.escapeError(start, "unicode escape sequence needs 1 to 6 hex digits! (StringToken)")
+			return 0, false, false
+		}
+
+		codePoint, _ := strconv.ParseUint(digits, 16, 32)
+		if codePoint > unicode.MaxRune || (codePoint >= 0xD800 && codePoint < 0xE000) {
+			lxr.escapeError(start, "\"%s\" is not a valid unicode code point! (StringToken)", digits)
+			return 0, false, false
+		}
+		return rune(codePoint), false, true
+
+	}
+
+	lxr.escapeError(start, "unknown escape sequence \"\\%c\"! (StringToken)", c)
+	return 0, false, false
+}
+
+// getEscapeDigits reads exactly count digits in the given base
+func (lxr *Lexer) getEscapeDigits(base int, count int) (int, bool) {
+	value := 0
+	for i := 0; i < count; i++ {
+		if lxr.done() {
+			return 0, false
+		}
+
+		digit := digitValue(lxr.peek(0))
+		if digit >= base {
+			return 0, false
+		}
+
+		value = value*base + digit
+		lxr.Increment()
+	}
+	return value, true
+}
+
+// escapeError reports a bad escape sequence starting at the given position
+func (lxr *Lexer) escapeError(start token.Pos, message string, fargs ...interface{}) {
+	lxr.reportError(
+		print2.UnexpectedCharacterError,
+		lxr.spanSince(start),
+		message,
+		fargs...,
+	)
+}
+
+// digitValue returns the numeric value of a digit in any base up to 16
+// anything that isn't a digit gets a value of 16
+func digitValue(c rune) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 10)
+	case 'A' <= c && c <= 'F':
+		return int(c - 'A' + 10)
+	}
+	return 16
+}
+
+func isDecimalDigit(c rune) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c rune) bool {
+	return digitValue(c) < 16
+}
 
-	lxr.Tokens = append(lxr.Tokens, token.CreateTokenReal(buffer, realValueBuffer, token.STRING, lxr.GetCurrentTextSpan(len(buffer))))
+// the keywords the lexer reserves, the rest of the keyword table (main, type, ...)
+// is lexed as regular identifiers so those words can still be used as names
+var reservedKeywords = map[token.TokenType]bool{
+	token.FN:       true,
+	token.RETURN:   true,
+	token.VAR:      true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.IF:       true,
+	token.ELSE:     true,
+	token.TRUE:     true,
+	token.FALSE:    true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+	token.STRUCT:   true,
+	token.EXTERNAL: true,
+	token.PACKAGE:  true,
+	token.SET:      true,
+	token.USING:    true,
+	token.IMPORT:   true,
+	token.MAKE:     true,
+	token.MAP:      true,
+	token.RANGE:    true,
 }
 
 // getId checks if an identifier is a keyword or a regular identifier
 // then it generates a token and slaps it back to the lexer.
+// The literal keeps the identifier as it's written, the real value is its NFC form if that's different (see identifiers.go)
 func (lxr *Lexer) getId() {
-	buffer := string(lxr.Code[lxr.Index])
+	start := lxr.pos()
 	lxr.Increment()
 
-	for lxr.Index < len(lxr.Code) &&
-		(unicode.IsLetter(lxr.Code[lxr.Index]) || unicode.IsNumber(lxr.Code[lxr.Index]) ||
-			lxr.Code[lxr.Index] == '_' || lxr.Code[lxr.Index] == '$') {
-		buffer += string(lxr.Code[lxr.Index])
+	for !lxr.done() && isIdentifierContinue(lxr.peek(0)) {
 		lxr.Increment()
 	}
 
-	switch buffer {
-	case "fn":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.FN))
-	case "return":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.RETURN))
-	case "var":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.VAR))
-	case "while":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.WHILE))
-	case "for":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.FOR))
-	case "if":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.IF))
-	case "else":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.ELSE))
-	case "true":
-		lxr.Tokens = append(lxr.Tokens, token.CreateTokenReal(buffer, true, token.TRUE, lxr.GetCurrentTextSpan(len(buffer))))
-	case "false":
-		lxr.Tokens = append(lxr.Tokens, token.CreateTokenReal(buffer, false, token.FALSE, lxr.GetCurrentTextSpan(len(buffer))))
+	buffer := lxr.text(start)
+	name := normalizeIdentifier(buffer)
+
+	tokenType := token.LookupIdent(name)
+	if tokenType.IsKeyword() && !reservedKeywords[tokenType] {
+		tokenType = token.IDENT
+	}
+
+	switch tokenType {
+	case token.TRUE:
+		lxr.emit(token.CreateTokenReal(buffer, true, token.TRUE, start, lxr.pos()))
+	case token.FALSE:
+		lxr.emit(token.CreateTokenReal(buffer, false, token.FALSE, start, lxr.pos()))
+	case token.IDENT:
+		// the real value is only needed if normalizing changed the name, see Token.Name()
+		var real interface{}
+		if name != buffer {
+			real = name
+		}
+		lxr.emit(token.CreateTokenReal(buffer, real, token.IDENT, start, lxr.pos()))
 	default:
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.IDENT))
+		// every other reserved keyword (break, continue, ...)
+		lxr.emit(token.CreateTokenReal(buffer, nil, tokenType, start, lxr.pos()))
 	}
 }
 
-// getComment skips the comments
+// getComment skips line comments, doc comments ("/// ...") are turned into
+// COMMENT tokens if the lexer has been told to emit them
 func (lxr *Lexer) getComment() {
-	lxr.Increment()
-	for lxr.Index < len(lxr.Code) && lxr.Code[lxr.Index] != '\n' {
+	start := lxr.pos()
+	for !lxr.done() && lxr.peek(0) != '\n' {
 		lxr.Increment()
 	}
+
+	if lxr.EmitDocComments && lxr.hasPrefix(start, "///") {
+		buffer := lxr.text(start)
+		text := strings.TrimSpace(strings.TrimPrefix(buffer, "///"))
+		lxr.emit(token.CreateTokenReal(buffer, text, token.COMMENT, start, lxr.pos()))
+		return
+	}
+
+	lxr.addTrivia(token.LineCommentTrivia, start)
+}
+
+// getBlockComment skips block comments ("/* ... */"), these can be nested.
+// Doc comments ("/** ... */") are turned into COMMENT tokens if the lexer has been told to emit them
+func (lxr *Lexer) getBlockComment() {
+	start := lxr.pos()
+	depth := 0
+	multiLine := false
+
+	for !lxr.done() {
+		if lxr.peek(0) == '/' && lxr.peek(1) == '*' {
+			depth++
+			lxr.Increment()
+			lxr.Increment()
+		} else if lxr.peek(0) == '*' && lxr.peek(1) == '/' {
+			depth--
+			lxr.Increment()
+			lxr.Increment()
+
+			if depth == 0 {
+				break
+			}
+		} else {
+			multiLine = multiLine || lxr.peek(0) == '\n'
+			lxr.Increment()
+		}
+	}
+
+	if depth != 0 {
+		lxr.reportError(
+			print2.UnexpectedCharacterError,
+			lxr.spanSince(start),
+			"block comment is not terminated! Expected %d more closing */ (CommentToken)",
+			depth,
+		)
+		lxr.addTrivia(token.BlockCommentTrivia, start)
+		return
+	}
+
+	// a block comment spanning lines acts like a line break
+	if multiLine {
+		lxr.insertSemicolon()
+	}
+
+	// "/**/" is an empty comment and not the start of a doc comment
+	if lxr.EmitDocComments && lxr.hasPrefix(start, "/**") && lxr.Index-lxr.File.Offset(start) > 4 {
+		buffer := lxr.text(start)
+		text := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(buffer, "/**"), "*/"))
+		lxr.emit(token.CreateTokenReal(buffer, text, token.COMMENT, start, lxr.pos()))
+		return
+	}
+
+	lxr.addTrivia(token.BlockCommentTrivia, start)
+}
+
+// isDocComment checks if a doc comment ("/// ..." or "/** ... */") starts here
+func (lxr *Lexer) isDocComment() bool {
+	if lxr.peek(0) != '/' {
+		return false
+	}
+
+	// "/**/" is an empty comment and not the start of a doc comment
+	return lxr.peek(1) == '/' && lxr.peek(2) == '/' ||
+		lxr.peek(1) == '*' && lxr.peek(2) == '*' && lxr.peek(3) != '/'
+}
+
+// getNewline reads a line break, which might end the statement (see insertSemicolon())
+func (lxr *Lexer) getNewline() {
+	start := lxr.pos()
+	lxr.insertSemicolon()
 	lxr.Increment()
+	lxr.addTrivia(token.NewlineTrivia, start)
+}
+
+// getWhitespace reads whitespace up to the next line break
+func (lxr *Lexer) getWhitespace() {
+	start := lxr.pos()
+	for !lxr.done() && isWhitespace(lxr.peek(0)) {
+		lxr.Increment()
+	}
+	lxr.addTrivia(token.WhitespaceTrivia, start)
+}
+
+// addTrivia keeps the code since start as trivia, either as trailing trivia
+// of the token we're collecting it for or as leading trivia of the next token
+func (lxr *Lexer) addTrivia(kind token.TriviaKind, start token.Pos) {
+	text := lxr.source[lxr.File.Offset(start):lxr.Index]
+
+	// most trivia is a line break or a bit of indentation, those don't need a copy each
+	trivia := token.Trivia{Kind: kind}
+	if common, ok := commonTrivia[string(text)]; ok {
+		trivia.Text = common
+	} else {
+		trivia.Text = string(text)
+	}
+
+	if lxr.trailing >= 0 {
+		lxr.after.add(trivia)
+		return
+	}
+	lxr.leading.add(trivia)
+}
+
+// commonTrivia holds the texts of the trivia that comes up all the time: line breaks and
+// runs of up to 16 spaces or tabs
+var commonTrivia = func() map[string]string {
+	common := map[string]string{"\n": "\n", "\r\n": "\r\n"}
+	for n := 1; n <= 16; n++ {
+		for _, text := range []string{strings.Repeat(" ", n), strings.Repeat("\t", n)} {
+			common[text] = text
+		}
+	}
+	return common
+}()
+
+// triviaList collects trivia for one token at a time. The trivia of all tokens shares
+// backing arrays, so tokens don't need an allocation each for their trivia
+type triviaList struct {
+	items []token.Trivia
+	start int // where the trivia of the token being collected for starts
+}
+
+// the number of trivia that share a backing array
+const triviaChunk = 256
+
+// add adds trivia to the token being collected for
+func (list *triviaList) add(trivia token.Trivia) {
+	if len(list.items) == cap(list.items) {
+		// move the trivia collected so far over, it has to stay in one piece
+		chunk := make([]token.Trivia, 0, max(triviaChunk, 2*(len(list.items)-list.start)))
+		list.items = append(chunk, list.items[list.start:]...)
+		list.start = 0
+	}
+	list.items = append(list.items, trivia)
+}
+
+// take hands out the trivia collected so far and starts collecting for the next token.
+// The capacity is capped, so appending to the trivia of a token never overwrites another one's
+func (list *triviaList) take() []token.Trivia {
+	if list.start == len(list.items) {
+		return nil
+	}
+
+	taken := list.items[list.start:len(list.items):len(list.items)]
+	list.start = len(list.items)
+	return taken
+}
+
+// isWhitespace checks for whitespace other than line breaks
+func isWhitespace(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\v' || c == '\r'
+}
+
+// insertSemicolon is called at every line break (and at the end of the file).
+// If semicolon insertion is enabled a SEMICOLON token is inserted when the line
+// ends in a token that could end a statement (same rules as go):
+// an identifier, a literal, true/false, return, break, continue, ')', ']' or '}'
+func (lxr *Lexer) insertSemicolon() {
+	// embedded expressions in strings can span lines, but they're still part of the string
+	if !lxr.InsertSemicolons || len(lxr.interpolations) > 0 {
+		return
+	}
+
+	switch lxr.last {
+	case token.IDENT,
+		token.INT, token.UINT, token.FLOAT32, token.FLOAT64, token.STRING, token.CHAR, token.INTERPOLATION_END,
+		token.TRUE, token.FALSE,
+		token.RETURN, token.BREAK, token.CONTINUE,
+		token.RPAREN, token.RBRACK, token.RBRACE:
+	default:
+		return
+	}
+
+	// the semicolon sits on the line break
+	pos, end := lxr.pos(), lxr.pos()
+	if !lxr.done() {
+		end++
+	}
+
+	lxr.emit(token.CreateTokenReal("\n", nil, token.SEMICOLON, pos, end))
 }
 
 // getOperator gets all the operators (symbol-like things)
-// it also makes sure that they are correctly lexed in case of combination (==, etc)
+// it always takes the longest operator declared in the token table (maximal munch),
+// so "a+b" is "a" "+" "b" and "<<=" is a single token
 func (lxr *Lexer) getOperator() {
-	buffer := string(lxr.Code[lxr.Index])
+	start := lxr.pos()
+	lxr.ensure(lxr.Index + token.MaxOperatorLength)
 
-	for lxr.Index < len(lxr.Code) && (len(buffer) == 1 || buffer == ":") {
-		lxr.Increment()
-		if lxr.Index < len(lxr.Code) {
-			buffer += string(lxr.Code[lxr.Index])
-		}
-	}
-
-	switch buffer {
-	case "=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.ASSIGN))
-	case "+":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.ADD))
-	case "-":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.SUB))
-	case "*":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.MUL))
-	case "/":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.QUO))
-	case "%":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.REM))
-	case "&":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.AND))
-	case "|":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.OR))
-	case "^":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.XOR))
-	case "<<":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.SHL))
-	case ">>":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.SHR))
-	case "&^":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.AND_NOT))
-	case "&&":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.LAND))
-	case "||":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.LOR))
-	case "==":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.EQ))
-	case "!=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.NOT_EQ))
-	case "<":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.LT))
-	case "<=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.LEQ))
-	case ">":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.GT))
-	case ">=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.GEQ))
-	case "<=>":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.SPACESHIP))
-	case "!":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.BANG))
-	case "+=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.ADD_ASSIGN))
-	case "-=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.SUB_ASSIGN))
-	case "*=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.MUL_ASSIGN))
-	case "/=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.QUO_ASSIGN))
-	case "%=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.REM_ASSIGN))
-	case "&=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.AND_ASSIGN))
-	case "|=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.OR_ASSIGN))
-	case "^=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.XOR_ASSIGN))
-	case "<<=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.SHL_ASSIGN))
-	case ">>=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.SHR_ASSIGN))
-	case "&^=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.AND_NOT_ASSIGN))
-	case ",":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.COMMA))
-	case "(":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.LPAREN))
-	case ")":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.RPAREN))
-	case "{":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.LBRACE))
-	case "}":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.RBRACE))
-	case ".":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.PERIOD))
-	case "[":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.LBRACK))
-	case "]":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.RBRACK))
-	case ";":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.SEMICOLON))
-	case ":=":
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.DEFINE))
-	default:
-		lxr.Tokens = append(lxr.Tokens, token.CreateToken(buffer, token.ILLEGAL))
+	for length := min(token.MaxOperatorLength, len(lxr.source)-lxr.Index); length > 0; length-- {
+		if tokenType, ok := token.LookupOperator(string(lxr.source[lxr.Index : lxr.Index+length])); ok {
+			for i := 0; i < length; i++ {
+				lxr.Increment()
+			}
+
+			// the token table already has the text, no need to copy it
+			lxr.emit(token.CreateTokenReal(tokenType.String(), nil, tokenType, start, lxr.pos()))
+			return
+		}
 	}
+
+	// nothing matched, this character is garbage
+	lxr.Increment()
+	buffer := lxr.text(start)
+
+	lxr.reportError(
+		print2.UnexpectedCharacterError,
+		lxr.spanSince(start),
+		"an unexpected character was found \"%s\"! Lexer is unable to process this character! (BadToken)",
+		buffer,
+	)
+	lxr.emit(token.CreateTokenReal(buffer, nil, token.ILLEGAL, start, lxr.pos()))
 }
 
-// Increment increments the lexer index, column, and line
+// fill reads the next chunk of code from the reader
+// it returns false once there is nothing left to read
+func (lxr *Lexer) fill() bool {
+	if lxr.readErr != nil {
+		return false
+	}
+
+	lxr.source = slices.Grow(lxr.source, chunkSize)
+	n, err := lxr.reader.Read(lxr.source[len(lxr.source):cap(lxr.source)])
+	lxr.source = lxr.source[:len(lxr.source)+n]
+	lxr.File.Grow(len(lxr.source))
+
+	if err != nil {
+		lxr.readErr = err
+
+		if err != io.EOF {
+			print2.Error(
+				"LEXER",
+				print2.FileVoidError,
+				print2.TextSpan{},
+				"file \"%s\" could not be read: %s",
+				lxr.File.Name(),
+				err.Error(),
+			)
+		}
+	}
+
+	return n > 0 || err == nil
+}
+
+// ensure makes sure everything up to (excluding) the given byte index has been read,
+// unless the code is shorter than that
+func (lxr *Lexer) ensure(index int) {
+	for index > len(lxr.source) && lxr.fill() {
+	}
+}
+
+// done tells us if we've reached the end of the code
+func (lxr *Lexer) done() bool {
+	lxr.ensure(lxr.Index + 1)
+	return lxr.Index >= len(lxr.source)
+}
+
+// decode gets the character at the given byte index and its size in bytes
+// past the end of the code it returns '\000' with a size of 0
+func (lxr *Lexer) decode(index int) (rune, int) {
+	lxr.ensure(index + utf8.UTFMax)
+	if index >= len(lxr.source) {
+		return '\000', 0
+	}
+
+	if c := lxr.source[index]; c < utf8.RuneSelf {
+		return rune(c), 1
+	}
+	return utf8.DecodeRune(lxr.source[index:])
+}
+
+// peek looks at the character offset characters ahead without consuming anything
+func (lxr *Lexer) peek(offset int) rune {
+	index := lxr.Index
+	for ; offset > 0; offset-- {
+		_, size := lxr.decode(index)
+		if size == 0 {
+			return '\000'
+		}
+		index += size
+	}
+
+	c, _ := lxr.decode(index)
+	return c
+}
+
+// hasPrefix checks if the text from the given position on starts with prefix
+func (lxr *Lexer) hasPrefix(start token.Pos, prefix string) bool {
+	index := lxr.File.Offset(start)
+	lxr.ensure(index + len(prefix))
+	return strings.HasPrefix(string(lxr.source[index:min(index+len(prefix), len(lxr.source))]), prefix)
+}
+
+// text gets the code from the given position up to the current one
+func (lxr *Lexer) text(start token.Pos) string {
+	return string(lxr.source[lxr.File.Offset(start):lxr.Index])
+}
+
+// Increment increments the lexer index, line breaks are remembered in the file
 func (lxr *Lexer) Increment() {
-	if lxr.Code[lxr.Index] == '\n' {
-		lxr.Line++
-		lxr.Column = 1
-	} else {
-		lxr.Column++
+	c, size := lxr.decode(lxr.Index)
+	if size == 0 {
+		return
+	}
+
+	lxr.Index += size
+	if c == '\n' {
+		lxr.File.AddLine(lxr.Index)
 	}
-	lxr.Index++
 }
 
-// GetCurrentTextSpan gets the current span of text being processed
-func (lxr *Lexer) GetCurrentTextSpan(buffer int) print2.TextSpan {
-	return print2.TextSpan{
-		File: lxr.File,
+// pos gets the position we're at right now
+func (lxr *Lexer) pos() token.Pos {
+	return lxr.File.Pos(lxr.Index)
+}
+
+// spanSince gets the span from the given position up to the current one
+func (lxr *Lexer) spanSince(start token.Pos) print2.TextSpan {
+	return lxr.File.Span(start, lxr.pos())
+}
+
+// reportError reports an error in the code, since we might not have read
+// the whole line yet we do that first so the code snippet is complete
+func (lxr *Lexer) reportError(_type print2.ErrorType, span print2.TextSpan, message string, fargs ...interface{}) {
+	lxr.RememberSource()
+	print2.Error("LEXER", _type, span, message, fargs...)
+}
 
-		StartIndex: lxr.Index - buffer,
-		EndIndex:   lxr.Index,
+// RememberSource makes the code read so far (at least up to the end of the current line)
+// known for error messages
+func (lxr *Lexer) RememberSource() {
+	index := lxr.Index
+	for {
+		lxr.ensure(index + 1)
+		if index >= len(lxr.source) || lxr.source[index] == '\n' {
+			break
+		}
+		index++
+	}
 
-		StartLine: lxr.Line,
-		EndLine:   lxr.Line,
+	lxr.rememberLines(index)
+}
 
-		StartColumn: lxr.Column - buffer,
-		EndColumn:   lxr.Column,
+// rememberLines hands the lines up to end (the end of a line or of the file) over for error messages.
+// Lines handed over before aren't copied again, and the new ones are copied at once and then split,
+// so errors don't cost more the further into the file they are
+func (lxr *Lexer) rememberLines(end int) {
+	if lxr.remembered <= end {
+		lxr.lines = append(lxr.lines, strings.Split(string(lxr.source[lxr.remembered:end]), "\n")...)
+		lxr.remembered = end + 1
 	}
+
+	print2.CodeReference = lxr.lines
+	print2.SourceFiles[lxr.File.Name()] = lxr.lines
 }
 
 // RememberSourceFile remembers the source code for the given file
-func RememberSourceFile(contents []rune, filename string) {
+func RememberSourceFile(contents string, filename string) {
 	// Offload a copy of contents for error handling
 	// Also split at new lines because that makes referencing easier
-	print2.CodeReference = make([]string, 0)
-	print2.CodeReference = strings.Split(string(contents), "\n")
-	print2.SourceFiles[filename] = string(contents)
+	print2.CodeReference = strings.Split(contents, "\n")
+	print2.SourceFiles[filename] = print2.CodeReference
 }
@@ -0,0 +1,154 @@
+package lexer
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// equivalenceSource has a bit of everything, including multi-byte characters
+// that end up split between reads when the reader hands out a byte at a time
+const equivalenceSource = `package main
+/// a doc comment
+fn main() {
+	var string s; "日本 ${name + 1} é \x41"
+	var string r; ` + "`raw ${x}\nlines`" + `
+	var char c; '€'
+	var uint u; 0xFF_u + 1e3f
+	/* block /* nested */ comment */
+	#if DEBUG
+	print("debug")
+	#else
+	print(s, r, c, u)
+	#endif
+	x <<= 2; y &^= 3
+	return
+}
+var bad; "unterminated
+`
+
+// readers returns the ways the code is handed to New, each splitting it differently
+func readers(code string) map[string]io.Reader {
+	return map[string]io.Reader{
+		"whole":    strings.NewReader(code),
+		"one byte": iotest.OneByteReader(strings.NewReader(code)),
+		"half":     iotest.HalfReader(strings.NewReader(code)),
+	}
+}
+
+// drain pulls every token out of a lexer
+func drain(lxr *Lexer) []token.Token {
+	tokens := make([]token.Token, 0)
+	for {
+		tok := lxr.Next()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+// TestNextMatchesLex checks that streaming tokens out of a reader gives the same tokens
+// as lexing the whole file at once, however the reader splits the code
+func TestNextMatchesLex(t *testing.T) {
+	print2.OutputErrorMessages = false
+
+	for _, options := range []Options{
+		{TreatHashtagAsComment: true},
+		{TreatHashtagAsComment: true, EmitDocComments: true, InsertSemicolons: true},
+		{InsertSemicolons: true, Defines: map[string]bool{"DEBUG": true}},
+	} {
+		expected := LexInternal([]rune(equivalenceSource), "equivalence.tod", options)
+
+		for name, reader := range readers(equivalenceSource) {
+			lxr := New(reader, "equivalence.tod")
+			lxr.Options = options
+			tokens := drain(lxr)
+
+			if len(tokens) != len(expected) {
+				t.Fatalf("%s %+v: got %d tokens, expected %d", name, options, len(tokens), len(expected))
+			}
+
+			for i, tok := range tokens {
+				want := expected[i]
+				if tok.Type != want.Type || tok.Literal != want.Literal || !reflect.DeepEqual(tok.RealValue, want.RealValue) ||
+					tok.Span() != want.Span() || tok.FullText() != want.FullText() {
+					t.Fatalf("%s %+v: token %d is %q (%v, %v), expected %q (%v, %v)",
+						name, options, i, tok.Literal, tok.Type, tok.Span(), want.Literal, want.Type, want.Span())
+				}
+			}
+		}
+	}
+}
+
+// TestRememberedLines checks that errors raised while streaming leave the lines of the file for code snippets
+func TestRememberedLines(t *testing.T) {
+	print2.OutputErrorMessages = false
+	code := "var a; 1\nvar b; \"unterminated\n\nvar c; '\nvar d; 0x\n"
+
+	lxr := New(iotest.OneByteReader(strings.NewReader(code)), "lines.tod")
+	drain(lxr)
+
+	lines := print2.SourceFiles["lines.tod"]
+	if expected := strings.Split(code, "\n"); !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("remembered %q, expected %q", lines, expected)
+	}
+}
+
+// TestIdentifierNames checks that identifiers are known by their NFC form, however they're written
+func TestIdentifierNames(t *testing.T) {
+	for _, test := range []struct {
+		code string
+		name string
+	}{
+		{"count", "count"},
+		{"caf\u00e9", "caf\u00e9"},
+		{"cafe\u0301", "caf\u00e9"}, // e and a combining acute accent
+	} {
+		tok := drain(New(strings.NewReader(test.code), "names.tod"))[0]
+		if tok.Type != token.IDENT || tok.Literal != test.code || tok.Name() != test.name {
+			t.Errorf("%q lexed to %v %q named %q, expected the name %q", test.code, tok.Type, tok.Literal, tok.Name(), test.name)
+		}
+	}
+}
+
+// TestFloatSuffix checks that an "f" suffix makes any number a FLOAT32
+func TestFloatSuffix(t *testing.T) {
+	print2.OutputErrorMessages = false
+
+	for _, test := range []struct {
+		code      string
+		tokenType token.TokenType
+		value     interface{}
+	}{
+		{"2.5f", token.FLOAT32, float32(2.5)},
+		{"3f", token.FLOAT32, float32(3)},
+		{"1e3f", token.FLOAT32, float32(1000)},
+		{"0.1f", token.FLOAT32, float32(0.1)},
+		{"2.5", token.FLOAT64, 2.5},
+		{"3", token.INT, 3},
+	} {
+		tok := drain(New(strings.NewReader(test.code), "floats.tod"))[0]
+		if tok.Type != test.tokenType || tok.RealValue != test.value {
+			t.Errorf("%q lexed to %v %#v, expected %v %#v", test.code, tok.Type, tok.RealValue, test.tokenType, test.value)
+		}
+	}
+}
+
+// BenchmarkNext streams the tokens out of a reader without keeping them, see BenchmarkLex
+func BenchmarkNext(b *testing.B) {
+	print2.OutputErrorMessages = false
+	b.SetBytes(int64(len(benchmarkSource)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		lxr := New(strings.NewReader(benchmarkSource), "bench.tod")
+		for lxr.Next().Type != token.EOF {
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package lexer
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// the preprocessor directives the lexer understands
+// a directive has to be the first thing on its line, like "#if DEBUG"
+var directives = map[string]bool{
+	"define": true,
+	"if":     true,
+	"elif":   true,
+	"else":   true,
+	"endif":  true,
+	"error":  true,
+}
+
+// conditional is an #if block we are in
+type conditional struct {
+	start   token.Pos // where the #if is, for errors
+	active  bool      // is the current branch compiled
+	taken   bool      // has a branch been taken already (the following ones are skipped then)
+	hasElse bool
+}
+
+// skipping tells us if we're in a part of the code that's left out by #if/#elif/#else
+func (lxr *Lexer) skipping() bool {
+	return len(lxr.conditionals) > 0 && !lxr.conditionals[len(lxr.conditionals)-1].active
+}
+
+// defined checks if a name has been defined, either through the options or with #define
+func (lxr *Lexer) defined(name string) bool {
+	return lxr.defines[name] || lxr.Defines[name]
+}
+
+// atDirective checks if a preprocessor directive starts here
+func (lxr *Lexer) atDirective() bool {
+	if lxr.peek(0) != '#' || !lxr.atLineStart() {
+		return false
+	}
+
+	var name strings.Builder
+	for i := 1; unicode.IsLetter(lxr.peek(i)); i++ {
+		name.WriteRune(lxr.peek(i))
+	}
+	return directives[name.String()]
+}
+
+// atLineStart checks if there's nothing but whitespace in front of us on this line
+func (lxr *Lexer) atLineStart() bool {
+	for i := lxr.Index - 1; i >= 0 && lxr.source[i] != '\n'; i-- {
+		if !isWhitespace(rune(lxr.source[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// skipLine skips a line of code that's left out by #if/#elif/#else
+// the code is kept as trivia so it's still there for tools working on the source
+func (lxr *Lexer) skipLine() {
+	start := lxr.pos()
+	for !lxr.done() && lxr.peek(0) != '\n' {
+		lxr.Increment()
+	}
+	lxr.Increment() // \n
+
+	lxr.addTrivia(token.SkippedTrivia, start)
+}
+
+// getDirective reads and executes a preprocessor directive, the line break at the end is left alone
+func (lxr *Lexer) getDirective() {
+	start := lxr.pos()
+	lxr.Increment() // #
+
+	nameStart := lxr.pos()
+	for !lxr.done() && unicode.IsLetter(lxr.peek(0)) {
+		lxr.Increment()
+	}
+	name := lxr.text(nameStart)
+
+	argumentStart := lxr.pos()
+	for !lxr.done() && lxr.peek(0) != '\n' {
+		lxr.Increment()
+	}
+
+	// directives may end in a comment
+	argument := lxr.text(argumentStart)
+	if index := strings.Index(argument, "//"); index >= 0 {
+		argument = argument[:index]
+	}
+	argument = strings.TrimSpace(argument)
+
+	lxr.addTrivia(token.DirectiveTrivia, start)
+	span := lxr.spanSince(start)
+
+	switch name {
+	case "define":
+		if lxr.skipping() {
+			return
+		}
+
+		if !isDirectiveName(argument) {
+			lxr.reportError(print2.InvalidDirectiveError, span, "#define needs a single name to define, got \"%s\"!", argument)
+			return
+		}
+
+		if lxr.defines == nil {
+			lxr.defines = make(map[string]bool)
+		}
+		lxr.defines[argument] = true
+
+	case "if":
+		// inside of skipped code no branch can be taken
+		if lxr.skipping() {
+			lxr.conditionals = append(lxr.conditionals, conditional{start: start, active: false, taken: true})
+			return
+		}
+
+		active := lxr.evaluateCondition(argument, span)
+		lxr.conditionals = append(lxr.conditionals, conditional{start: start, active: active, taken: active})
+
+	case "elif", "else":
+		if len(lxr.conditionals) == 0 {
+			lxr.reportError(print2.InvalidDirectiveError, span, "#%s without #if!", name)
+			return
+		}
+
+		current := &lxr.conditionals[len(lxr.conditionals)-1]
+		if current.hasElse {
+			lxr.reportError(print2.InvalidDirectiveError, span, "#%s after #else!", name)
+			current.active = false
+			return
+		}
+
+		if name == "else" {
+			current.hasElse = true
+			current.active = !current.taken
+		} else {
+			current.active = !current.taken && lxr.evaluateCondition(argument, span)
+		}
+		current.taken = current.taken || current.active
+
+	case "endif":
+		if len(lxr.conditionals) == 0 {
+			lxr.reportError(print2.InvalidDirectiveError, span, "#endif without #if!")
+			return
+		}
+		lxr.conditionals = lxr.conditionals[:len(lxr.conditionals)-1]
+
+	case "error":
+		if !lxr.skipping() {
+			lxr.reportError(print2.ErrorDirectiveError, span, "#error %s", argument)
+		}
+	}
+}
+
+// closeConditionals reports every #if that's still open at the end of the file
+func (lxr *Lexer) closeConditionals() {
+	for _, open := range lxr.conditionals {
+		lxr.reportError(print2.InvalidDirectiveError, lxr.File.Span(open.start, open.start+1), "#if is never closed! Expected #endif")
+	}
+	lxr.conditionals = nil
+}
+
+// evaluateCondition evaluates the condition of an #if or #elif.
+// Conditions are made of names (true if defined), '!', '&&', '||' and parentheses
+func (lxr *Lexer) evaluateCondition(text string, span print2.TextSpan) bool {
+	condition := conditionParser{text: text, lxr: lxr}
+	value := condition.parseOr()
+
+	if condition.skipSpaces(); condition.failed || condition.index < len(condition.text) {
+		lxr.reportError(print2.InvalidDirectiveError, span, "invalid condition \"%s\"!", text)
+		return false
+	}
+	return value
+}
+
+// conditionParser parses and evaluates a condition in one go
+type conditionParser struct {
+	text   string
+	index  int
+	failed bool
+	lxr    *Lexer
+}
+
+func (c *conditionParser) skipSpaces() {
+	for c.index < len(c.text) && isWhitespace(rune(c.text[c.index])) {
+		c.index++
+	}
+}
+
+// accept consumes op if it comes next
+func (c *conditionParser) accept(op string) bool {
+	c.skipSpaces()
+	if strings.HasPrefix(c.text[c.index:], op) {
+		c.index += len(op)
+		return true
+	}
+	return false
+}
+
+func (c *conditionParser) parseOr() bool {
+	value := c.parseAnd()
+	for c.accept("||") {
+		// no short circuit, the rest still has to be parsed
+		right := c.parseAnd()
+		value = value || right
+	}
+	return value
+}
+
+func (c *conditionParser) parseAnd() bool {
+	value := c.parseUnary()
+	for c.accept("&&") {
+		right := c.parseUnary()
+		value = value && right
+	}
+	return value
+}
+
+func (c *conditionParser) parseUnary() bool {
+	if c.accept("!") {
+		return !c.parseUnary()
+	}
+
+	if c.accept("(") {
+		value := c.parseOr()
+		if !c.accept(")") {
+			c.failed = true
+		}
+		return value
+	}
+
+	c.skipSpaces()
+	start := c.index
+	for c.index < len(c.text) && (c.text[c.index] == '_' || unicode.IsLetter(rune(c.text[c.index])) || unicode.IsDigit(rune(c.text[c.index]))) {
+		c.index++
+	}
+
+	name := c.text[start:c.index]
+	if !isDirectiveName(name) {
+		c.failed = true
+		return false
+	}
+	return c.lxr.defined(name)
+}
+
+// isDirectiveName checks if name can be used with #define
+func isDirectiveName(name string) bool {
+	for i, c := range name {
+		if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return name != ""
+}
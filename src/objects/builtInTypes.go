@@ -0,0 +1,105 @@
+package objects
+
+// built-in types, these exist without being declared anywhere
+var (
+	VoidType   = CreateTypeObject("void", make([]TypeObject, 0), false, false, PackageObject{}, nil)
+	BoolType   = CreateTypeObject("bool", make([]TypeObject, 0), false, false, PackageObject{}, nil)
+	IntType    = CreateTypeObject("int", make([]TypeObject, 0), false, false, PackageObject{}, nil)
+	UIntType   = CreateTypeObject("uint", make([]TypeObject, 0), false, false, PackageObject{}, nil)
+	FloatType  = CreateTypeObject("float", make([]TypeObject, 0), false, false, PackageObject{}, nil)
+	DoubleType = CreateTypeObject("double", make([]TypeObject, 0), false, false, PackageObject{}, nil)
+	CharType   = CreateTypeObject("char", make([]TypeObject, 0), false, false, PackageObject{}, nil)
+	StringType = CreateTypeObject("string", make([]TypeObject, 0), true, false, PackageObject{}, nil)
+)
+
+var builtInTypes = map[string]TypeObject{
+	VoidType.Name:   VoidType,
+	BoolType.Name:   BoolType,
+	IntType.Name:    IntType,
+	UIntType.Name:   UIntType,
+	FloatType.Name:  FloatType,
+	DoubleType.Name: DoubleType,
+	CharType.Name:   CharType,
+	StringType.Name: StringType,
+}
+
+// LookupBuiltInType finds a built-in type by its name
+func LookupBuiltInType(name string) (TypeObject, bool) {
+	typ, ok := builtInTypes[name]
+	return typ, ok
+}
+
+// IsStringConvertible tells us if values of a built-in type can be turned into a string,
+// like the expressions in an interpolated string. Structs can be too if all their fields can,
+// that's up to the binder since it knows the fields
+func IsStringConvertible(typ TypeObject) bool {
+	switch typ.Name {
+	case BoolType.Name, IntType.Name, UIntType.Name, FloatType.Name, DoubleType.Name, CharType.Name, StringType.Name:
+		return !typ.IsUserDefined
+	}
+	return false
+}
+
+// CreatePointerType creates the type of a pointer to base, written pointer[base]
+func CreatePointerType(base TypeObject) TypeObject {
+	return CreateTypeObject("pointer", []TypeObject{base}, false, false, PackageObject{}, nil)
+}
+
+// IsPointer tells us if typ is a pointer, see CreatePointerType
+func IsPointer(typ TypeObject) bool {
+	return typ.Name == "pointer" && !typ.IsUserDefined && len(typ.SubTypes) == 1
+}
+
+// CreateSliceType creates the type of a slice of element, written []element.
+// A slice is a (pointer, length, capacity) header pointing into a buffer that can grow
+func CreateSliceType(element TypeObject) TypeObject {
+	return CreateTypeObject("slice", []TypeObject{element}, true, false, PackageObject{}, nil)
+}
+
+// CreateArrayType creates the type of an array of length elements, written [length]element
+func CreateArrayType(element TypeObject, length int) TypeObject {
+	typ := CreateTypeObject("array", []TypeObject{element}, false, false, PackageObject{}, nil)
+	typ.Length = length
+	return typ
+}
+
+// CreateMapType creates the type of a map from key to value, written map[key]value
+func CreateMapType(key TypeObject, value TypeObject) TypeObject {
+	return CreateTypeObject("map", []TypeObject{key, value}, true, false, PackageObject{}, nil)
+}
+
+// IsMap tells us if typ is a map, see CreateMapType
+func IsMap(typ TypeObject) bool {
+	return typ.Name == "map" && !typ.IsUserDefined && len(typ.SubTypes) == 2
+}
+
+// CreateMapIteratorType creates the type of the iterator a range loop over a map of key to value walks it with
+func CreateMapIteratorType(key TypeObject, value TypeObject) TypeObject {
+	return CreateTypeObject("mapIterator", []TypeObject{key, value}, true, false, PackageObject{}, nil)
+}
+
+// IsMapIterator tells us if typ is a map iterator, see CreateMapIteratorType
+func IsMapIterator(typ TypeObject) bool {
+	return typ.Name == "mapIterator" && !typ.IsUserDefined && len(typ.SubTypes) == 2
+}
+
+// IsSlice tells us if typ is a slice, see CreateSliceType
+func IsSlice(typ TypeObject) bool {
+	return typ.Name == "slice" && !typ.IsUserDefined && len(typ.SubTypes) == 1
+}
+
+// IsArray tells us if typ is a fixed size array, see CreateArrayType
+func IsArray(typ TypeObject) bool {
+	return typ.Name == "array" && !typ.IsUserDefined && len(typ.SubTypes) == 1
+}
+
+// ElementType returns the type of the elements of a slice, array or string
+func ElementType(typ TypeObject) (TypeObject, bool) {
+	if IsSlice(typ) || IsArray(typ) {
+		return typ.SubTypes[0], true
+	}
+	if typ.Name == StringType.Name && !typ.IsUserDefined {
+		return CharType, true
+	}
+	return TypeObject{}, false
+}
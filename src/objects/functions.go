@@ -19,6 +19,8 @@ type FunctionObject struct {
 	Parameters  []ParameterObject
 	TypeObject  TypeObject
 	Declaration ast.FunctionDeclarationMember
+
+	ExternalDeclaration ast.ExternalFunctionDeclarationMember // if External is set
 }
 
 func (FunctionObject) ObjectType() ObjectType {
@@ -61,16 +63,16 @@ func CreateFunctionObject(name string, params []ParameterObject, typeObject Type
 	}
 }
 
-func CreateExternalFunctionObject(name string, params []ParameterObject, typeObject TypeObject, declaration ast.FunctionDeclarationMember) FunctionObject {
+func CreateExternalFunctionObject(name string, params []ParameterObject, typeObject TypeObject, declaration ast.ExternalFunctionDeclarationMember) FunctionObject {
 	return FunctionObject{
 		Exists: true,
 		Name:   name,
 
-		Parameters:  params,
-		TypeObject:  typeObject,
-		Declaration: declaration,
-		External:    true,
-		Public:      true,
+		Parameters:          params,
+		TypeObject:          typeObject,
+		ExternalDeclaration: declaration,
+		External:            true,
+		Public:              true,
 	}
 }
 
@@ -13,6 +13,10 @@ type PackageObject struct {
 	Functions     []FunctionObject
 	Module        *ir.Module
 	ErrorLocation print2.TextSpan
+
+	// packages made from source
+	Path    string             // import path
+	Exports map[string]Objects // everything declared with "set"
 }
 
 func (PackageObject) ObjectType() ObjectType {
@@ -42,3 +46,22 @@ func CreatePackageObject(name string, functions []FunctionObject, module *ir.Mod
 		ErrorLocation: errorLocation,
 	}
 }
+
+// CreateSourcePackageObject creates the object for an imported package that's compiled from source
+func CreateSourcePackageObject(name string, path string, exports map[string]Objects, errorLocation print2.TextSpan) PackageObject {
+	functions := make([]FunctionObject, 0)
+	for _, sym := range exports {
+		if function, ok := sym.(FunctionObject); ok {
+			functions = append(functions, function)
+		}
+	}
+
+	return PackageObject{
+		Exists:        true,
+		Name:          name,
+		Path:          path,
+		Functions:     functions,
+		Exports:       exports,
+		ErrorLocation: errorLocation,
+	}
+}
@@ -21,6 +21,10 @@ type TypeFunctionObject struct {
 	Declaration ast.FunctionDeclarationMember
 
 	OriginType TypeObject
+
+	// methods declared in code: fn (p *Point) Len() float
+	Receiver        ParameterObject
+	PointerReceiver bool
 }
 
 func (TypeFunctionObject) ObjectType() ObjectType {
@@ -73,3 +77,17 @@ func CreateBuiltInTypeFunctionObject(name string, parameters []ParameterObject,
 		OriginType:  origin,
 	}
 }
+
+// CreateMethodObject creates a method declared on a struct, origin is the type of the receiver (without the pointer)
+func CreateMethodObject(name string, receiver ParameterObject, pointerReceiver bool, parameters []ParameterObject, typeObject TypeObject, declaration ast.FunctionDeclarationMember, origin TypeObject) TypeFunctionObject {
+	return TypeFunctionObject{
+		Exist:           true,
+		Name:            name,
+		Parameters:      parameters,
+		Type:            typeObject,
+		Declaration:     declaration,
+		OriginType:      origin,
+		Receiver:        receiver,
+		PointerReceiver: pointerReceiver,
+	}
+}
@@ -1,6 +1,10 @@
 package objects
 
-import "github.com/NikoMalik/Tod-go-compiler/src/print2"
+import (
+	"fmt"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
 
 type TypeObject struct {
 	Objects
@@ -10,6 +14,8 @@ type TypeObject struct {
 	IsUserDefined bool
 	Package       PackageObject
 	SourceObject  Objects
+
+	Length int // the N of an array type [N]T
 }
 
 func (TypeObject) ObjectType() ObjectType {
@@ -29,9 +35,13 @@ func (t TypeObject) FingerPrint() string {
 	if t.IsObject {
 		id += "O"
 	}
-	id += "_" + t.Name + "_["
+	id += "_" + t.Name
+	if IsArray(t) {
+		id += fmt.Sprintf("%d", t.Length)
+	}
+	id += "_["
 	for _, subtype := range t.SubTypes {
-		if subtype.Name == "array" {
+		if len(subtype.SubTypes) > 0 {
 			id += subtype.FingerPrint() + ";"
 		} else {
 			id += subtype.Name + ";"
@@ -41,6 +51,21 @@ func (t TypeObject) FingerPrint() string {
 	return id
 }
 
+// TypeName is how the type is written in source code, for error messages
+func (t TypeObject) TypeName() string {
+	switch {
+	case IsSlice(t):
+		return "[]" + t.SubTypes[0].TypeName()
+	case IsArray(t):
+		return fmt.Sprintf("[%d]%s", t.Length, t.SubTypes[0].TypeName())
+	case IsMap(t):
+		return "map[" + t.SubTypes[0].TypeName() + "]" + t.SubTypes[1].TypeName()
+	case IsPointer(t):
+		return "pointer[" + t.SubTypes[0].TypeName() + "]"
+	}
+	return t.Name
+}
+
 func CreateTypeObject(name string, subtypes []TypeObject, isObject bool, isUserDefined bool, pck PackageObject, src Objects) TypeObject {
 	return TypeObject{
 		Name:          name,
@@ -0,0 +1,403 @@
+package packager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/lexer"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/parser"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/semantic"
+)
+
+// Package is all .tod files of one directory, compiled together.
+// The files share one top level scope, so they can use each other's declarations
+type Package struct {
+	Name  string
+	Path  string // import path, empty for the package we're compiling
+	Dir   string
+	Files []File
+
+	Scope   *semantic.Scope
+	Exports map[string]objects.Objects // everything declared with "set"
+}
+
+// File is one source file of a package
+type File struct {
+	Path    string
+	Clause  ast.PackageReferenceMember // package name
+	Imports []ast.ImportDeclarationMember
+	Members []ast.MemberNode // everything after the package clause (except imports)
+
+	// the imported packages, its parent is the package's scope
+	Scope *semantic.Scope
+}
+
+// Loader loads packages and everything they import, every package is only loaded once
+type Loader struct {
+	// the directories imports are looked up in, in order
+	SearchPath []string
+	Options    lexer.Options
+
+	packages map[string]*Package // loaded packages by directory
+	loading  []*Package          // the chain of imports being loaded right now, for finding cycles
+}
+
+// CreateLoader creates a loader for a project. Imports are looked up in the project root,
+// its vendor directory and the standard library (if there is one), in that order
+func CreateLoader(root string, stdlib string, options lexer.Options) Loader {
+	searchPath := []string{root, filepath.Join(root, "vendor")}
+	if stdlib != "" {
+		searchPath = append(searchPath, stdlib)
+	}
+
+	return Loader{
+		SearchPath: searchPath,
+		Options:    options,
+		packages:   make(map[string]*Package),
+		loading:    make([]*Package, 0),
+	}
+}
+
+// Load loads the package in dir (and the packages it imports) with dir as the project root
+func Load(dir string, options lexer.Options) (*Package, bool) {
+	loader := CreateLoader(dir, "", options)
+	return loader.Load(dir)
+}
+
+// Load parses every .tod file in dir, declares their top level names in one shared scope and binds
+// the bodies of their functions. Every file has to start with a package clause and all of them have to agree on the name
+func (l *Loader) Load(dir string) (*Package, bool) {
+	return l.load(filepath.Clean(dir), "")
+}
+
+func (l *Loader) load(dir string, path string) (*Package, bool) {
+	if pkg, loaded := l.packages[dir]; loaded {
+		return pkg, true
+	}
+
+	scope := semantic.CreateScope(nil)
+	pkg := &Package{
+		Path:    path,
+		Dir:     dir,
+		Files:   make([]File, 0),
+		Scope:   &scope,
+		Exports: make(map[string]objects.Objects),
+	}
+
+	l.loading = append(l.loading, pkg)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	paths, ok := sourceFiles(dir)
+	if !ok {
+		l.packages[dir] = pkg
+		return pkg, false
+	}
+
+	var named File // the file that gave the package its name
+	for _, path := range paths {
+		file, parsed := parseFile(path, l.Options)
+		ok = ok && parsed
+		pkg.Files = append(pkg.Files, file)
+
+		if file.Clause.Package.Literal == "" {
+			continue
+		}
+
+		// the first file names the package, the others have to follow
+		if pkg.Name == "" {
+			pkg.Name = file.Clause.Package.Name()
+			named = file
+		} else if file.Clause.Package.Name() != pkg.Name {
+			print2.Error(
+				"PACKAGER",
+				print2.PackageNameMismatchError,
+				file.Clause.Package.Span(),
+				"found package \"%s\" in \"%s\", but the other files in \"%s\" are package \"%s\"!",
+				file.Clause.Package.Name(),
+				filepath.Base(path),
+				dir,
+				pkg.Name,
+			)
+			print2.Note(named.Clause.Package.Span(), "package \"%s\" is declared here", pkg.Name)
+			ok = false
+		}
+	}
+
+	// imports first, so everything we depend on is there
+	for i := range pkg.Files {
+		ok = l.importPackages(&pkg.Files[i], pkg) && ok
+	}
+
+	// one scope for the whole package
+	members := make([]ast.MemberNode, 0)
+	for _, file := range pkg.Files {
+		members = append(members, file.Members...)
+	}
+
+	ok = scope.DeclareMembers(members) && ok
+
+	// bodies are bound per file so they see its imports. Only once everything else is fine,
+	// a broken declaration (or a file that didn't parse) would show up again in every body
+	if ok {
+		for i := range pkg.Files {
+			file := &pkg.Files[i]
+			lowered, bound := file.Scope.BindFunctionBodies(file.Members)
+			file.Members = lowered
+			ok = bound && ok
+		}
+	}
+
+	for _, member := range members {
+		name, hasName := semantic.MemberName(member)
+		if !hasName || !semantic.IsExported(member) {
+			continue
+		}
+
+		if sym, found := scope.Objects[name.Name()]; found {
+			pkg.Exports[name.Name()] = sym
+		}
+	}
+
+	l.packages[dir] = pkg
+	return pkg, ok
+}
+
+// importPackages loads the packages a file imports and declares them in the file's scope
+func (l *Loader) importPackages(file *File, pkg *Package) bool {
+	scope := semantic.CreateScope(pkg.Scope)
+	file.Scope = &scope
+
+	ok := true
+	seen := make(map[string]ast.ImportSpecNode) // by the name the package goes by in this file
+
+	for _, declaration := range file.Imports {
+		for _, spec := range declaration.Imports {
+			path := spec.ImportPath()
+
+			dir, found := l.resolve(path)
+			if !found {
+				print2.Error(
+					"PACKAGER",
+					print2.UnknownPackageError,
+					spec.Path.Span(),
+					"couldn't find package \"%s\"! Looked in %s",
+					path,
+					strings.Join(l.SearchPath, ", "),
+				)
+				ok = false
+				continue
+			}
+
+			if cycle := l.cycle(dir, path); cycle != "" {
+				print2.Error(
+					"PACKAGER",
+					print2.ImportCycleError,
+					spec.Path.Span(),
+					"import cycle: %s",
+					cycle,
+				)
+				ok = false
+				continue
+			}
+
+			imported, loaded := l.load(dir, path)
+			ok = ok && loaded
+
+			// nothing to import, the errors have been reported already
+			if imported.Name == "" {
+				continue
+			}
+
+			name := imported.Name
+			if spec.HasAlias() {
+				name = spec.Alias.Name()
+			}
+
+			// the same package can be imported twice, as long as it goes by another name
+			if first, imported := seen[name]; imported {
+				print2.Error(
+					"PACKAGER",
+					print2.DuplicatePackageImportError,
+					spec.Span(),
+					"there already is an import called \"%s\" in this file! Give one of them an alias",
+					name,
+				)
+				print2.Note(first.Span(), "\"%s\" is imported here", name)
+				ok = false
+				continue
+			}
+			seen[name] = spec
+
+			if !scope.TryDeclareObject(objects.CreateSourcePackageObject(name, path, imported.Exports, spec.Span())) {
+				print2.Error(
+					"PACKAGER",
+					print2.DuplicatePackageImportError,
+					spec.Span(),
+					"package \"%s\" can't be imported as \"%s\", the package already declares something called that! Give it an alias",
+					path,
+					name,
+				)
+				ok = false
+			}
+		}
+	}
+
+	return ok
+}
+
+// resolve finds the directory of an import path, the first match in the search path wins
+func (l *Loader) resolve(path string) (string, bool) {
+	// imports stay inside of the search path
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if path == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	for _, root := range l.SearchPath {
+		dir := filepath.Join(root, clean)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// cycle checks if importing dir would import a package that is still being loaded,
+// if it does it returns the chain of imports like "a" -> "b" -> "a"
+func (l *Loader) cycle(dir string, path string) string {
+	for i, pkg := range l.loading {
+		if pkg.Dir != dir {
+			continue
+		}
+
+		chain := make([]string, 0)
+		for _, link := range l.loading[i:] {
+			chain = append(chain, fmt.Sprintf("\"%s\"", link.importName()))
+		}
+		chain = append(chain, fmt.Sprintf("\"%s\"", path))
+
+		return strings.Join(chain, " -> ")
+	}
+	return ""
+}
+
+// importName is how a package is imported, the package we're compiling goes by its name
+func (pkg *Package) importName() string {
+	if pkg.Path == "" {
+		return pkg.Name
+	}
+	return pkg.Path
+}
+
+// sourceFiles lists the .tod files of a package directory (sorted by name)
+func sourceFiles(dir string) ([]string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		print2.Error(
+			"PACKAGER",
+			print2.FileDoesNotExistError,
+			print2.TextSpan{},
+			"couldn't read package directory \"%s\": %s",
+			dir,
+			err.Error(),
+		)
+		return nil, false
+	}
+
+	paths := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".tod") {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	if len(paths) == 0 {
+		print2.Error(
+			"PACKAGER",
+			print2.EmptyPackageError,
+			print2.TextSpan{},
+			"there are no .tod files in \"%s\"!",
+			dir,
+		)
+		return nil, false
+	}
+
+	return paths, true
+}
+
+// parseFile parses a file of the package and splits off its package clause
+func parseFile(path string, options lexer.Options) (File, bool) {
+	file := File{Path: path, Imports: make([]ast.ImportDeclarationMember, 0), Members: make([]ast.MemberNode, 0)}
+
+	source, err := os.Open(path)
+	if err != nil {
+		print2.Error(
+			"PACKAGER",
+			print2.FilePermissionError,
+			print2.TextSpan{},
+			"couldn't open \"%s\": %s",
+			path,
+			err.Error(),
+		)
+		return file, false
+	}
+	defer source.Close()
+
+	lxr := lexer.New(source, path)
+	lxr.Options = options
+
+	// a file with syntax errors doesn't load, whatever else is in it
+	errors := len(print2.ErrorList)
+	members := parser.ParseFrom(lxr)
+	ok := len(print2.ErrorList) == errors
+
+	hasClause := false
+	for i, member := range members {
+		if declaration, isImport := member.(ast.ImportDeclarationMember); isImport {
+			file.Imports = append(file.Imports, declaration)
+			continue
+		}
+
+		clause, isClause := member.(ast.PackageReferenceMember)
+		if !isClause {
+			file.Members = append(file.Members, member)
+			continue
+		}
+
+		if i != 0 {
+			print2.Error(
+				"PACKAGER",
+				print2.MisplacedPackageClauseError,
+				clause.Span(),
+				"the package clause has to be the first thing in a file!",
+			)
+			ok = false
+			continue
+		}
+		file.Clause = clause
+		hasClause = true
+	}
+
+	if !hasClause {
+		span := print2.TextSpan{}
+		if len(members) > 0 {
+			span = members[0].Span()
+		}
+
+		print2.Error(
+			"PACKAGER",
+			print2.MissingPackageClauseError,
+			span,
+			"\"%s\" doesn't say which package it belongs to! Start it with \"package name\"",
+			filepath.Base(path),
+		)
+		ok = false
+	}
+
+	return file, ok
+}
@@ -0,0 +1,174 @@
+package packager
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/lexer"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// project writes the files of a project (by their path in it) to a temporary directory
+func project(t *testing.T, files map[string]string) string {
+	t.Helper()
+	print2.OutputErrorMessages = false
+	print2.ErrorList = print2.ErrorList[:0]
+
+	root := t.TempDir()
+	for path, code := range files {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+var options = lexer.Options{TreatHashtagAsComment: true, InsertSemicolons: true}
+
+// errorTypes lists the types of the errors reported so far
+func errorTypes() []print2.ErrorType {
+	types := make([]print2.ErrorType, 0, len(print2.ErrorList))
+	for _, report := range print2.ErrorList {
+		types = append(types, report.ErrType)
+	}
+	return types
+}
+
+// TestSyntaxErrors checks that a package doesn't load if one of its files doesn't parse
+func TestSyntaxErrors(t *testing.T) {
+	root := project(t, map[string]string{
+		"a.tod": "package main\nfn a() {}\n",
+		"b.tod": "package main\nfn b() { var int x; ] }\n",
+	})
+
+	pkg, ok := Load(root, options)
+	if ok {
+		t.Errorf("loaded a package with syntax errors")
+	}
+	if pkg.Name != "main" || len(pkg.Files) != 2 {
+		t.Errorf("expected both files of package main, got %d files of %q", len(pkg.Files), pkg.Name)
+	}
+	if types := errorTypes(); len(types) != 1 || types[0] != print2.UnexpectedTokenError {
+		t.Errorf("reported %v, expected the syntax error", types)
+	}
+
+	// the same package without the error is fine
+	root = project(t, map[string]string{
+		"a.tod": "package main\nfn a() {}\n",
+		"b.tod": "package main\nfn b() { var int x; 1 }\n",
+	})
+	if _, ok := Load(root, options); !ok {
+		t.Errorf("couldn't load the package: %v", errorTypes())
+	}
+}
+
+// TestImportAliases checks that a package can be imported twice under different names,
+// but two imports can't go by the same name
+func TestImportAliases(t *testing.T) {
+	root := project(t, map[string]string{
+		"main.tod":        "package main\nimport (\n\t\"geo\"\n\tg \"geo\"\n)\nfn run() {}\n",
+		"geo/geo.tod":     "package geo\nset fn Area(w int, h int) int {}\nset int Origin\n",
+		"shapes/geo.tod":  "package geo\nset fn Sides() int {}\n",
+		"broken/main.tod": "package main\nimport (\n\t\"geo\"\n\t\"shapes\"\n)\n",
+	})
+
+	pkg, ok := Load(root, options)
+	if !ok {
+		t.Fatalf("couldn't load the package: %v", errorTypes())
+	}
+
+	scope := pkg.Files[0].Scope
+	for _, name := range []string{"geo", "g"} {
+		imported, isPackage := scope.Objects[name].(objects.PackageObject)
+		if !isPackage || imported.Path != "geo" {
+			t.Errorf("%q should be package \"geo\", it's %v", name, scope.Objects[name])
+		}
+	}
+
+	// geo and shapes are both package geo
+	loader := CreateLoader(root, "", options)
+	if _, ok := loader.Load(filepath.Join(root, "broken")); ok {
+		t.Errorf("imported two packages called \"geo\" without an alias")
+	}
+	if types := errorTypes(); len(types) != 1 || types[0] != print2.DuplicatePackageImportError {
+		t.Errorf("reported %v, expected a duplicate import", types)
+	}
+}
+
+// TestQualifiedNames checks that what an imported package exports is found through its name
+func TestQualifiedNames(t *testing.T) {
+	root := project(t, map[string]string{
+		"main.tod":    "package main\nimport m \"geo\"\nm.Origin\nm.Area(1, 2)\nm.hidden\nm.Area(1)\n",
+		"geo/geo.tod": "package geo\nset fn Area(w int, h int) int {}\nset int Origin\nvar int hidden\n",
+	})
+
+	pkg, ok := Load(root, options)
+	if !ok {
+		t.Fatalf("couldn't load the package: %v", errorTypes())
+	}
+
+	file := pkg.Files[0]
+	expressions := make([]ast.Expression, 0)
+	for _, member := range file.Members {
+		statement := member.(ast.GlobalStatementMember).Statement.(ast.ExpressionStatementNode)
+		expressions = append(expressions, statement.Expression)
+	}
+
+	// m.Origin
+	variable, ok := file.Scope.BindFieldAccess(expressions[0].(ast.ClassFieldAccessExpressionNode))
+	if !ok || variable.ObjectName() != "Origin" || variable.VarType().Name != objects.IntType.Name {
+		t.Errorf("m.Origin bound to %v (%t)", variable, ok)
+	}
+
+	// m.Area(1, 2)
+	call := expressions[1].(ast.TypeCallExpressionNode)
+	imported := file.Scope.Objects["m"].(objects.PackageObject)
+	function, ok := file.Scope.BindPackageCall(imported, call)
+	if !ok || function.Name != "Area" || function.TypeObject.Name != objects.IntType.Name {
+		t.Errorf("m.Area(1, 2) bound to %v (%t)", function.Name, ok)
+	}
+
+	// hidden isn't exported, and Area takes two arguments
+	print2.ErrorList = print2.ErrorList[:0]
+	if _, ok := file.Scope.BindFieldAccess(expressions[2].(ast.ClassFieldAccessExpressionNode)); ok {
+		t.Errorf("m.hidden isn't exported, but has been found")
+	}
+	if _, ok := file.Scope.BindPackageCall(imported, expressions[3].(ast.TypeCallExpressionNode)); ok {
+		t.Errorf("m.Area(1) has the wrong number of arguments, but has been bound")
+	}
+	expected := []print2.ErrorType{print2.UndefinedVariableReferenceError, print2.BadNumberOfParametersError}
+	if types := errorTypes(); !slices.Equal(types, expected) {
+		t.Errorf("reported %v, expected %v", types, expected)
+	}
+}
+
+// TestFunctionBodies checks that bodies are bound with the imports of their own file
+// and come back lowered
+func TestFunctionBodies(t *testing.T) {
+	root := project(t, map[string]string{
+		"main.tod":    "package main\nimport \"geo\"\nfn total(sides []int) int {\nfor i := range sides {\nreturn geo.Area(i, 2)\n}\nreturn 0\n}\n",
+		"other.tod":   "package main\nfn area() int {\nreturn geo.Area(1, 2)\n}\n",
+		"geo/geo.tod": "package geo\nset fn Area(w int, h int) int {}\n",
+	})
+
+	pkg, ok := Load(root, options)
+	if ok {
+		t.Fatalf("other.tod doesn't import geo, but the package has been loaded")
+	}
+	if types := errorTypes(); len(types) != 1 || types[0] != print2.UndefinedVariableReferenceError {
+		t.Errorf("reported %v, expected geo to be unknown in other.tod", types)
+	}
+
+	total := pkg.Files[0].Members[0].(ast.FunctionDeclarationMember)
+	if _, lowered := total.Body.Statements[0].(ast.BlockStatementNode); !lowered {
+		t.Errorf("the range loop hasn't been lowered, it's a %T", total.Body.Statements[0])
+	}
+}
@@ -1,9 +1,11 @@
 package parser
 
 import (
-	"os"
+	"slices"
+	"strings"
 
 	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/lexer"
 	"github.com/NikoMalik/Tod-go-compiler/src/print2"
 	"github.com/NikoMalik/Tod-go-compiler/src/token"
 )
@@ -11,6 +13,27 @@ import (
 type Parser struct {
 	Tokens []token.Token
 	Index  int
+
+	// doc comments, keyed by the index of the token they are in front of
+	Comments map[int][]token.Token
+
+	// if set, tokens are pulled from the lexer only once the parser needs them
+	Lexer    *lexer.Lexer
+	comments []token.Token // doc comments waiting for the token they belong to
+
+	// set after a syntax error until the parser is back on track (see synchronize()),
+	// more errors on the same line are most likely caused by the first one so they aren't reported
+	recovering bool
+	errorLine  int
+	errorIndex int // the token the parser was at when it found the last error
+	errors     int // syntax errors so far, reported or not
+
+	// for error messages: the tokens that would've been valid at the current token
+	// and what came right before it ("expected `)` or `,` after argument")
+	expected []token.TokenType
+	after    string
+
+	delimiters []token.Token // the (, [ and { that haven't been closed yet
 }
 
 func (p *Parser) current() token.Token {
@@ -19,6 +42,8 @@ func (p *Parser) current() token.Token {
 }
 
 func (p *Parser) peek(offset int) token.Token {
+	p.pull(p.Index + offset)
+
 	if p.Index+offset < 0 || p.Index+offset >= len(p.Tokens) {
 		return token.Token{
 			Type:    token.EOF,
@@ -28,47 +53,305 @@ func (p *Parser) peek(offset int) token.Token {
 	return p.Tokens[p.Index+offset]
 }
 
-func (p *Parser) consume(except token.TokenType) token.Token {
-	if p.current().Type == except {
-		additionalInfo := ""
+// consume returns the current token and moves on if it's the expected one.
+// If it isn't, an error is reported and a placeholder for the missing token is
+// returned instead, the current token is left alone so the parser can pick it up again
+func (p *Parser) consume(expected token.TokenType) token.Token {
+	if p.current().Type == expected {
+		p.advance()
+		tok := p.peek(-1)
+		p.trackDelimiter(tok)
+		return tok
+	}
 
-		if p.current().Type == token.IDENT {
-			additionalInfo = "ident may be " + p.current().Literal
-		}
+	reported := p.reportError(
+		p.currentSpan(),
+		"expected %s, found %s",
+		p.expectation(describeType(expected)),
+		describeFound(p.current()),
+	)
 
-		print2.Error(
-			"PARSER",
-			print2.UnexpectedTokenError,
-			p.current().Span,
-			"unexpected Token \"%s\"! Expected \"%s\"!"+additionalInfo,
-			p.current().Type,
-			except,
-		)
+	// a missing closing delimiter, point at the one it should close
+	if opening, ok := p.closeDelimiter(expected); ok && reported {
+		print2.Note(opening.Span(), "unclosed %s opened here", describeType(opening.Type))
 	}
+
+	// the placeholder takes the place of the expected token
+	p.expected = p.expected[:0]
+	p.after = ""
+
+	return token.CreateTokenSpaced("", expected, false, p.current().Pos, p.current().Pos)
+}
+
+// currentSpan is where the current token is for error messages,
+// a semicolon inserted at a line break points at the end of the line instead of spanning the break
+func (p *Parser) currentSpan() print2.TextSpan {
+	if tok := p.current(); isInsertedSemicolon(tok) {
+		return token.Files.Span(tok.Pos, tok.Pos)
+	}
+	return p.current().Span()
+}
+
+// advance moves on to the next token, what would've been valid at the last one doesn't matter anymore
+func (p *Parser) advance() {
 	p.Index++
-	return p.peek(-1)
+	p.expected = p.expected[:0]
+	p.after = ""
+}
+
+// at checks if the current token is of the given type.
+// If it isn't the type is remembered as one that would've been valid here, for error messages
+func (p *Parser) at(expected token.TokenType) bool {
+	if p.current().Type == expected {
+		return true
+	}
+	p.expected = append(p.expected, expected)
+	return false
+}
+
+// expectAfter tells error messages what came right before the current token, like "argument"
+func (p *Parser) expectAfter(what string) {
+	p.after = what
+}
+
+// expectation describes what would've been valid at the current token, starting with the given names
+func (p *Parser) expectation(names ...string) string {
+	for _, expected := range p.expected {
+		names = append(names, describeType(expected))
+	}
+
+	// every name only once
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if !slices.Contains(unique, name) {
+			unique = append(unique, name)
+		}
+	}
+
+	description := unique[len(unique)-1]
+	if len(unique) > 1 {
+		description = strings.Join(unique[:len(unique)-1], ", ") + " or " + description
+	}
+
+	if p.after != "" {
+		description += " after " + p.after
+	}
+	return description
+}
+
+// describeType describes a token type for error messages
+func describeType(tokenType token.TokenType) string {
+	switch tokenType {
+	case token.EOF:
+		return "end of file"
+	case token.IDENT:
+		return "identifier"
+	case token.INT, token.UINT:
+		return "integer"
+	case token.FLOAT32, token.FLOAT64:
+		return "float"
+	case token.STRING:
+		return "string"
+	case token.CHAR:
+		return "character"
+	case token.INTERPOLATION_MIDDLE, token.INTERPOLATION_END:
+		return "`}`"
+	}
+	return "`" + tokenType.String() + "`"
+}
+
+// isInsertedSemicolon checks if a semicolon was inserted by the lexer at a line break instead of written out
+func isInsertedSemicolon(tok token.Token) bool {
+	return tok.Type == token.SEMICOLON && tok.Literal == "\n"
+}
+
+// describeFound describes the token we found for error messages
+func describeFound(tok token.Token) string {
+	switch {
+	case tok.Type == token.EOF:
+		return "end of file"
+	case isInsertedSemicolon(tok):
+		return "newline"
+	case tok.Type == token.IDENT:
+		return "identifier `" + tok.Literal + "`"
+	case tok.Type.IsLiteral():
+		return describeType(tok.Type) + " `" + tok.Literal + "`"
+	}
+	return "`" + tok.Type.String() + "`"
+}
+
+// trackDelimiter keeps the stack of open delimiters up to date
+func (p *Parser) trackDelimiter(tok token.Token) {
+	switch tok.Type {
+	case token.LPAREN, token.LBRACK, token.LBRACE:
+		p.delimiters = append(p.delimiters, tok)
+	case token.RPAREN, token.RBRACK, token.RBRACE:
+		p.closeDelimiter(tok.Type)
+	}
+}
+
+// closeDelimiter removes the innermost open delimiter the closing one belongs to
+// (and whatever has been left open inside of it) and returns it
+func (p *Parser) closeDelimiter(closing token.TokenType) (token.Token, bool) {
+	opening, ok := openingDelimiters[closing]
+	if !ok {
+		return token.Token{}, false
+	}
+
+	for i := len(p.delimiters) - 1; i >= 0; i-- {
+		if p.delimiters[i].Type == opening {
+			tok := p.delimiters[i]
+			p.delimiters = p.delimiters[:i]
+			return tok, true
+		}
+	}
+	return token.Token{}, false
+}
+
+var openingDelimiters = map[token.TokenType]token.TokenType{
+	token.RPAREN: token.LPAREN,
+	token.RBRACK: token.LBRACK,
+	token.RBRACE: token.LBRACE,
+}
+
+// reportError reports a syntax error, unless it's on the line of the one we're still recovering from.
+// It returns if the error has actually been reported
+func (p *Parser) reportError(span print2.TextSpan, message string, args ...interface{}) bool {
+	p.errors++
+	p.errorIndex = p.Index
+	if p.recovering && span.StartLine == p.errorLine {
+		return false
+	}
+	p.recovering = true
+	p.errorLine = span.StartLine
+
+	p.rememberSource()
+	print2.Error("PARSER", print2.UnexpectedTokenError, span, message, args...)
+	return true
+}
+
+// synchronize skips ahead after a syntax error, to right after the next ";"
+// or up to the next "}" or member ("fn", "struct"), where parsing can continue.
+// startIndex is where the statement (or member) we gave up on started
+func (p *Parser) synchronize(startIndex int) {
+	// the statement did end properly, we're already where we need to be.
+	// Unless the error came after its end, "var int x; ]" ends at the ";" but the "]" is still to be skipped
+	if p.Index != startIndex && p.errorIndex < p.Index && (p.peek(-1).Type == token.SEMICOLON || p.peek(-1).Type == token.RBRACE) {
+		p.recovering = false
+		return
+	}
+
+	// make sure we're moving
+	if p.Index == startIndex && p.current().Type != token.EOF {
+		p.advance()
+	}
+
+	for {
+		switch p.current().Type {
+		case token.EOF, token.RBRACE, token.FN, token.STRUCT:
+			p.recovering = false
+			return
+		case token.SEMICOLON:
+			p.advance()
+			p.recovering = false
+			return
+		}
+		p.advance()
+	}
 }
 
 func (p *Parser) rewind(to token.Token) {
 	for p.current().String(false) != to.String(false) {
 		p.Index--
 	}
+
+	// forget the delimiters we're going back before
+	for len(p.delimiters) > 0 && p.delimiters[len(p.delimiters)-1].Pos >= to.Pos {
+		p.delimiters = p.delimiters[:len(p.delimiters)-1]
+	}
+	p.expected = p.expected[:0]
+	p.after = ""
 }
 
 func Parse(tokens []token.Token) []ast.MemberNode {
 	parser := Parser{
-		Tokens: tokens,
-		Index:  0,
+		Tokens:   make([]token.Token, 0, len(tokens)),
+		Index:    0,
+		Comments: make(map[int][]token.Token),
 	}
+
+	for _, tok := range tokens {
+		parser.add(tok)
+	}
+
 	return parser.parseMembers()
 
 }
 
+// ParseFrom parses the tokens handed out by the lexer,
+// the lexer only gets to work once the parser actually needs the next token
+func ParseFrom(lxr *lexer.Lexer) []ast.MemberNode {
+	parser := Parser{
+		Tokens:   make([]token.Token, 0),
+		Index:    0,
+		Comments: make(map[int][]token.Token),
+		Lexer:    lxr,
+	}
+
+	return parser.parseMembers()
+}
+
+// add appends a token to the parser's tokens.
+// Doc comments aren't part of the grammar, we keep them on the side
+// so declarations can pick up the ones in front of them
+func (p *Parser) add(tok token.Token) {
+	if tok.Type == token.COMMENT {
+		p.comments = append(p.comments, tok)
+		return
+	}
+
+	if len(p.comments) > 0 {
+		p.Comments[len(p.Tokens)] = p.comments
+		p.comments = nil
+	}
+	p.Tokens = append(p.Tokens, tok)
+}
+
+// pull lexes tokens until the given index is available (or the file is over)
+func (p *Parser) pull(index int) {
+	if p.Lexer == nil {
+		return
+	}
+
+	for index >= len(p.Tokens) && (len(p.Tokens) == 0 || p.Tokens[len(p.Tokens)-1].Type != token.EOF) {
+		p.add(p.Lexer.Next())
+	}
+}
+
+// rememberSource makes sure the code the parser has seen so far is known to error messages
+func (p *Parser) rememberSource() {
+	if p.Lexer != nil {
+		p.Lexer.RememberSource()
+	}
+}
+
+// docComment returns the doc comments in front of the current token
+func (p *Parser) docComment() []token.Token {
+	return p.Comments[p.Index]
+}
+
 func (p *Parser) parseMembers() []ast.MemberNode {
 	members := make([]ast.MemberNode, 0)
 
 	for p.current().Type != token.EOF {
-		startToken := p.current()
+		// empty members (stray or inserted semicolons)
+		if p.current().Type == token.SEMICOLON {
+			p.consume(token.SEMICOLON)
+			continue
+		}
+
+		startIndex := p.Index
+		errors := p.errors
 
 		// parse all
 
@@ -76,10 +359,10 @@ func (p *Parser) parseMembers() []ast.MemberNode {
 
 		members = append(members, member)
 
-		// if we got stuck
+		// if we got stuck or lost
 
-		if startToken == p.current() {
-			p.Index++
+		if startIndex == p.Index || errors != p.errors {
+			p.synchronize(startIndex)
 		}
 	}
 	return members
@@ -87,16 +370,29 @@ func (p *Parser) parseMembers() []ast.MemberNode {
 
 func (p *Parser) parseMember(allow bool, allowPackages bool) ast.MemberNode {
 
-	if p.current().Type == token.FN {
+	// "set" in front of a declaration exports it from the package
+	if p.current().Type == token.FN || (p.current().Type == token.SET && p.peek(1).Type == token.FN) {
 		return p.parseFunctionDeclaration()
 	}
 
+	if p.current().Type == token.EXTERNAL {
+		return p.parseExternalFunctionDeclaration()
+	}
+
+	// package main, the package this file belongs to
 	if p.current().Type == token.PACKAGE && allowPackages {
+		return p.parsePackageReference()
+	}
+
+	if p.current().Type == token.USING && allowPackages {
 		return p.parsePackageUse()
+	}
 
+	if p.current().Type == token.IMPORT && allowPackages {
+		return p.parseImportDeclaration()
 	}
 
-	if p.current().Type == token.STRUCT {
+	if p.current().Type == token.STRUCT || (p.current().Type == token.SET && p.peek(1).Type == token.STRUCT) {
 		return p.parseStructDeclaration()
 	}
 
@@ -110,6 +406,7 @@ func (p *Parser) parseGlobalStatement() ast.GlobalStatementMember {
 }
 
 func (p *Parser) parseFunctionDeclaration() ast.FunctionDeclarationMember {
+	doc := p.docComment()
 
 	isPublic := false
 	if p.current().Type == token.SET {
@@ -119,6 +416,13 @@ func (p *Parser) parseFunctionDeclaration() ast.FunctionDeclarationMember {
 
 	kw := p.consume(token.FN) // fn yo(opa string) string {?????}
 
+	// fn (p *Point) Len() float, a method
+	var receiver *ast.ReceiverNode
+	if p.current().Type == token.LPAREN {
+		parsed := p.parseReceiver()
+		receiver = &parsed
+	}
+
 	identifier := p.consume(token.IDENT)
 
 	p.consume(token.LPAREN)
@@ -131,7 +435,29 @@ func (p *Parser) parseFunctionDeclaration() ast.FunctionDeclarationMember {
 
 	body := p.parseBlockStatement()
 
-	return ast.CreateFunctionDeclarationMember(kw, identifier, params, typeClause, body, isPublic)
+	if receiver != nil {
+		return ast.CreateMethodDeclarationMember(kw, *receiver, identifier, params, typeClause, body, isPublic, doc)
+	}
+	return ast.CreateFunctionDeclarationMember(kw, identifier, params, typeClause, body, isPublic, doc)
+}
+
+// (p *Point) or (p Point)
+func (p *Parser) parseReceiver() ast.ReceiverNode {
+	p.consume(token.LPAREN)
+
+	identifier := p.consume(token.IDENT)
+
+	pointer := token.Token{}
+	if p.current().Type == token.MUL {
+		pointer = p.consume(token.MUL)
+	}
+
+	typeClause := p.parseTypeClause()
+
+	p.expectAfter("receiver")
+	p.consume(token.RPAREN)
+
+	return ast.CreateReceiverNode(identifier, pointer, typeClause)
 }
 
 func (p *Parser) parseBlockStatement() ast.BlockStatementNode {
@@ -139,13 +465,25 @@ func (p *Parser) parseBlockStatement() ast.BlockStatementNode {
 
 	openBrace := p.consume(token.LBRACE)
 	for p.current().Type != token.EOF && p.current().Type != token.RBRACE {
-		startToken := p.current()
+		// empty statements
+		if p.current().Type == token.SEMICOLON {
+			p.consume(token.SEMICOLON)
+			continue
+		}
+
+		// a member can't be inside of a block, the "}" must be missing
+		if p.current().Type == token.FN || p.current().Type == token.STRUCT {
+			break
+		}
+
+		startIndex := p.Index
+		errors := p.errors
 
 		statement := p.parseStatement()
 		statements = append(statements, statement)
 
-		if startToken == p.current() {
-			p.Index++
+		if startIndex == p.Index || errors != p.errors {
+			p.synchronize(startIndex)
 		}
 	}
 
@@ -154,16 +492,18 @@ func (p *Parser) parseBlockStatement() ast.BlockStatementNode {
 	return ast.CreateBlockStatementNode(openBrace, statements, closeBrace)
 }
 
+// external fn puts(s string) int;
+// the function is implemented in C, we only get its signature
 func (p *Parser) parseExternalFunctionDeclaration() ast.ExternalFunctionDeclarationMember {
-	kw := p.consume(token.EXTERNAL) // external fn yo(opa string) string {?????}
+	kw := p.consume(token.EXTERNAL)
+	p.consume(token.FN)
 
 	identifier := p.consume(token.IDENT)
 
 	p.consume(token.LPAREN)
 	params := p.parseParameterList() // we need only arguments
-	p.consume(token.RPAREN)
-
 	closing := p.consume(token.RPAREN)
+
 	typeClause := p.parseOptionalTypeClause()
 
 	if p.current().Type == token.SEMICOLON {
@@ -175,6 +515,7 @@ func (p *Parser) parseExternalFunctionDeclaration() ast.ExternalFunctionDeclarat
 
 func (p *Parser) parsePackageReference() ast.PackageReferenceMember {
 	kw := p.consume(token.PACKAGE)
+
 	id := p.consume(token.IDENT)
 
 	if p.current().Type == token.SEMICOLON {
@@ -193,6 +534,61 @@ func (p *Parser) parsePackageUse() ast.PackageUseMember {
 	return ast.CreatePackageUseMember(kw, id)
 }
 
+// import "math"
+// import ( m "math"; "io" ), one import per line
+func (p *Parser) parseImportDeclaration() ast.ImportDeclarationMember {
+	kw := p.consume(token.IMPORT)
+
+	if p.current().Type != token.LPAREN {
+		spec := p.parseImportSpec()
+
+		if p.current().Type == token.SEMICOLON {
+			p.consume(token.SEMICOLON)
+		}
+		return ast.CreateImportDeclarationMember(kw, []ast.ImportSpecNode{spec}, spec.Path)
+	}
+
+	p.consume(token.LPAREN)
+
+	imports := make([]ast.ImportSpecNode, 0)
+	for p.current().Type != token.EOF && !p.at(token.RPAREN) {
+		// empty lines (inserted semicolons)
+		if p.current().Type == token.SEMICOLON {
+			p.consume(token.SEMICOLON)
+			continue
+		}
+
+		startIndex := p.Index
+		imports = append(imports, p.parseImportSpec())
+
+		if startIndex == p.Index {
+			break
+		}
+
+		if p.current().Type != token.RPAREN {
+			p.expectAfter("import")
+			p.consume(token.SEMICOLON)
+		}
+	}
+
+	closing := p.consume(token.RPAREN)
+
+	if p.current().Type == token.SEMICOLON {
+		p.consume(token.SEMICOLON)
+	}
+	return ast.CreateImportDeclarationMember(kw, imports, closing)
+}
+
+func (p *Parser) parseImportSpec() ast.ImportSpecNode {
+	alias := token.Token{}
+	if p.current().Type == token.IDENT {
+		alias = p.consume(token.IDENT)
+	}
+
+	path := p.consume(token.STRING)
+	return ast.CreateImportSpecNode(alias, path)
+}
+
 func (p *Parser) parseParameterList() []ast.ParameterNode {
 	params := make([]ast.ParameterNode, 0)
 
@@ -201,9 +597,10 @@ func (p *Parser) parseParameterList() []ast.ParameterNode {
 
 		params = append(params, param)
 
-		if p.current().Type == token.COMMA {
+		if p.at(token.COMMA) {
 			p.consume(token.COMMA)
 		} else {
+			p.expectAfter("parameter")
 			break
 		}
 	}
@@ -212,6 +609,14 @@ func (p *Parser) parseParameterList() []ast.ParameterNode {
 }
 
 func (p *Parser) parseStructDeclaration() ast.StructDeclarationMember {
+	doc := p.docComment()
+
+	isPublic := false
+	if p.current().Type == token.SET {
+		p.consume(token.SET)
+		isPublic = true
+	}
+
 	kw := p.consume(token.STRUCT)
 	id := p.consume(token.IDENT)
 
@@ -223,11 +628,29 @@ func (p *Parser) parseStructDeclaration() ast.StructDeclarationMember {
 
 	fields := make([]ast.ParameterNode, 0)
 	for p.current().Type != token.EOF && p.current().Type != token.RBRACE {
+		// a member can't be inside of a struct, the "}" must be missing
+		if p.current().Type == token.FN || p.current().Type == token.STRUCT {
+			break
+		}
+
+		startIndex := p.Index
+		errors := p.errors
+
 		field := p.parseParameter() // name + type
 
 		fields = append(fields, field)
 
-		if p.current().Type != token.EOF && p.current().Type != token.RBRACE {
+		// skip to the next field
+		if startIndex == p.Index || errors != p.errors {
+			p.synchronize(startIndex)
+			continue
+		}
+
+		// fields are separated by commas or (inserted) semicolons
+		if p.at(token.SEMICOLON) {
+			p.consume(token.SEMICOLON)
+		} else if p.current().Type != token.EOF && !p.at(token.RBRACE) {
+			p.expectAfter("field")
 			p.consume(token.COMMA)
 		}
 
@@ -235,10 +658,20 @@ func (p *Parser) parseStructDeclaration() ast.StructDeclarationMember {
 
 	closing := p.consume(token.RBRACE)
 
-	return ast.CreateStructDeclarationMember(kw, id, fields, closing)
+	return ast.CreateStructDeclarationMember(kw, id, fields, closing, isPublic, doc)
 }
 
 func (p *Parser) parseTypeClause() ast.TypeClauseNode {
+	// []T and [N]T
+	if p.current().Type == token.LBRACK {
+		return p.parseBracketTypeClause()
+	}
+
+	// map[K]V
+	if p.current().Type == token.MAP {
+		return p.parseMapTypeClause()
+	}
+
 	var pack *token.Token = nil
 	if p.peek(1).Type == token.PACKAGE {
 		pck := p.consume(token.IDENT)
@@ -256,7 +689,8 @@ func (p *Parser) parseTypeClause() ast.TypeClauseNode {
 		for true {
 			subTypes = append(subTypes, p.parseTypeClause())
 
-			if p.current().Type != token.COMMA {
+			if !p.at(token.COMMA) {
+				p.expectAfter("type")
 				break
 			}
 			p.consume(token.COMMA)
@@ -277,7 +711,7 @@ func (p *Parser) parseParameter() ast.ParameterNode {
 }
 
 func (p *Parser) parseOptionalTypeClause() ast.TypeClauseNode {
-	if p.current().Type != token.IDENT {
+	if p.current().Type != token.IDENT && p.current().Type != token.LBRACK && p.current().Type != token.MAP {
 		return ast.TypeClauseNode{}
 	}
 
@@ -311,10 +745,11 @@ func (p *Parser) parseStatement() ast.Statement {
 
 	} else if cur == token.WHILE {
 		statement = p.parseWhileStatement()
-	} else if cur == token.FN {
-		statement = p.parseForStatement()
 	} else if cur == token.ELSE {
 		statement = p.parseElseClause()
+	} else if cur == token.IDENT && p.peek(1).Type == token.COMMA &&
+		p.peek(2).Type == token.IDENT && p.peek(3).Type == token.DEFINE {
+		statement = p.parseCommaOkDeclaration()
 	} else {
 		statement = p.parseExpressionStatement()
 	}
@@ -330,14 +765,15 @@ func (p *Parser) parseVariableDeclaration() ast.VariableDeclarationStatementNode
 
 	typeClause := ast.TypeClauseNode{}
 
-	if p.current().Type == token.IDENT &&
-		(p.peek(1).Type == token.IDENT || p.peek(1).Type == token.LBRACK) {
+	if p.current().Type == token.LBRACK || p.current().Type == token.MAP || (p.current().Type == token.IDENT &&
+		(p.peek(1).Type == token.IDENT || p.peek(1).Type == token.LBRACK)) {
 		typeClause = p.parseTypeClause()
 	}
 
 	identifier := p.consume(token.IDENT)
 
-	if p.current().Type == token.SEMICOLON {
+	// only a real ";" starts the initializer, one inserted at a line break ends the declaration
+	if p.current().Type == token.SEMICOLON && !isInsertedSemicolon(p.current()) {
 		p.consume(token.SEMICOLON)
 
 		initializer := p.parseExpression()
@@ -349,13 +785,27 @@ func (p *Parser) parseVariableDeclaration() ast.VariableDeclarationStatementNode
 	}
 }
 
+// v, ok := m[k]
+func (p *Parser) parseCommaOkDeclaration() ast.CommaOkDeclarationStatementNode {
+	value := p.consume(token.IDENT)
+	p.consume(token.COMMA)
+	ok := p.consume(token.IDENT)
+	define := p.consume(token.DEFINE)
+
+	initializer := p.parseExpression()
+
+	return ast.CreateCommaOkDeclarationStatementNode(value, ok, define, initializer)
+}
+
 func (p *Parser) parseExpression() ast.Expression {
 	if p.current().Type == token.TokenType(token.IDENT) && p.peek(1).Type == token.TokenType(token.ADD_ASSIGN) && !p.peek(1).SpaceAfter && token.GetBinaryOperatorPrecedence(p.peek(1)) != 0 {
 		return p.parseVariableEditorExpression()
 	}
 
-	if p.current().Type == token.IDENT && ((p.peek(1).Type == token.ADD) || p.peek(2).Type == token.ADD) ||
-		(p.peek(1).Type == token.SUB || p.peek(2).Type == token.SUB) {
+	// a++ and a--
+	if p.current().Type == token.IDENT && !p.peek(1).SpaceAfter &&
+		((p.peek(1).Type == token.ADD && p.peek(2).Type == token.ADD) ||
+			(p.peek(1).Type == token.SUB && p.peek(2).Type == token.SUB)) {
 		identifier := p.consume(token.IDENT)
 		operator := p.consume(p.current().Type)
 		p.consume(p.current().Type)
@@ -388,31 +838,50 @@ func (p *Parser) parseBinaryExpression(parentPrecedence int) ast.Expression {
 
 	unaryPrecedence := token.GetUnaryOperatorPrecedence(p.current())
 
-	if unaryPrecedence != 0 && unaryPrecedence > parentPrecedence {
+	// a unary operator can follow another one (**p, - -x), so it only needs the same precedence
+	if unaryPrecedence != 0 && unaryPrecedence >= parentPrecedence {
 		operator := p.consume(p.current().Type)
+		p.expectAfter(describeType(operator.Type))
 		operand := p.parseBinaryExpression(unaryPrecedence)
-		return ast.CreateUnaryExpressionNode(operator, operand)
+
+		// &x and *p get their own nodes, the binder types them as pointers
+		switch operator.Type {
+		case token.AND:
+			left = ast.CreateReferenceExpressionNode(operator, operand)
+		case token.MUL:
+			left = ast.CreateDereferenceExpressionNode(operator, operand)
+		default:
+			left = ast.CreateUnaryExpressionNode(operator, operand)
+		}
 
 		// if not, start by parsing our left expression
 
 	} else {
-		left = p.parsePrimaryExpression()
+		left = p.parsePostfixExpression(p.parsePrimaryExpression())
+	}
 
-		if p.current().Type == token.LBRACK {
-			left = p.parseArrayAccessExpressionFromValue(left)
-		}
+	for {
+		precedence := token.GetBinaryOperatorPrecedence(p.current())
 
-		for {
-			precedence := token.GetBinaryOperatorPrecedence(p.current())
+		if precedence == 0 || precedence <= parentPrecedence {
+			break
+		}
 
-			if precedence == 0 || precedence <= parentPrecedence {
-				break
-			}
+		operator := p.consume(p.current().Type)
+		p.expectAfter(describeType(operator.Type))
 
-			operator := p.consume(p.current().Type)
+		// a right associative operator lets its right side continue on the same level
+		rightPrecedence := precedence
+		if token.GetBinaryOperatorAssociativity(operator) == token.RightAssociative {
+			rightPrecedence--
+		}
 
-			right := p.parseBinaryExpression(precedence)
+		right := p.parseBinaryExpression(rightPrecedence)
 
+		// && and || short circuit, so they get their own node
+		if operator.Type == token.LAND || operator.Type == token.LOR {
+			left = ast.CreateLogicalExpressionNode(operator, left, right)
+		} else {
 			left = ast.CreateBinaryExpressionNode(operator, left, right)
 		}
 	}
@@ -426,7 +895,9 @@ func (p *Parser) parsePrimaryExpression() ast.Expression {
 
 	if cur == token.STRING {
 		return p.parseStringLiteral()
-	} else if cur == token.INT || cur == token.UINT || cur == token.FLOAT32 || cur == token.FLOAT64 {
+	} else if cur == token.INTERPOLATION_START {
+		return p.parseInterpolatedString()
+	} else if cur == token.INT || cur == token.UINT || cur == token.FLOAT32 || cur == token.FLOAT64 || cur == token.CHAR {
 		return p.parseNumberLiteral()
 	} else if cur == token.TRUE || cur == token.FALSE {
 		return p.parseBoolLiteral()
@@ -438,42 +909,17 @@ func (p *Parser) parsePrimaryExpression() ast.Expression {
 		return p.parseTypeCallExpression()
 	} else if cur == token.IDENT {
 		return p.parseNameOrCallExpression()
-	} else if p.current().Type == token.POINTER {
-		return p.parseReferenceExpression()
-
-	} else if cur == token.ADDRESS {
-		return p.parseDereferenceExpression()
 	} else if cur == token.MAIN {
 		return p.parseMainExpression()
+	} else if cur == token.MAKE {
+		return p.parseMakeExpression()
 	}
 
-	additionalInfo := ""
-
-	print2.Error(
-		"PARSER",
-		print2.UnexpectedTokenError,
-		p.current().Span,
-		"unexpected token: "+p.current().Type.String()+"\n"+additionalInfo,
-		p.current().Type,
-	)
-	os.Exit(1)
-
-	return nil
-
-}
+	// report it and leave a placeholder, the statement will be skipped
+	p.reportError(p.currentSpan(), "expected %s, found %s", p.expectation("expression"), describeFound(p.current()))
 
-func (p *Parser) parseReferenceExpression() ast.ReferenceExpressionNode {
-	keyword := p.consume(token.POINTER)
-	expression := p.parseNameExpression()
+	return ast.CreateMissingExpressionNode(p.current())
 
-	return ast.CreateReferenceExpressionNode(keyword, expression)
-}
-
-func (p *Parser) parseDereferenceExpression() ast.DereferenceExpressionNode {
-	keyword := p.consume(token.ADDRESS)
-	expression := p.parsePrimaryExpression()
-
-	return ast.CreateDereferenceExpressionNode(keyword, expression)
 }
 
 func (p *Parser) parseIfStatement() ast.IfStatementNode {
@@ -485,6 +931,7 @@ func (p *Parser) parseIfStatement() ast.IfStatementNode {
 
 	condition := p.parseExpression()
 
+	p.expectAfter("condition")
 	p.consume(token.RPAREN)
 
 	statement := p.parseStatement()
@@ -530,7 +977,7 @@ func (p *Parser) parseReturnStatement() ast.ReturnStatementNode {
 
 	var expression ast.Expression = nil
 
-	if p.current().Type != token.SEMICOLON {
+	if p.current().Type != token.SEMICOLON && p.current().Type != token.RBRACE {
 		expression = p.parseExpression()
 	}
 
@@ -538,9 +985,13 @@ func (p *Parser) parseReturnStatement() ast.ReturnStatementNode {
 
 }
 
-func (p *Parser) parseForStatement() ast.ForStatementNode {
+func (p *Parser) parseForStatement() ast.Statement {
 	keyword := p.consume(token.FOR)
 
+	if p.current().Type != token.LPAREN {
+		return p.parseForRangeStatement(keyword)
+	}
+
 	p.consume(token.LPAREN)
 
 	initializer := p.parseVariableDeclaration()
@@ -561,6 +1012,30 @@ func (p *Parser) parseForStatement() ast.ForStatementNode {
 
 }
 
+// for range x, for i := range x or for i, v := range x
+func (p *Parser) parseForRangeStatement(keyword token.Token) ast.ForRangeStatementNode {
+	key := token.Token{}
+	value := token.Token{}
+
+	if p.current().Type != token.RANGE {
+		key = p.consume(token.IDENT)
+
+		if p.current().Type == token.COMMA {
+			p.consume(token.COMMA)
+			value = p.consume(token.IDENT)
+		}
+
+		p.consume(token.DEFINE)
+	}
+
+	rangeKeyword := p.consume(token.RANGE)
+	expression := p.parseExpression()
+
+	statement := p.parseStatement()
+
+	return ast.CreateForRangeStatementNode(keyword, key, value, rangeKeyword, expression, statement)
+}
+
 func (p *Parser) parseWhileStatement() ast.WhileStatementNode {
 
 	keyword := p.consume(token.WHILE)
@@ -569,6 +1044,7 @@ func (p *Parser) parseWhileStatement() ast.WhileStatementNode {
 
 	condition := p.parseExpression()
 
+	p.expectAfter("condition")
 	p.consume(token.RPAREN) // )
 
 	statement := p.parseStatement()
@@ -650,6 +1126,40 @@ func (p *Parser) parseComplexCastExpression() ast.Expression {
 	return ast.CreateCallExpressionNode(identifier, []ast.Expression{expression}, typeClause, closing)
 }
 
+// []int (a slice) or [4]int (an array of 4 ints)
+func (p *Parser) parseBracketTypeClause() ast.TypeClauseNode {
+	opening := p.consume(token.LBRACK)
+
+	length := token.Token{}
+	if p.current().Type == token.INT {
+		length = p.consume(token.INT)
+	}
+
+	p.expectAfter(describeType(opening.Type))
+	closing := p.consume(token.RBRACK)
+
+	element := p.parseTypeClause()
+
+	if length.Type == token.INT {
+		return ast.CreateArrayTypeClauseNode(opening, length, closing, element)
+	}
+	return ast.CreateSliceTypeClauseNode(opening, closing, element)
+}
+
+func (p *Parser) parseMapTypeClause() ast.TypeClauseNode {
+	keyword := p.consume(token.MAP)
+
+	p.consume(token.LBRACK)
+	key := p.parseTypeClause()
+
+	p.expectAfter("key type")
+	closing := p.consume(token.RBRACK)
+
+	value := p.parseTypeClause()
+
+	return ast.CreateMapTypeClauseNode(keyword, key, closing, value)
+}
+
 func (p *Parser) parseUncertainTypeClause() (ast.TypeClauseNode, bool) {
 	var pack *token.Token = nil
 
@@ -715,6 +1225,52 @@ func (p *Parser) parsePackageCallExpression() ast.PackageCallExpressionNode {
 
 }
 
+// parsePostfixExpression parses the indexing, field accesses and method calls after a value: a[i].b.c()
+// an assignment (a[i] = v, a.b = v) ends the chain
+func (p *Parser) parsePostfixExpression(base ast.Expression) ast.Expression {
+	for {
+		switch p.current().Type {
+		case token.LBRACK:
+			base = p.parseArrayAccessExpressionFromValue(base)
+			if _, isAssignment := base.(ast.ArrayAssignmentExpressionNode); isAssignment {
+				return base
+			}
+		case token.PERIOD:
+			base = p.parseFieldAccessExpressionFromValue(base)
+			if _, isAssignment := base.(ast.ClassFieldAssignmentExpressionNode); isAssignment {
+				return base
+			}
+		default:
+			return base
+		}
+	}
+}
+
+// p.x or p.x = value
+func (p *Parser) parseFieldAccessExpressionFromValue(base ast.Expression) ast.Expression {
+	period := p.consume(token.PERIOD) // .
+
+	p.expectAfter(describeType(period.Type))
+	field := p.consume(token.IDENT)
+
+	if p.current().Type == token.ASSIGN {
+		p.consume(token.ASSIGN)
+		value := p.parseExpression()
+		return ast.CreateClassFieldAssignmentExpressionNode(base, field, value)
+	}
+
+	// p.Len(), a method call
+	if p.current().Type == token.LPAREN {
+		p.consume(token.LPAREN)            // (
+		args := p.parseArguments()         //  we get arguments
+		closing := p.consume(token.RPAREN) // )
+
+		return ast.CreateTypeCallExpressionNode(base, field, args, closing)
+	}
+
+	return ast.CreateClassFieldAccessExpressionNode(base, field)
+}
+
 func (p *Parser) parseArrayAccessExpression() ast.Expression {
 	base := p.parseNameExpression()
 
@@ -724,9 +1280,21 @@ func (p *Parser) parseArrayAccessExpression() ast.Expression {
 
 func (p *Parser) parseArrayAccessExpressionFromValue(base ast.Expression) ast.Expression {
 	// we need the identifier to know which package to select
-	p.consume(token.LBRACK)      // [
+	p.consume(token.LBRACK) // [
+
+	// a[:high]
+	if p.current().Type == token.COLON {
+		return p.parseSliceExpressionFromValue(base, nil)
+	}
+
 	index := p.parseExpression() //  we get arguments
-	p.consume(token.RBRACK)      // ]
+
+	// a[low:high]
+	if p.current().Type == token.COLON {
+		return p.parseSliceExpressionFromValue(base, index)
+	}
+
+	p.consume(token.RBRACK) // ]
 
 	if p.current().Type == token.ASSIGN {
 		p.consume(token.ASSIGN)
@@ -738,9 +1306,39 @@ func (p *Parser) parseArrayAccessExpressionFromValue(base ast.Expression) ast.Ex
 
 }
 
+// the rest of a[low:high] or a[low:high:max], after the low bound (which may be left out)
+func (p *Parser) parseSliceExpressionFromValue(base ast.Expression, low ast.Expression) ast.Expression {
+	p.consume(token.COLON) // :
+
+	// a[low:]
+	var high ast.Expression
+	if p.current().Type != token.RBRACK && p.current().Type != token.COLON {
+		high = p.parseExpression()
+	}
+
+	// a[low:high:max], high and max are required here
+	var max ast.Expression
+	if p.current().Type == token.COLON {
+		colon := p.consume(token.COLON)
+		if high == nil {
+			p.reportError(colon.Span(), "the middle index is required in a[low:high:max]")
+		}
+		max = p.parseExpression()
+	}
+
+	p.expectAfter("index")
+	closing := p.consume(token.RBRACK) // ]
+
+	return ast.CreateSliceExpressionNode(base, low, high, max, closing)
+}
+
 func (p *Parser) parseMakeExpression() ast.Expression {
 	makeKeyword := p.consume(token.MAKE) // make
 
+	if p.current().Type == token.MAP {
+		return p.parseMakeMapExpression(makeKeyword)
+	}
+
 	var pack *token.Token = nil
 	if p.peek(1).Type == token.PACKAGE {
 		pck := p.consume(token.IDENT)
@@ -768,28 +1366,89 @@ func (p *Parser) parseMakeExpression() ast.Expression {
 
 }
 
+// make Point{1, 2} or make Point{x: 1, y: 2}
 func (p *Parser) parseMakeStructExpression(makeKeyword token.Token, baseType token.Token) ast.Expression {
 	literals := make([]ast.Expression, 0)
+	keys := make([]token.Token, 0)
 
 	p.consume(token.LBRACE) // {
 
 	for p.current().Type != token.RBRACE &&
 		p.current().Type != token.EOF {
+		// x: 1
+		keyed := p.current().Type == token.IDENT && p.peek(1).Type == token.COLON
+
+		// the literal is whatever its first value is, values of the other kind are left out
+		mixed := keyed != (len(keys) > 0) && len(literals) > 0
+		if mixed {
+			p.reportError(p.currentSpan(), "can't mix keyed and positional values in a struct literal")
+		}
+
+		if keyed {
+			key := p.consume(token.IDENT)
+			p.consume(token.COLON)
+
+			if !mixed {
+				keys = append(keys, key)
+			}
+		}
+
 		expression := p.parseExpression()
-		literals = append(literals, expression)
+		if !mixed {
+			literals = append(literals, expression)
+		}
 
-		if p.current().Type == token.COMMA {
+		if p.at(token.COMMA) {
 			p.consume(token.COMMA)
 		} else {
+			p.expectAfter("value")
 			break
 		}
 	}
 
 	closing := p.consume(token.RBRACE) // }
 
+	if len(keys) > 0 {
+		return ast.CreateKeyedMakeStructExpressionNode(baseType, keys, literals, makeKeyword, closing)
+	}
 	return ast.CreateMakeStructExpressionNode(baseType, literals, makeKeyword, closing)
 }
 
+// make map[string]int{"a": 1} or make map[string]int
+func (p *Parser) parseMakeMapExpression(makeKeyword token.Token) ast.Expression {
+	typ := p.parseMapTypeClause()
+
+	keys := make([]ast.Expression, 0)
+	values := make([]ast.Expression, 0)
+
+	if p.current().Type != token.LBRACE {
+		return ast.CreateMakeMapExpressionNode(typ, keys, values, makeKeyword, token.Token{})
+	}
+
+	p.consume(token.LBRACE) // {
+
+	for p.current().Type != token.RBRACE &&
+		p.current().Type != token.EOF {
+		keys = append(keys, p.parseExpression())
+
+		p.expectAfter("key")
+		p.consume(token.COLON)
+
+		values = append(values, p.parseExpression())
+
+		if p.at(token.COMMA) {
+			p.consume(token.COMMA)
+		} else {
+			p.expectAfter("value")
+			break
+		}
+	}
+
+	closing := p.consume(token.RBRACE) // }
+
+	return ast.CreateMakeMapExpressionNode(typ, keys, values, makeKeyword, closing)
+}
+
 func (p *Parser) parseMakeArrayExpression() ast.MakeArrayExpressionNode {
 	keyword := p.consume(token.MAKE) // make
 
@@ -808,9 +1467,10 @@ func (p *Parser) parseMakeArrayExpression() ast.MakeArrayExpressionNode {
 			expression := p.parseExpression()
 			literals = append(literals, expression)
 
-			if p.current().Type == token.COMMA {
+			if p.at(token.COMMA) {
 				p.consume(token.COMMA)
 			} else {
+				p.expectAfter("value")
 				break
 			}
 		}
@@ -906,9 +1566,10 @@ func (p *Parser) parseArguments() []ast.Expression {
 		p.current().Type != token.EOF {
 		expression := p.parseExpression()
 		args = append(args, expression)
-		if p.current().Type == token.COMMA {
+		if p.at(token.COMMA) {
 			p.consume(token.COMMA)
 		} else {
+			p.expectAfter("argument")
 			break
 		}
 	}
@@ -922,18 +1583,40 @@ func (p *Parser) parseStringLiteral() ast.LiteralExpressionNode {
 	return ast.CreateLiteralExpressionNode(str)
 }
 
+// "user ${name} has ${count} items"
+func (p *Parser) parseInterpolatedString() ast.InterpolatedStringExpressionNode {
+	parts := []token.Token{p.consume(token.INTERPOLATION_START)}
+	expressions := make([]ast.Expression, 0)
+
+	for {
+		expressions = append(expressions, p.parseExpression())
+
+		if !p.at(token.INTERPOLATION_MIDDLE) {
+			p.expectAfter("embedded expression")
+			break
+		}
+		parts = append(parts, p.consume(token.INTERPOLATION_MIDDLE))
+	}
+
+	parts = append(parts, p.consume(token.INTERPOLATION_END))
+	return ast.CreateInterpolatedStringExpressionNode(parts, expressions)
+}
+
 func (p *Parser) parseNumberLiteral() ast.LiteralExpressionNode {
 	if p.current().Type == token.INT {
 		integer := p.consume(token.INT)
 		return ast.CreateLiteralExpressionNode(integer)
 	} else if p.current().Type == token.FLOAT32 {
-		float := p.consume(token.FLOAT64)
+		float := p.consume(token.FLOAT32)
 		return ast.CreateLiteralExpressionNode(float)
 	} else if p.current().Type == token.FLOAT64 {
 		float := p.consume(token.FLOAT64)
 		return ast.CreateLiteralExpressionNode(float)
 	} else if p.current().Type == token.UINT {
-		char := p.consume(token.UINT)
+		integer := p.consume(token.UINT)
+		return ast.CreateLiteralExpressionNode(integer)
+	} else if p.current().Type == token.CHAR {
+		char := p.consume(token.CHAR)
 		return ast.CreateLiteralExpressionNode(char)
 	}
 
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/lexer"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// parse parses code with semicolons inserted at line ends,
+// it returns the members and the errors reported on the way
+func parse(t *testing.T, code string) ([]ast.MemberNode, []print2.ErrorReport) {
+	t.Helper()
+	print2.OutputErrorMessages = false
+	print2.ErrorList = print2.ErrorList[:0]
+
+	lxr := lexer.New(strings.NewReader(code), t.Name()+".tod")
+	lxr.InsertSemicolons = true
+	members := ParseFrom(lxr)
+
+	return members, print2.ErrorList
+}
+
+// expressions parses code made of expression statements only and returns their expressions
+func expressions(t *testing.T, code string) []ast.Expression {
+	t.Helper()
+	members, errors := parse(t, code)
+	if len(errors) != 0 {
+		t.Fatalf("%q: unexpected errors %v", code, errors)
+	}
+
+	result := make([]ast.Expression, 0, len(members))
+	for _, member := range members {
+		global, ok := member.(ast.GlobalStatementMember)
+		if !ok {
+			t.Fatalf("%q: expected a statement, got %T", code, member)
+		}
+		statement, ok := global.Statement.(ast.ExpressionStatementNode)
+		if !ok {
+			t.Fatalf("%q: expected an expression statement, got %T", code, global.Statement)
+		}
+		result = append(result, statement.Expression)
+	}
+	return result
+}
+
+// TestPointerOperators checks that unary & and * get their own nodes
+// and binary & and * are still operators
+func TestPointerOperators(t *testing.T) {
+	cases := []struct {
+		code     string
+		expected reflect.Type
+	}{
+		{"&x", reflect.TypeOf(ast.ReferenceExpressionNode{})},
+		{"*p", reflect.TypeOf(ast.DereferenceExpressionNode{})},
+		{"**p", reflect.TypeOf(ast.DereferenceExpressionNode{})},
+		{"*&x", reflect.TypeOf(ast.DereferenceExpressionNode{})},
+		{"-x", reflect.TypeOf(ast.UnaryExpressionNode{})},
+		{"- -x", reflect.TypeOf(ast.UnaryExpressionNode{})},
+		{"a & b", reflect.TypeOf(ast.BinaryExpressionNode{})},
+		{"a * *p", reflect.TypeOf(ast.BinaryExpressionNode{})},
+	}
+
+	for _, c := range cases {
+		expression := expressions(t, c.code)[0]
+		if got := reflect.TypeOf(expression); got != c.expected {
+			t.Errorf("%q: parsed as %s, expected %s", c.code, got, c.expected)
+		}
+	}
+
+	// the operand of a unary operator is parsed at its precedence, *p + 1 is (*p) + 1
+	binary, ok := expressions(t, "*p + 1")[0].(ast.BinaryExpressionNode)
+	if !ok {
+		t.Fatalf("*p + 1: expected a binary expression")
+	}
+	if _, ok := binary.Left.(ast.DereferenceExpressionNode); !ok {
+		t.Errorf("*p + 1: left side is %T, expected a dereference", binary.Left)
+	}
+
+	// the operand ends up in both fields the binder might look at
+	reference := expressions(t, "&x")[0].(ast.ReferenceExpressionNode)
+	if _, ok := reference.ExpressionNode.(ast.NameExpressNode); !ok {
+		t.Errorf("&x: operand is %T, expected a name", reference.ExpressionNode)
+	}
+}
+
+// TestFloatLiterals checks that suffixed floats parse as literals, with a single error for a bad one
+func TestFloatLiterals(t *testing.T) {
+	for _, code := range []string{"2.5f", "1e3f", "2.5", "var float x; 2.5f"} {
+		members, errors := parse(t, code)
+		if len(errors) != 0 || len(members) != 1 {
+			t.Errorf("%q: parsed to %d members with errors %v", code, len(members), errors)
+		}
+	}
+
+	literal := expressions(t, "2.5f")[0].(ast.LiteralExpressionNode)
+	if literal.LiteralToken.Type != token.FLOAT32 || literal.LiteralToken.RealValue != float32(2.5) {
+		t.Errorf("2.5f: parsed to %v %#v", literal.LiteralToken.Type, literal.LiteralToken.RealValue)
+	}
+
+	// the parser gives up on the declaration at "]", which comes after its ";",
+	// that mustn't make it report the same error again for the next statement
+	if _, errors := parse(t, "var int x; ]\n"); len(errors) != 1 {
+		t.Errorf("expected one error, got %v", errors)
+	}
+}
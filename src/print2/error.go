@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"unicode/utf8"
 )
 
 // ErrorReport is a structure to hold all info about an error
@@ -14,10 +15,20 @@ type ErrorReport struct {
 	Span        TextSpan
 	Message     string
 	MessageArgs []interface{}
+	Notes       []ErrorNote
+}
+
+// ErrorNote points at another place in the code that helps to understand an error
+type ErrorNote struct {
+	Span    TextSpan
+	Message string
 }
 
 var ErrorList = make([]ErrorReport, 0)
 
+// WarningList holds the warnings, they don't stop the compilation
+var WarningList = make([]ErrorReport, 0)
+
 // CodeReference stores code for both error lookups and compiler-time error messages.
 // It stores code for error lookups, when compiling it is overwritten with the code to compile.
 var CodeReference []string = []string{
@@ -26,7 +37,8 @@ var CodeReference []string = []string{
 
 var OutputErrorMessages = true
 
-var SourceFiles = make(map[string]string)
+// SourceFiles holds the lines of every file we know the code of, for code snippets in errors
+var SourceFiles = make(map[string][]string)
 
 // When no data can be found for line, length or column
 
@@ -49,7 +61,22 @@ func Error(area string, _type ErrorType, span TextSpan, message string, fargs ..
 	}
 
 	// remember this error
-	ErrorList = append(ErrorList, ErrorReport{area, _type, span, message, fargs})
+	ErrorList = append(ErrorList, ErrorReport{area, _type, span, message, fargs, nil})
+}
+
+// Note prints a note pointing at another place in the code,
+// it belongs to the error reported right before it
+func Note(span TextSpan, message string, fargs ...interface{}) {
+	if OutputErrorMessages {
+		PrintCodeSnippet(span)
+		WriteCF(DarkCyan, "[> Note(%d, %d, %s): ", span.StartLine, span.StartColumn, span.File)
+		PrintCF(Gray, message, fargs...)
+	}
+
+	if len(ErrorList) > 0 {
+		last := &ErrorList[len(ErrorList)-1]
+		last.Notes = append(last.Notes, ErrorNote{span, fmt.Sprintf(message, fargs...)})
+	}
 }
 
 func CrashIfErrorsFound() {
@@ -79,18 +106,23 @@ func ErrorS(area string, _type ErrorType, span TextSpan, message string, a ...in
 
 // Warning prints custom warning message and code snippet to terminal/console
 func Warning(area string, _type ErrorType, span TextSpan, message string, fargs ...interface{}) {
-	PrintCodeSnippet(span)
-	WriteCF(Cyan, "[%s] ", strings.ToUpper(area))
-	WriteC(DarkCyan, string(_type))
-	WriteCF(DarkYellow, " Warning(%d, %d, %s): ", span.StartLine, span.StartColumn, span.File)
-	WriteCF(Gray, message, fargs...)
-	code := ErrorTypeToCode(_type)
-	WriteC(DarkYellow, "\n[> Error look up code: ")
-	WriteCF(Cyan, "%d", code)
-	WriteC(DarkYellow, " (use: ")
-	WriteC(Yellow, "rgoc -lookup ")
-	WriteCF(Cyan, "%d", code)
-	PrintC(DarkYellow, ", for more information)]\n")
+	if OutputErrorMessages {
+		PrintCodeSnippet(span)
+		WriteCF(Cyan, "[%s] ", strings.ToUpper(area))
+		WriteC(DarkCyan, string(_type))
+		WriteCF(DarkYellow, " Warning(%d, %d, %s): ", span.StartLine, span.StartColumn, span.File)
+		WriteCF(Gray, message, fargs...)
+		code := ErrorTypeToCode(_type)
+		WriteC(DarkYellow, "\n[> Error look up code: ")
+		WriteCF(Cyan, "%d", code)
+		WriteC(DarkYellow, " (use: ")
+		WriteC(Yellow, "rgoc -lookup ")
+		WriteCF(Cyan, "%d", code)
+		PrintC(DarkYellow, ", for more information)]\n")
+	}
+
+	// remember this warning
+	WarningList = append(WarningList, ErrorReport{area, _type, span, message, fargs, nil})
 }
 
 // PrintCodeSnippet does what it says on the label, it prints a snippet of the code in CodeReference.
@@ -100,8 +132,19 @@ func PrintCodeSnippet(span TextSpan) {
 		return
 	}
 
-	errorFile := SourceFiles[span.File]
-	errorLines := strings.Split(errorFile, "\n")
+	errorLines := SourceFiles[span.File]
+
+	// the file is read while lexing, we might not know the line (yet)
+	if span.StartLine <= 0 || span.StartLine > len(errorLines) {
+		return
+	}
+
+	// columns are counted in bytes, the markers need them in characters
+	startColumn := characterColumn(errorLines[span.StartLine-1], span.StartColumn)
+	endColumn := span.EndColumn
+	if span.EndLine <= len(errorLines) {
+		endColumn = characterColumn(errorLines[span.EndLine-1], span.EndColumn)
+	}
 
 	// is the error contained on a single line?
 	if span.StartLine == span.EndLine {
@@ -112,10 +155,10 @@ func PrintCodeSnippet(span TextSpan) {
 		fmt.Printf("\n")
 
 		// spacer to the start of the error
-		WriteC(Gray, strings.Repeat(" ", span.StartColumn+offset-1))
+		WriteC(Gray, strings.Repeat(" ", startColumn+offset-1))
 
-		// add the error marker
-		PrintC(Red, strings.Repeat("^", span.EndColumn-span.StartColumn))
+		// add the error marker, even errors without a width (like a missing token) get one
+		PrintC(Red, strings.Repeat("^", max(endColumn-startColumn, 1)))
 
 		// we don
 		return
@@ -126,16 +169,16 @@ func PrintCodeSnippet(span TextSpan) {
 	offset := GetOffset(span.StartLine)
 
 	// spacer to the start of the error
-	WriteC(Gray, strings.Repeat(" ", span.StartColumn+offset-1))
+	WriteC(Gray, strings.Repeat(" ", startColumn+offset-1))
 
 	// add the error marker for the start of the borblem
 	WriteC(Red, "v")
 
 	// loins
-	WriteC(Red, strings.Repeat("-", len(errorLines[span.StartLine])-span.StartColumn+1))
+	WriteC(Red, strings.Repeat("-", max(utf8.RuneCountInString(errorLines[span.StartLine-1])-startColumn, 0)))
 
 	// print the lines
-	for i := span.StartLine; i < span.EndLine; i++ {
+	for i := span.StartLine; i <= span.EndLine && i <= len(errorLines); i++ {
 		line := errorLines[i-1] // lines are 1-indexed
 
 		// output the line
@@ -143,8 +186,8 @@ func PrintCodeSnippet(span TextSpan) {
 	}
 	fmt.Printf("\n")
 
-	// spacer to the start of the error
-	WriteC(Red, strings.Repeat("-", span.EndColumn+GetOffset(span.EndLine)+1))
+	// spacer to the end of the error
+	WriteC(Red, strings.Repeat("-", max(endColumn+GetOffset(span.EndLine)-2, 0)))
 
 	// add the error marker
 	WriteC(Red, "^")
@@ -152,6 +195,14 @@ func PrintCodeSnippet(span TextSpan) {
 	fmt.Printf("\n")
 }
 
+// characterColumn converts a byte column of the given line into a character column
+func characterColumn(line string, column int) int {
+	if column <= 1 {
+		return column
+	}
+	return utf8.RuneCountInString(line[:min(column-1, len(line))]) + 1
+}
+
 func PrintLineOfCode(nr int, line string) int {
 	prefix := fmt.Sprintf("%d |  ", nr)
 	WriteCF(White, "\n%s%s", prefix, strings.Replace(line, "\t", " ", -1))
@@ -214,6 +265,8 @@ const (
 
 	// Preprocessor Errors
 	FileAlreadyInSourcesWarning = "FileAlreadyInSources Warning"
+	InvalidDirectiveError       = "InvalidDirective"
+	ErrorDirectiveError         = "ErrorDirective"
 
 	// Lexer Errors
 	UnexpectedCharacterError = "UnexpectedCharacter"
@@ -266,6 +319,7 @@ const (
 	UnexpectedNonPointerValueError        = "UnexpectedNonPointerValueError"
 	TooManyStructParametersError          = "TooManyStructParametersError"
 	OutsideThisError                      = "OutsideThisError"
+	ConfusableIdentifierWarning           = "ConfusableIdentifierWarning"
 
 	// Emitter Errors
 	UnknownVTableError       = "UnknownVTableError"
@@ -282,6 +336,21 @@ const (
 	UnparsableFingerprintError        = "UnparsableFingerprintError"
 	ImpossibleFunctionProcessingError = "ImpossibleFunctionProcessingError"
 	ImpossibleFieldProcessingError    = "ImpossibleFieldProcessingError"
+
+	// Package loading Errors
+	EmptyPackageError           = "EmptyPackageError"
+	MissingPackageClauseError   = "MissingPackageClauseError"
+	MisplacedPackageClauseError = "MisplacedPackageClauseError"
+	PackageNameMismatchError    = "PackageNameMismatchError"
+	ImportCycleError            = "ImportCycleError"
+
+	// more Binder Errors
+	DuplicateFieldError         = "DuplicateFieldError"
+	InvalidMapKeyError          = "InvalidMapKeyError"
+	UnexpectedNonMapValueError  = "UnexpectedNonMapValueError"
+	InvalidRangeError           = "InvalidRangeError"
+	InvalidReferenceError       = "InvalidReferenceError"
+	TooFewStructParametersError = "TooFewStructParametersError"
 )
 
 // ErrorCode the numerical representation of an Error, this allows it to be "looked up"
@@ -367,6 +436,28 @@ const (
 	UnparsableFingerprintErrorCode        = iota + 5000
 	ImpossibleFunctionProcessingErrorCode = iota + 5000
 	ImpossibleFieldProcessingErrorCode    = iota + 5000
+
+	// Preprocessor directive ErrorCodes (start at 6000)
+	InvalidDirectiveErrorCode = iota + 6000
+	ErrorDirectiveErrorCode   = iota + 6000
+
+	// Binder warnings (iota keeps counting, so they land after the other binder codes)
+	ConfusableIdentifierWarningCode = iota + 3000
+
+	// Package loading ErrorCodes (iota keeps counting, they land after the other packager codes)
+	EmptyPackageErrorCode           = iota + 5000
+	MissingPackageClauseErrorCode   = iota + 5000
+	MisplacedPackageClauseErrorCode = iota + 5000
+	PackageNameMismatchErrorCode    = iota + 5000
+	ImportCycleErrorCode            = iota + 5000
+
+	// more Binder ErrorCodes (iota keeps counting, they land after the other binder codes)
+	DuplicateFieldErrorCode         = iota + 3000
+	InvalidMapKeyErrorCode          = iota + 3000
+	UnexpectedNonMapValueErrorCode  = iota + 3000
+	InvalidRangeErrorCode           = iota + 3000
+	InvalidReferenceErrorCode       = iota + 3000
+	TooFewStructParametersErrorCode = iota + 3000
 )
 
 var ErrorTypeCodeRelations = map[ErrorType]ErrorCode{
@@ -430,6 +521,22 @@ var ErrorTypeCodeRelations = map[ErrorType]ErrorCode{
 	CAdapterCompilationError:              CAdapterCompilationErrorCode,
 	ExternalCAdapterWarning:               ExternalCAdapterWarningCode,
 	OutsideThisError:                      OutsideThisErrorCode,
+	InvalidDirectiveError:                 InvalidDirectiveErrorCode,
+	ErrorDirectiveError:                   ErrorDirectiveErrorCode,
+	ConfusableIdentifierWarning:           ConfusableIdentifierWarningCode,
+
+	EmptyPackageError:           EmptyPackageErrorCode,
+	MissingPackageClauseError:   MissingPackageClauseErrorCode,
+	MisplacedPackageClauseError: MisplacedPackageClauseErrorCode,
+	PackageNameMismatchError:    PackageNameMismatchErrorCode,
+	ImportCycleError:            ImportCycleErrorCode,
+
+	DuplicateFieldError:         DuplicateFieldErrorCode,
+	InvalidMapKeyError:          InvalidMapKeyErrorCode,
+	UnexpectedNonMapValueError:  UnexpectedNonMapValueErrorCode,
+	InvalidRangeError:           InvalidRangeErrorCode,
+	InvalidReferenceError:       InvalidReferenceErrorCode,
+	TooFewStructParametersError: TooFewStructParametersErrorCode,
 }
 
 func ErrorTypeToCode(e ErrorType) ErrorCode {
@@ -518,6 +625,149 @@ The most likely cause of this error is &drmultiple points in float literals&dr.`
 		"example":    "",
 		"additional": "",
 	},
+	InvalidDirectiveErrorCode: {
+		"name": "InvalidDirective",
+		"area": "Lexer",
+		"explanation": `Lines starting with &w#define&w, &w#if&w, &w#elif&w, &w#else&w, &w#endif&w or &w#error&w are &bpreprocessor directives&b.
+This error occurs when a directive is &rmalformed&r (like a &w#define&w without a name or an &w#if&w without a condition)
+or when the &w#if&w/&w#endif&w blocks &rdon't match up&r (like an &w#endif&w without an &w#if&w, or an &w#if&w that is never closed).`,
+		"example":    "",
+		"additional": "",
+	},
+	ErrorDirectiveErrorCode: {
+		"name": "ErrorDirective",
+		"area": "Lexer",
+		"explanation": `This error is raised by an &w#error&w directive in your code. It only fires if the directive is in a part of
+the code that's &wnot skipped&w by &w#if&w/&w#elif&w/&w#else&w, it's usually used to stop compilation for unsupported configurations.`,
+		"example":    "",
+		"additional": "",
+	},
+	ConfusableIdentifierWarningCode: {
+		"name": "ConfusableIdentifierWarning",
+		"area": "Binder",
+		"explanation": `This warning occurs when &wtwo identifiers look the same&w but are written with &rdifferent characters&r,
+like a latin &wa&w and a cyrillic &wа&w. They are &rdifferent names&r to the compiler, which makes for very confusing bugs.
+Identifiers are compared after &bNFC normalization&b, so the same letter written with or without a combining accent is the same name.`,
+		"example":    "",
+		"additional": "",
+	},
+	EmptyPackageErrorCode: {
+		"name": "EmptyPackageError",
+		"area": "Packager",
+		"explanation": `This error occurs when a package directory has &rno .tod files&r in it. A package is made of &wall .tod files
+in one directory&w, so there's nothing to compile.`,
+		"example":    "",
+		"additional": "",
+	},
+	MissingPackageClauseErrorCode: {
+		"name": "MissingPackageClauseError",
+		"area": "Packager",
+		"explanation": `This error occurs when a file doesn't start with a &wpackage clause&w. Every file has to say which package
+it belongs to before anything else, like &bpackage main&b.`,
+		"example":    "",
+		"additional": "",
+	},
+	MisplacedPackageClauseErrorCode: {
+		"name":        "MisplacedPackageClauseError",
+		"area":        "Packager",
+		"explanation": `This error occurs when a &wpackage clause&w isn't the first thing in a file, or a file has &rmore than one&r.`,
+		"example":     "",
+		"additional":  "",
+	},
+	PackageNameMismatchErrorCode: {
+		"name": "PackageNameMismatchError",
+		"area": "Packager",
+		"explanation": `This error occurs when the files of one directory &rdon't agree&r on the package name. All .tod files in
+a directory form &wone package&w, so all of their package clauses need the same name.`,
+		"example":    "",
+		"additional": "",
+	},
+	DuplicatePackageImportErrorCode: {
+		"name": "DuplicatePackageImportError",
+		"area": "Binder",
+		"explanation": `This error occurs when a file imports the &rsame package twice&r, or two imports end up with the &rsame name&r.
+Remove one of the imports, or give one of them an &walias&w like &bimport m "math"&b.`,
+		"example":    "",
+		"additional": "",
+	},
+	UnknownPackageErrorCode: {
+		"name": "UnknownPackageError",
+		"area": "Binder",
+		"explanation": `This error occurs when an imported package &rcan't be found&r. Imports are looked up in the &wproject root&w,
+then in its &wvendor&w directory and last in the &wstandard library&w, the first directory with a matching path is used.`,
+		"example":    "",
+		"additional": "",
+	},
+	TooManyStructParametersErrorCode: {
+		"name": "TooManyStructParametersError",
+		"area": "Binder",
+		"explanation": `This error occurs when a struct literal has &rmore values than the struct has fields&r, like
+&bmake Point{1, 2, 3}&b for a struct with two fields. Fields without a value get their &wzero value&w.`,
+		"example":    "",
+		"additional": "",
+	},
+	UnknownFieldErrorCode: {
+		"name": "UnknownFieldError",
+		"area": "Binder",
+		"explanation": `This error occurs when a field is used that the struct &rdoesn't have&r, either through &bp.x&b or
+in a keyed struct literal like &bmake Point{z: 1}&b. Check the spelling against the &wstruct declaration&w.`,
+		"example":    "",
+		"additional": "",
+	},
+	DuplicateFieldErrorCode: {
+		"name":        "DuplicateFieldError",
+		"area":        "Binder",
+		"explanation": `This error occurs when a keyed struct literal gives the &rsame field twice&r, like &bmake Point{x: 1, x: 2}&b.`,
+		"example":     "",
+		"additional":  "",
+	},
+	ImportCycleErrorCode: {
+		"name": "ImportCycleError",
+		"area": "Packager",
+		"explanation": `This error occurs when packages &rimport each other&r, directly or through other packages. A package
+can only be compiled once everything it imports is, so the imports have to form a &wtree&w. The error shows the &wcycle&w,
+move the shared declarations into a package of their own to break it.`,
+		"example":    "",
+		"additional": "",
+	},
+	InvalidMapKeyErrorCode: {
+		"name": "InvalidMapKeyError",
+		"area": "Binder",
+		"explanation": `This error occurs when a map type uses a key type that &rcan't be hashed&r. Keys have to be an &wint&w,
+&wuint&w, &wstring&w, &wbool&w or &wchar&w, or a struct made only of those.`,
+		"example":    "",
+		"additional": "",
+	},
+	UnexpectedNonMapValueErrorCode: {
+		"name":        "UnexpectedNonMapValueError",
+		"area":        "Binder",
+		"explanation": `This error occurs when something that only works on maps, like &bdelete(m, k)&b or &bv, ok := m[k]&b, is &rgiven something else&r.`,
+		"example":     "",
+		"additional":  "",
+	},
+	InvalidRangeErrorCode: {
+		"name":        "InvalidRangeError",
+		"area":        "Binder",
+		"explanation": `This error occurs when a &bfor ... := range x&b loop is given a value that &rcan't be ranged over&r (only slices, arrays, strings, maps and integers can), or when a range over an integer names &rmore than one variable&r.`,
+		"example":     "",
+		"additional":  "",
+	},
+	InvalidReferenceErrorCode: {
+		"name":        "InvalidReferenceError",
+		"area":        "Binder",
+		"explanation": `This error occurs when &b&x&b is used on a value that &rdoesn't have an address&r. Only variables, their fields and values behind pointers (&b*p&b) do, literals and results of calls don't.`,
+		"example":     "",
+		"additional":  "",
+	},
+	TooFewStructParametersErrorCode: {
+		"name": "TooFewStructParametersError",
+		"area": "Binder",
+		"explanation": `This error occurs when a struct literal without keys has &rfewer values than the struct has fields&r, like
+&bmake Point{1}&b for a struct with two fields. Name the fields to leave some of them at their &wzero value&w
+(&bmake Point{x: 1}&b), or leave out all values (&bmake Point{}&b).`,
+		"example":    "",
+		"additional": "",
+	},
 	UnexpectedTokenErrorCode: {
 		"name": "UnexpectedToken",
 		"area": "Parser",
@@ -0,0 +1,329 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// functionBody is what the statements of a body need to know about the function they're in
+type functionBody struct {
+	name       string
+	returnType objects.TypeObject
+	loops      int // how many loops deep we are, break and continue need one
+}
+
+// BindFunctionBodies binds the bodies of the functions and methods among members, once DeclareMembers
+// has declared everything they may use. s is the scope of the file they come from, so imports can be found.
+// The members are returned with their bodies lowered (range loops become for and while loops)
+func (s *Scope) BindFunctionBodies(members []ast.MemberNode) ([]ast.MemberNode, bool) {
+	ok := true
+
+	bound := make([]ast.MemberNode, 0, len(members))
+	for _, member := range members {
+		if function, isFunction := member.(ast.FunctionDeclarationMember); isFunction {
+			body, bodyOk := s.BindFunctionBody(function)
+			function.Body = body
+			member = function
+			ok = bodyOk && ok
+		}
+		bound = append(bound, member)
+	}
+
+	return bound, ok
+}
+
+// BindFunctionBody binds the body of a declared function or method in a scope holding
+// its parameters (and receiver) and returns the lowered body
+func (s *Scope) BindFunctionBody(member ast.FunctionDeclarationMember) (ast.BlockStatementNode, bool) {
+	params, returnType, declared := s.signatureOf(member)
+	if !declared {
+		// declaring it failed, that has been reported already
+		return member.Body, false
+	}
+
+	identifiers := make([]token.Token, 0, len(params))
+	if member.Receiver != nil {
+		identifiers = append(identifiers, member.Receiver.Identifier)
+	}
+	for _, param := range member.Parameters {
+		identifiers = append(identifiers, param.Identifier)
+	}
+
+	scope := CreateScope(s)
+	ok := true
+
+	for i, param := range params {
+		// two parameters with the same name have been reported with the signature
+		if scope.TryDeclareVariable(param, identifiers[i]) || scope.Objects[param.Name] != nil {
+			continue
+		}
+
+		print2.Error(
+			"SEMANTIC",
+			print2.DuplicateVariableDeclarationError,
+			identifiers[i].Span(),
+			"something called \"%s\" already exists, a parameter can't have that name!",
+			param.Name,
+		)
+		ok = false
+	}
+
+	body := &functionBody{name: member.Identifier.Name(), returnType: returnType}
+	block, bodyOk := scope.bindStatements(member.Body, body)
+
+	return block, bodyOk && ok
+}
+
+// signatureOf finds the function or method DeclareMembers made of a declaration,
+// it returns its parameters (the receiver first) and its return type
+func (s *Scope) signatureOf(member ast.FunctionDeclarationMember) ([]objects.ParameterObject, objects.TypeObject, bool) {
+	name := member.Identifier.Name()
+
+	if member.Receiver == nil {
+		function, ok := s.TryLookupObject(name).(objects.FunctionObject)
+		if !ok || function.Declaration.Identifier.Pos != member.Identifier.Pos {
+			return nil, objects.TypeObject{}, false
+		}
+		return function.Parameters, function.TypeObject, true
+	}
+
+	origin := member.Receiver.TypeClause.TypeIdentifier.Name()
+	for scope := s; scope != nil; scope = scope.Parent {
+		method, ok := scope.Methods[origin][name]
+		if ok && method.Declaration.Identifier.Pos == member.Identifier.Pos {
+			return append([]objects.ParameterObject{method.Receiver}, method.Parameters...), method.Type, true
+		}
+	}
+	return nil, objects.TypeObject{}, false
+}
+
+// bindStatements binds the statements of a block in a scope of their own
+func (s *Scope) bindStatements(node ast.BlockStatementNode, body *functionBody) (ast.BlockStatementNode, bool) {
+	scope := CreateScope(s)
+	ok := true
+
+	statements := make([]ast.Statement, 0, len(node.Statements))
+	for _, statement := range node.Statements {
+		statement, statementOk := scope.bindStatement(statement, body)
+		statements = append(statements, statement)
+		ok = statementOk && ok
+	}
+
+	node.Statements = statements
+	return node, ok
+}
+
+// bindStatement binds a statement of a function body and returns it lowered
+func (s *Scope) bindStatement(statement ast.Statement, body *functionBody) (ast.Statement, bool) {
+	switch statement := statement.(type) {
+	case ast.BlockStatementNode:
+		return s.bindStatements(statement, body)
+
+	case ast.ExpressionStatementNode:
+		_, ok := s.typeOf(statement.Expression)
+		return statement, ok
+
+	case ast.VariableDeclarationStatementNode:
+		return statement, s.bindLocalDeclaration(statement)
+
+	case ast.CommaOkDeclarationStatementNode:
+		_, _, ok := s.BindCommaOkDeclaration(statement)
+		return statement, ok
+
+	case ast.IfStatementNode:
+		ok := s.bindCondition(statement.Condition)
+
+		then, thenOk := s.bindNested(statement.ThenStatement, body)
+		statement.ThenStatement = then
+		ok = thenOk && ok
+
+		if statement.ElseClause.ClauseIsSet {
+			otherwise, elseOk := s.bindNested(statement.ElseClause.ElseStatement, body)
+			statement.ElseClause.ElseStatement = otherwise
+			ok = elseOk && ok
+		}
+		return statement, ok
+
+	case ast.ReturnStatementNode:
+		return statement, s.bindReturn(statement, body)
+
+	case ast.ForStatementNode:
+		// the loop variable only lives as long as the loop
+		scope := CreateScope(s)
+		ok := scope.bindLocalDeclaration(statement.Initializer)
+		ok = scope.bindCondition(statement.Condition) && ok
+
+		body.loops++
+		updation, updationOk := scope.bindStatement(statement.Updation, body)
+		loop, loopOk := scope.bindNested(statement.StatementNode, body)
+		body.loops--
+
+		statement.Updation = updation
+		statement.StatementNode = loop
+		return statement, updationOk && loopOk && ok
+
+	case ast.WhileStatementNode:
+		ok := s.bindCondition(statement.Condition)
+
+		body.loops++
+		loop, loopOk := s.bindNested(statement.StatementNode, body)
+		body.loops--
+
+		statement.StatementNode = loop
+		return statement, loopOk && ok
+
+	case ast.ForRangeStatementNode:
+		lowered, ok := s.LowerForRange(statement)
+		if !ok {
+			return statement, false
+		}
+		return s.bindStatement(lowered, body)
+
+	case ast.BreakStatementNode:
+		if body.loops == 0 {
+			print2.Error(
+				"SEMANTIC",
+				print2.OutsideBreakError,
+				statement.Span(),
+				"break can only be used inside of a loop!",
+			)
+			return statement, false
+		}
+		return statement, true
+
+	case ast.ContinueStatementNode:
+		if body.loops == 0 {
+			print2.Error(
+				"SEMANTIC",
+				print2.OutsideContinueError,
+				statement.Span(),
+				"continue can only be used inside of a loop!",
+			)
+			return statement, false
+		}
+		return statement, true
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.UnknownStatementError,
+		statement.Span(),
+		"this statement can't be used inside of a function!",
+	)
+	return statement, false
+}
+
+// bindNested binds the statement of an if, else or loop, it gets a scope of its own
+// even if it isn't a block (if (x) var int y; 1 doesn't declare y after the if)
+func (s *Scope) bindNested(statement ast.Statement, body *functionBody) (ast.Statement, bool) {
+	scope := CreateScope(s)
+	return scope.bindStatement(statement, body)
+}
+
+// bindLocalDeclaration declares a local variable, its type is written out or comes from its initializer
+func (s *Scope) bindLocalDeclaration(node ast.VariableDeclarationStatementNode) bool {
+	name := node.Identifier.Name()
+
+	typ, ok := s.LookupType(node.TypeClause)
+
+	switch {
+	case node.Initializer != nil && !node.TypeClause.ClauseIsSet:
+		typ, ok = s.typeOf(node.Initializer)
+	case node.Initializer != nil:
+		ok = ok && s.bindAssignedValue(typ, node.Initializer)
+	case !node.TypeClause.ClauseIsSet:
+		print2.Error(
+			"SEMANTIC",
+			print2.IllegalVariableDeclarationError,
+			node.Identifier.Span(),
+			"variable \"%s\" needs a type or a value to get its type from!",
+			name,
+		)
+		ok = false
+	}
+
+	if !s.TryDeclareVariable(objects.CreateLocalVariableObject(name, false, typ), node.Identifier) {
+		print2.Error(
+			"SEMANTIC",
+			print2.DuplicateVariableDeclarationError,
+			node.Identifier.Span(),
+			"a variable called \"%s\" already exists!",
+			name,
+		)
+		return false
+	}
+	return ok
+}
+
+// bindCondition checks the condition of an if, a for or a while, it has to be a bool
+func (s *Scope) bindCondition(condition ast.Expression) bool {
+	typ, ok := s.typeOf(condition)
+	if !ok {
+		return false
+	}
+
+	if typ.FingerPrint() != objects.BoolType.FingerPrint() {
+		print2.Error(
+			"SEMANTIC",
+			print2.ConversionError,
+			condition.Span(),
+			"a condition has to be a bool, not a \"%s\"!",
+			typ.TypeName(),
+		)
+		return false
+	}
+	return true
+}
+
+// bindReturn checks that a return statement gives back what its function returns
+func (s *Scope) bindReturn(node ast.ReturnStatementNode, body *functionBody) bool {
+	void := body.returnType.FingerPrint() == objects.VoidType.FingerPrint()
+
+	if node.Expression == nil {
+		if !void {
+			print2.Error(
+				"SEMANTIC",
+				print2.VoidReturnError,
+				node.Keyword.Span(),
+				"function \"%s\" has to return a \"%s\"!",
+				body.name,
+				body.returnType.TypeName(),
+			)
+			return false
+		}
+		return true
+	}
+
+	typ, ok := s.typeOf(node.Expression)
+	if !ok {
+		return false
+	}
+
+	if void {
+		print2.Error(
+			"SEMANTIC",
+			print2.VoidReturnError,
+			node.Span(),
+			"function \"%s\" doesn't return anything, it can't return a \"%s\"!",
+			body.name,
+			typ.TypeName(),
+		)
+		return false
+	}
+
+	if typ.FingerPrint() != body.returnType.FingerPrint() {
+		print2.Error(
+			"SEMANTIC",
+			print2.ConversionError,
+			node.Expression.Span(),
+			"function \"%s\" returns a \"%s\", not a \"%s\"!",
+			body.name,
+			body.returnType.TypeName(),
+			typ.TypeName(),
+		)
+		return false
+	}
+	return true
+}
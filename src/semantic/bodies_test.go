@@ -0,0 +1,81 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// bind declares code and binds its function bodies, the code has to parse
+func bind(t *testing.T, code string) ([]ast.MemberNode, bool) {
+	t.Helper()
+	scope, members := declare(t, code)
+	return scope.BindFunctionBodies(members)
+}
+
+// body is the bound body of the function called name
+func body(t *testing.T, members []ast.MemberNode, name string) ast.BlockStatementNode {
+	t.Helper()
+	for _, member := range members {
+		if function, ok := member.(ast.FunctionDeclarationMember); ok && function.Identifier.Name() == name {
+			return function.Body
+		}
+	}
+	t.Fatalf("there is no function called %s", name)
+	return ast.BlockStatementNode{}
+}
+
+// TestFunctionBodies checks parameters, locals and their scopes, returns and conditions
+func TestFunctionBodies(t *testing.T) {
+	cases := []struct {
+		code   string
+		errors []print2.ErrorType
+	}{
+		{"fn f(a int) int {\nvar b; a\nreturn b\n}", nil},
+		{"fn f(a int) int {\nreturn \"a\"\n}", []print2.ErrorType{print2.ConversionError}},
+		{"fn f() {\nreturn 1\n}", []print2.ErrorType{print2.VoidReturnError}},
+		{"fn f() int {\nreturn\n}", []print2.ErrorType{print2.VoidReturnError}},
+		{"fn f() {\nvar string s; 1\n}", []print2.ErrorType{print2.ConversionError}},
+		{"fn f() {\nvar int a; 1\nvar int a; 2\n}", []print2.ErrorType{print2.DuplicateVariableDeclarationError}},
+		{"var int g\nfn f(g int) {}", []print2.ErrorType{print2.DuplicateVariableDeclarationError}},
+
+		// a block's locals are gone after it, so they can be declared again
+		{"fn f() {\n{\nvar int a; 1\n}\nvar int a; 2\n}", nil},
+		{"fn f() int {\n{\nvar int a; 1\n}\nreturn a\n}", []print2.ErrorType{print2.UndefinedVariableReferenceError}},
+		{"fn f(a int) {\nif (a > 1) var int b; a\nvar int b; 2\n}", nil},
+
+		{"fn f(a int) {\nif (a) {\n}\n}", []print2.ErrorType{print2.ConversionError}},
+		{"fn f(a int) {\nwhile (a < 3) {\na++\n}\n}", nil},
+		{"fn f() {\nfor (var int i; 0; i < 3; i++) {\n}\nvar int i; 0\n}", nil},
+		{"fn f() {\nbreak\n}", []print2.ErrorType{print2.OutsideBreakError}},
+		{"fn f(a bool) {\nif (a) {\ncontinue\n}\n}", []print2.ErrorType{print2.OutsideContinueError}},
+
+		// operands and arguments are bound too
+		{"fn f(a int) bool {\nreturn a == \"a\"\n}", []print2.ErrorType{print2.BinaryOperatorTypeError}},
+		{"fn f(a int) bool {\nreturn a && true\n}", []print2.ErrorType{print2.BinaryOperatorTypeError}},
+		{"fn f(a int) int {\nreturn b + a\n}", []print2.ErrorType{print2.UndefinedVariableReferenceError}},
+		{"fn g(a int) int {}\nfn f() int {\nreturn g(1)\n}", nil},
+		{"fn g(a int) int {}\nfn f() int {\nreturn g(\"1\")\n}", []print2.ErrorType{print2.ConversionError}},
+		{"fn g(a int) int {}\nfn f() int {\nreturn g()\n}", []print2.ErrorType{print2.BadNumberOfParametersError}},
+
+		// the body of a method sees its receiver, comma ok and slices are bound
+		{counter + "fn (c Counter) Twice() int {\nreturn c.n * 2\n}", nil},
+		{"fn f(m map[string]int) int {\nv, ok := m[\"a\"]\nreturn v\n}", nil},
+		{"fn f(s []int) []int {\nreturn s[1:]\n}", nil},
+		{"fn f(s []int) int {\nreturn s[\"a\":]\n}", []print2.ErrorType{print2.UnexpectedNonIntegerValueError}},
+	}
+
+	for _, c := range cases {
+		_, ok := bind(t, c.code)
+
+		expectErrors(t, c.code, c.errors...)
+		if ok != (len(c.errors) == 0) {
+			t.Errorf("%q: bound is %t", c.code, ok)
+		}
+	}
+
+	// the signature reports two parameters with the same name, the body doesn't again
+	bind(t, "fn f(a int, a int) {}")
+	expectErrors(t, "duplicate parameters", print2.DuplicateParameterError)
+}
@@ -0,0 +1,92 @@
+package semantic
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// characters that look (almost) the same as a latin letter or digit,
+// a small part of the confusables from UTS #39 (Unicode Security Mechanisms)
+var confusables = map[rune]rune{
+	// cyrillic
+	'а': 'a', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j', 'ӏ': 'l',
+	'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'ԝ': 'w', 'х': 'x', 'у': 'y',
+	'А': 'A', 'В': 'B', 'С': 'C', 'Е': 'E', 'Н': 'H', 'І': 'l', 'Ј': 'J', 'К': 'K',
+	'М': 'M', 'О': 'O', 'Р': 'P', 'Ѕ': 'S', 'Т': 'T', 'Х': 'X', 'Ү': 'Y',
+
+	// greek
+	'α': 'a', 'ι': 'i', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'υ': 'u',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'l', 'Κ': 'K', 'Μ': 'M',
+	'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+
+	// latin lookalikes
+	'0': 'O', '1': 'l', 'I': 'l', 'ı': 'i',
+}
+
+// skeleton maps a name to what it looks like, two names with the same skeleton are confusable.
+// Compatibility characters (like fullwidth letters) are decomposed first, then every lookalike
+// is replaced by the character it looks like
+func skeleton(name string) string {
+	var result strings.Builder
+	for _, c := range norm.NFKD.String(name) {
+		if lookalike, ok := confusables[c]; ok {
+			c = lookalike
+		}
+		result.WriteRune(c)
+	}
+	return norm.NFC.String(result.String())
+}
+
+// confusableWith returns the names visible from this scope that look like name but aren't name.
+// Every scope keeps its names by skeleton, so only the names with the same skeleton are looked at
+func (s *Scope) confusableWith(name string, key string) []string {
+	found := make([]string, 0)
+
+	for scope := s; scope != nil; scope = scope.Parent {
+		for _, other := range scope.skeletons[key] {
+			if other != name {
+				found = append(found, other)
+			}
+		}
+	}
+
+	sort.Strings(found)
+	return found
+}
+
+// warnConfusable warns if a newly declared name can be mistaken for another one in scope,
+// like a latin "a" and a cyrillic "а". key is the skeleton of name
+func (s *Scope) warnConfusable(name string, key string, span print2.TextSpan) {
+	for _, other := range s.confusableWith(name, key) {
+		print2.Warning(
+			"SEMANTIC",
+			print2.ConfusableIdentifierWarning,
+			span,
+			"identifier \"%s\" looks like \"%s\" (%+q and %+q)! Use one of the two names",
+			name,
+			other,
+			name,
+			other,
+		)
+	}
+}
+
+// declarationSpan finds where an object has been declared, if we know that
+func declarationSpan(sym objects.Objects) print2.TextSpan {
+	switch sym := sym.(type) {
+	case objects.FunctionObject:
+		if sym.External {
+			return sym.ExternalDeclaration.Identifier.Span()
+		}
+		return sym.Declaration.Identifier.Span()
+	case objects.StructObject:
+		return sym.Declaration.Identifier.Span()
+	case objects.PackageObject:
+		return sym.ErrorLocation
+	}
+	return print2.TextSpan{}
+}
@@ -0,0 +1,52 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// TestConfusableVariables checks that declaring a lookalike of a name in scope warns
+// at the new declaration, also for variables which don't know where they've been declared
+func TestConfusableVariables(t *testing.T) {
+	print2.WarningList = print2.WarningList[:0]
+
+	// the second one has a cyrillic "а"
+	declare(t, "var int a\nvar int b\nvar int а\n")
+
+	if len(print2.WarningList) != 1 {
+		t.Fatalf("expected one warning, got %v", print2.WarningList)
+	}
+
+	warning := print2.WarningList[0]
+	if warning.ErrType != print2.ConfusableIdentifierWarning {
+		t.Errorf("expected a %s, got a %s", print2.ConfusableIdentifierWarning, warning.ErrType)
+	}
+	if warning.Span.StartLine != 3 || warning.Span.StartColumn != 9 {
+		t.Errorf("warning is at %d:%d, expected 3:9", warning.Span.StartLine, warning.Span.StartColumn)
+	}
+}
+
+// TestConfusableScopes checks that names of enclosing scopes are looked at too,
+// and that a name isn't confusable with itself
+func TestConfusableScopes(t *testing.T) {
+	print2.OutputErrorMessages = false
+	print2.WarningList = print2.WarningList[:0]
+
+	outer := CreateScope(nil)
+	outer.TryDeclareObject(objects.CreateGlobalVariableObject("IO", false, objects.IntType))
+	outer.TryDeclareObject(objects.CreateGlobalVariableObject("count", false, objects.IntType))
+
+	inner := CreateScope(&outer)
+
+	if found := inner.confusableWith("l0", skeleton("l0")); len(found) != 1 || found[0] != "IO" {
+		t.Errorf("\"l0\" should look like \"IO\", found %v", found)
+	}
+	if found := inner.confusableWith("count", skeleton("count")); len(found) != 0 {
+		t.Errorf("\"count\" shouldn't look like anything, found %v", found)
+	}
+	if len(print2.WarningList) != 0 {
+		t.Errorf("expected no warnings, got %v", print2.WarningList)
+	}
+}
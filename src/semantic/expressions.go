@@ -0,0 +1,344 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// typeOf works out the type of an expression, for the places that need to know
+// what they're working on (field access, method calls, indexing, built-ins)
+func (s *Scope) typeOf(expression ast.Expression) (objects.TypeObject, bool) {
+	switch expression := expression.(type) {
+	case ast.LiteralExpressionNode:
+		return literalType(expression.LiteralToken)
+
+	case ast.InterpolatedStringExpressionNode:
+		return objects.StringType, s.BindInterpolatedString(expression)
+
+	case ast.NameExpressNode:
+		name := expression.Identifier.Name()
+		variable, ok := s.TryLookupObject(name).(objects.VariableObjects)
+		if !ok {
+			print2.Error(
+				"SEMANTIC",
+				print2.UndefinedVariableReferenceError,
+				expression.Span(),
+				"couldn't find a variable called \"%s\"!",
+				name,
+			)
+			return objects.TypeObject{}, false
+		}
+		return variable.VarType(), true
+
+	case ast.ParanthesisedExpressionNode:
+		return s.typeOf(expression.ExpressionNode)
+
+	case ast.ClassFieldAccessExpressionNode:
+		field, ok := s.BindFieldAccess(expression)
+		if !ok {
+			return objects.TypeObject{}, false
+		}
+		return field.VarType(), true
+
+	case ast.ClassFieldAssignmentExpressionNode:
+		field, ok := s.BindFieldAssignment(expression)
+		if !ok {
+			return objects.TypeObject{}, false
+		}
+		return field.VarType(), s.bindAssignedValue(field.VarType(), expression.Value)
+
+	case ast.AssignmentExpressionNode:
+		typ, ok := s.typeOf(ast.CreateNameExpressionNode(expression.Identifier))
+		if !ok {
+			return objects.TypeObject{}, false
+		}
+		return typ, s.bindAssignedValue(typ, expression.ExpressionNode)
+
+	case ast.VariableEditorExpressionNode:
+		// x++, x--, x += value, ...
+		typ, ok := s.typeOf(ast.CreateNameExpressionNode(expression.Identifier))
+		if !ok || expression.IsSingleStep {
+			return typ, ok
+		}
+		return typ, s.bindAssignedValue(typ, expression.ExpressionNode)
+
+	case ast.ArrayAssignmentExpressionNode:
+		return s.BindIndexAssignment(expression)
+
+	case ast.TypeCallExpressionNode:
+		// math.Sqrt(x) calls a function of an imported package
+		if pkg, isPackage := s.packageOf(expression.Base); isPackage {
+			function, ok := s.BindPackageCall(pkg, expression)
+			return function.TypeObject, ok
+		}
+
+		method, ok := s.BindMethodCall(expression)
+		if !ok {
+			return objects.TypeObject{}, false
+		}
+		return method.Type, true
+
+	case ast.CallExpressionNode:
+		return s.typeOfCall(expression)
+
+	case ast.MakeStructExpressionNode:
+		sym, _, ok := s.BindStructLiteral(expression)
+		if !ok {
+			return objects.TypeObject{}, false
+		}
+		return sym.Type, true
+
+	case ast.MakeMapExpressionNode:
+		return s.BindMapLiteral(expression)
+
+	case ast.ArrayAccessExpressionNode:
+		return s.BindIndexExpression(expression)
+
+	case ast.SliceExpressionNode:
+		return s.BindSliceExpression(expression)
+
+	case ast.UnaryExpressionNode:
+		typ, ok := s.typeOf(expression.Operand)
+		if expression.Operator.Type == token.BANG {
+			return objects.BoolType, ok
+		}
+		return typ, ok
+
+	case ast.ReferenceExpressionNode:
+		return s.typeOfReference(expression)
+
+	case ast.DereferenceExpressionNode:
+		return s.typeOfDereference(expression)
+
+	case ast.LogicalExpressionNode:
+		typ, ok := s.bindOperands(expression.Operator, expression.Left, expression.Right)
+		if ok && typ.FingerPrint() != objects.BoolType.FingerPrint() {
+			print2.Error(
+				"SEMANTIC",
+				print2.BinaryOperatorTypeError,
+				expression.Operator.Span(),
+				"operator \"%s\" needs two bools, not \"%s\"!",
+				expression.Operator.Literal,
+				typ.TypeName(),
+			)
+			ok = false
+		}
+		return objects.BoolType, ok
+
+	case ast.BinaryExpressionNode:
+		typ, ok := s.bindOperands(expression.Operator, expression.Left, expression.Right)
+
+		switch expression.Operator.Type {
+		case token.EQ, token.NOT_EQ, token.LT, token.LEQ, token.GT, token.GEQ:
+			return objects.BoolType, ok
+		case token.SPACESHIP:
+			// a <=> b is -1, 0 or 1 whatever a and b are
+			return objects.IntType, ok
+		}
+		return typ, ok
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.UnknownDataTypeError,
+		expression.Span(),
+		"couldn't work out the type of this expression!",
+	)
+	return objects.TypeObject{}, false
+}
+
+// bindOperands checks that both sides of a binary operator have the same type and returns it
+func (s *Scope) bindOperands(operator token.Token, left ast.Expression, right ast.Expression) (objects.TypeObject, bool) {
+	leftType, leftOk := s.typeOf(left)
+	rightType, rightOk := s.typeOf(right)
+	if !leftOk || !rightOk {
+		return leftType, false
+	}
+
+	if leftType.FingerPrint() != rightType.FingerPrint() {
+		print2.Error(
+			"SEMANTIC",
+			print2.BinaryOperatorTypeError,
+			operator.Span(),
+			"operator \"%s\" can't be used on a \"%s\" and a \"%s\"!",
+			operator.Literal,
+			leftType.TypeName(),
+			rightType.TypeName(),
+		)
+		return leftType, false
+	}
+	return leftType, true
+}
+
+// bindAssignedValue checks that a value can be stored in something of type typ
+func (s *Scope) bindAssignedValue(typ objects.TypeObject, value ast.Expression) bool {
+	valueType, ok := s.typeOf(value)
+	if !ok {
+		return false
+	}
+
+	if valueType.FingerPrint() != typ.FingerPrint() {
+		print2.Error(
+			"SEMANTIC",
+			print2.ConversionError,
+			value.Span(),
+			"can't assign a \"%s\" to something of type \"%s\"!",
+			valueType.TypeName(),
+			typ.TypeName(),
+		)
+		return false
+	}
+	return true
+}
+
+// typeOfReference is the pointer type of &x, x has to live somewhere to point at
+func (s *Scope) typeOfReference(node ast.ReferenceExpressionNode) (objects.TypeObject, bool) {
+	typ, ok := s.typeOf(node.ExpressionNode)
+	if !ok {
+		return objects.TypeObject{}, false
+	}
+
+	if !isAddressable(node.ExpressionNode) {
+		print2.Error(
+			"SEMANTIC",
+			print2.InvalidReferenceError,
+			node.Span(),
+			"can't take the address of this value, only variables and their fields have one!",
+		)
+		return objects.TypeObject{}, false
+	}
+
+	return objects.CreatePointerType(typ), true
+}
+
+// typeOfDereference is the type *p points at
+func (s *Scope) typeOfDereference(node ast.DereferenceExpressionNode) (objects.TypeObject, bool) {
+	typ, ok := s.typeOf(node.ExpressionNode)
+	if !ok {
+		return objects.TypeObject{}, false
+	}
+
+	if !objects.IsPointer(typ) {
+		print2.Error(
+			"SEMANTIC",
+			print2.UnexpectedNonPointerValueError,
+			node.Span(),
+			"can't dereference a value of type \"%s\", it isn't a pointer!",
+			typ.Name,
+		)
+		return objects.TypeObject{}, false
+	}
+
+	return typ.SubTypes[0], true
+}
+
+// typeOfCall is the return type of a function, the result of a built-in or the target type of a cast
+func (s *Scope) typeOfCall(node ast.CallExpressionNode) (objects.TypeObject, bool) {
+	if node.CastingType.ClauseIsSet {
+		return s.LookupType(node.CastingType)
+	}
+
+	name := node.Identifier.Name()
+	if IsBuiltInFunction(name) {
+		return s.BindBuiltInCall(node)
+	}
+
+	if function, ok := s.TryLookupObject(name).(objects.FunctionObject); ok {
+		if len(node.Arguments) != len(function.Parameters) {
+			print2.Error(
+				"SEMANTIC",
+				print2.BadNumberOfParametersError,
+				node.Span(),
+				"function \"%s\" expects %d arguments, got %d!",
+				name,
+				len(function.Parameters),
+				len(node.Arguments),
+			)
+			return function.TypeObject, false
+		}
+		return function.TypeObject, s.bindArguments(node.Arguments, function.Parameters, name)
+	}
+
+	if name == objects.StringType.Name {
+		return objects.StringType, s.bindStringCast(node)
+	}
+
+	// int(x), float(x), ...
+	if typ, ok := objects.LookupBuiltInType(name); ok {
+		for _, argument := range node.Arguments {
+			_, argumentOk := s.typeOf(argument)
+			ok = argumentOk && ok
+		}
+		return typ, ok
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.UndefinedFunctionCallError,
+		node.Identifier.Span(),
+		"couldn't find a function called \"%s\"!",
+		name,
+	)
+	return objects.TypeObject{}, false
+}
+
+// bindArguments checks that every argument of a call has the type of its parameter,
+// there have to be as many arguments as parameters
+func (s *Scope) bindArguments(arguments []ast.Expression, params []objects.ParameterObject, function string) bool {
+	ok := true
+
+	for i, argument := range arguments {
+		typ, argumentOk := s.typeOf(argument)
+		if !argumentOk {
+			ok = false
+			continue
+		}
+
+		if typ.FingerPrint() != params[i].Type.FingerPrint() {
+			print2.Error(
+				"SEMANTIC",
+				print2.ConversionError,
+				argument.Span(),
+				"parameter \"%s\" of \"%s\" is a \"%s\", got a \"%s\"!",
+				params[i].Name,
+				function,
+				params[i].Type.TypeName(),
+				typ.TypeName(),
+			)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// literalType is the type of a literal, decided by its token
+func literalType(literal token.Token) (objects.TypeObject, bool) {
+	switch literal.Type {
+	case token.INT:
+		return objects.IntType, true
+	case token.UINT:
+		return objects.UIntType, true
+	case token.FLOAT32:
+		return objects.FloatType, true
+	case token.FLOAT64:
+		return objects.DoubleType, true
+	case token.CHAR:
+		return objects.CharType, true
+	case token.STRING:
+		return objects.StringType, true
+	case token.TRUE, token.FALSE:
+		return objects.BoolType, true
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.UnknownDataTypeError,
+		literal.Span(),
+		"couldn't work out the type of literal \"%s\"!",
+		literal.Literal,
+	)
+	return objects.TypeObject{}, false
+}
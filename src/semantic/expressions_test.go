@@ -0,0 +1,83 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// TestTypeOfOperators checks the types of expressions whose type isn't the type of their operands
+func TestTypeOfOperators(t *testing.T) {
+	declarations := "var int x\nvar pointer[int] p\nvar pointer[pointer[int]] pp\n"
+
+	cases := []struct {
+		expression string
+		expected   objects.TypeObject
+	}{
+		{"&x", objects.CreatePointerType(objects.IntType)},
+		{"*p", objects.IntType},
+		{"*&x", objects.IntType},
+		{"&*p", objects.CreatePointerType(objects.IntType)},
+		{"**pp", objects.IntType},
+		{"&p", objects.CreatePointerType(objects.CreatePointerType(objects.IntType))},
+		{"x <=> 2", objects.IntType},
+		{"\"a\" <=> \"b\"", objects.IntType},
+		{"x <= 2", objects.BoolType},
+		{"-x", objects.IntType},
+	}
+
+	for _, c := range cases {
+		scope, members := declare(t, declarations+c.expression)
+		typ, ok := scope.typeOf(lastExpression(t, members))
+		if !ok || typ.FingerPrint() != c.expected.FingerPrint() {
+			t.Errorf("%s: type is %s (%t), expected %s", c.expression, typ.FingerPrint(), ok, c.expected.FingerPrint())
+		}
+	}
+}
+
+// TestTypeOfPointerErrors checks that & and * are only used where they make sense
+func TestTypeOfPointerErrors(t *testing.T) {
+	cases := []struct {
+		expression string
+		expected   print2.ErrorType
+	}{
+		{"&5", print2.InvalidReferenceError},
+		{"&(x + 1)", print2.InvalidReferenceError},
+		{"*x", print2.UnexpectedNonPointerValueError},
+	}
+
+	for _, c := range cases {
+		scope, members := declare(t, "var int x\n"+c.expression)
+		if _, ok := scope.typeOf(lastExpression(t, members)); ok {
+			t.Errorf("%s: expected an error", c.expression)
+			continue
+		}
+
+		if types := errorTypes(); len(types) != 1 || types[0] != c.expected {
+			t.Errorf("%s: reported %v, expected %s", c.expression, types, c.expected)
+		}
+	}
+}
+
+// TestTypeOfQualifiedNames checks that pkg.Name is looked up in the package, not as a field or method
+func TestTypeOfQualifiedNames(t *testing.T) {
+	scope, members := declare(t, "math.Sqrt(2.0)\nmath.Pi\n")
+
+	sqrt := objects.CreateFunctionObject("Sqrt", []objects.ParameterObject{
+		objects.CreateParameterObject("x", 0, objects.DoubleType, 0),
+	}, objects.DoubleType, ast.FunctionDeclarationMember{}, true)
+	pi := objects.CreateGlobalVariableObject("Pi", true, objects.DoubleType)
+
+	exports := map[string]objects.Objects{"Sqrt": sqrt, "Pi": pi}
+	scope.TryDeclareObject(objects.CreateSourcePackageObject("math", "math", exports, print2.TextSpan{}))
+
+	for _, member := range members {
+		expression := member.(ast.GlobalStatementMember).Statement.(ast.ExpressionStatementNode).Expression
+		typ, ok := scope.typeOf(expression)
+		if !ok || typ.Name != objects.DoubleType.Name {
+			t.Errorf("type is %s (%t), expected a double: %v", typ.Name, ok, errorTypes())
+		}
+	}
+}
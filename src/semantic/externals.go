@@ -0,0 +1,65 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// BindExternalFunction turns an external function declaration into a function object and
+// declares it in the scope. External functions are implemented in C (libc or our own helpers),
+// we only know their signature
+func (s *Scope) BindExternalFunction(member ast.ExternalFunctionDeclarationMember) (objects.FunctionObject, bool) {
+	name := member.Identifier.Name()
+	params, ok := s.bindParameters(member.Parameters, name)
+
+	returnType, found := s.LookupType(member.TypeClause)
+	ok = ok && found
+
+	function := objects.CreateExternalFunctionObject(name, params, returnType, member)
+
+	if !s.TryDeclareObject(function) {
+		print2.Error(
+			"SEMANTIC",
+			print2.DuplicateFunctionError,
+			member.Identifier.Span(),
+			"a function called \"%s\" already exists!",
+			name,
+		)
+		return function, false
+	}
+
+	return function, ok
+}
+
+// bindParameters resolves the types of a function's parameters, names have to be unique
+func (s *Scope) bindParameters(parameters []ast.ParameterNode, function string) ([]objects.ParameterObject, bool) {
+	ok := true
+
+	params := make([]objects.ParameterObject, 0, len(parameters))
+	seen := make(map[string]bool)
+
+	for i, param := range parameters {
+		paramName := param.Identifier.Name()
+
+		if seen[paramName] {
+			print2.Error(
+				"SEMANTIC",
+				print2.DuplicateParameterError,
+				param.Identifier.Span(),
+				"a parameter called \"%s\" already exists in function \"%s\"!",
+				paramName,
+				function,
+			)
+			ok = false
+		}
+		seen[paramName] = true
+
+		typ, found := s.LookupType(param.TypeClause)
+		ok = ok && found
+
+		params = append(params, objects.CreateParameterObject(paramName, i, typ, 0))
+	}
+
+	return params, ok
+}
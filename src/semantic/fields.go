@@ -0,0 +1,80 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// BindFieldAccess finds the struct field read by p.x (or a.b.c),
+// or the variable of an imported package read by math.Pi
+func (s *Scope) BindFieldAccess(node ast.ClassFieldAccessExpressionNode) (objects.VariableObjects, bool) {
+	if pkg, ok := s.packageOf(node.Base); ok {
+		return s.BindPackageVariable(pkg, node.FieldIdentifier)
+	}
+
+	typ, ok := s.typeOf(node.Base)
+	if !ok {
+		return nil, false
+	}
+
+	return s.LookupField(typ, node.FieldIdentifier)
+}
+
+// BindFieldAssignment finds the struct field written by p.x = value (or the package variable)
+func (s *Scope) BindFieldAssignment(node ast.ClassFieldAssignmentExpressionNode) (objects.VariableObjects, bool) {
+	if pkg, ok := s.packageOf(node.Base); ok {
+		return s.BindPackageVariable(pkg, node.FieldIdentifier)
+	}
+
+	typ, ok := s.typeOf(node.Base)
+	if !ok {
+		return nil, false
+	}
+
+	return s.LookupField(typ, node.FieldIdentifier)
+}
+
+// LookupField finds a field in a struct type, reports UnknownFieldError if there's none
+func (s *Scope) LookupField(typ objects.TypeObject, field token.Token) (objects.VariableObjects, bool) {
+	name := field.Name()
+
+	if sym, isStruct := s.structOf(typ); isStruct {
+		for _, candidate := range sym.Fields {
+			if candidate.ObjectName() == name {
+				return candidate, true
+			}
+		}
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.UnknownFieldError,
+		field.Span(),
+		"type \"%s\" doesn't have a field called \"%s\"!",
+		typ.Name,
+		name,
+	)
+	return nil, false
+}
+
+// structOf finds the struct behind a type (or a pointer to it). The type may have been created before the
+// struct's fields were bound, so the struct in scope is preferred over the type's copy
+func (s *Scope) structOf(typ objects.TypeObject) (objects.StructObject, bool) {
+	// fields are reached through pointers too
+	if objects.IsPointer(typ) {
+		typ = typ.SubTypes[0]
+	}
+
+	if !typ.IsUserDefined {
+		return objects.StructObject{}, false
+	}
+
+	if sym, ok := s.TryLookupObject(typ.Name).(objects.StructObject); ok {
+		return sym, true
+	}
+
+	sym, ok := typ.SourceObject.(objects.StructObject)
+	return sym, ok
+}
@@ -0,0 +1,74 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+const shapes = `
+struct Point {
+	x int
+	y int
+}
+struct Line {
+	a Point
+	b pointer[Point]
+	name string
+}
+var Line l
+var pointer[Line] pl
+`
+
+// TestFieldAccess checks p.x and chains of it, through values and pointers
+func TestFieldAccess(t *testing.T) {
+	cases := []struct {
+		expression string
+		expected   objects.TypeObject
+		errors     []print2.ErrorType
+	}{
+		{"l.name", objects.StringType, nil},
+		{"l.a.x", objects.IntType, nil},
+		{"l.b.y", objects.IntType, nil},
+		{"pl.a.y", objects.IntType, nil},
+		{"pl.b.x", objects.IntType, nil},
+		{"(l.a).x", objects.IntType, nil},
+		{"l.a.z", objects.TypeObject{}, []print2.ErrorType{print2.UnknownFieldError}},
+		{"l.name.x", objects.TypeObject{}, []print2.ErrorType{print2.UnknownFieldError}},
+	}
+
+	for _, c := range cases {
+		scope, members := declare(t, shapes+c.expression)
+		typ, _ := scope.typeOf(lastExpression(t, members))
+		if typ.Name != c.expected.Name {
+			t.Errorf("%s: type is %q, expected %q", c.expression, typ.Name, c.expected.Name)
+		}
+		expectErrors(t, c.expression, c.errors...)
+	}
+}
+
+// TestFieldAssignment checks that a.b = v finds the field it writes
+func TestFieldAssignment(t *testing.T) {
+	scope, members := declare(t, shapes+"l.a.x = 1\npl.b.y = 2\nl.a.q = 3\n")
+
+	for i, expected := range []string{"x", "y", ""} {
+		statement := members[len(members)-3+i].(ast.GlobalStatementMember).Statement
+		assignment := statement.(ast.ExpressionStatementNode).Expression.(ast.ClassFieldAssignmentExpressionNode)
+
+		print2.ErrorList = print2.ErrorList[:0]
+		field, ok := scope.BindFieldAssignment(assignment)
+		if expected == "" {
+			if ok {
+				t.Errorf("assignment %d: q isn't a field, but has been found", i)
+			}
+			expectErrors(t, "l.a.q = 3", print2.UnknownFieldError)
+			continue
+		}
+
+		if !ok || field.ObjectName() != expected {
+			t.Errorf("assignment %d: bound to %v, expected field %s", i, field, expected)
+		}
+	}
+}
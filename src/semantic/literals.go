@@ -0,0 +1,109 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// BindStructLiteral checks the values of make Point{1, 2} or make Point{x: 1, y: 2} against the
+// struct's fields. It returns the value of every field in declaration order, fields without a
+// value keep their zero value and are nil in the result. Only keyed literals can leave out fields,
+// a positional one gives every field a value (or none at all, make Point{} is all zero values)
+func (s *Scope) BindStructLiteral(node ast.MakeStructExpressionNode) (objects.StructObject, []ast.Expression, bool) {
+	name := node.Type.Name()
+
+	sym, isStruct := s.TryLookupObject(name).(objects.StructObject)
+	if !isStruct {
+		print2.Error(
+			"SEMANTIC",
+			print2.UnknownStructError,
+			node.Type.Span(),
+			"couldn't find a struct called \"%s\"!",
+			name,
+		)
+		return sym, nil, false
+	}
+
+	values := make([]ast.Expression, len(sym.Fields))
+
+	if !node.IsKeyed() {
+		if len(node.LiteralValues) > len(sym.Fields) {
+			print2.Error(
+				"SEMANTIC",
+				print2.TooManyStructParametersError,
+				node.LiteralValues[len(sym.Fields)].Span(),
+				"struct \"%s\" has %d fields, but got %d values!",
+				name,
+				len(sym.Fields),
+				len(node.LiteralValues),
+			)
+			return sym, values, false
+		}
+
+		if len(node.LiteralValues) != 0 && len(node.LiteralValues) < len(sym.Fields) {
+			print2.Error(
+				"SEMANTIC",
+				print2.TooFewStructParametersError,
+				node.Span(),
+				"struct \"%s\" has %d fields, but got %d values! Name the fields to leave out the others",
+				name,
+				len(sym.Fields),
+				len(node.LiteralValues),
+			)
+			return sym, values, false
+		}
+
+		copy(values, node.LiteralValues)
+		return sym, values, true
+	}
+
+	ok := true
+	given := make(map[string]int) // field name -> index of the value that set it
+
+	for i, key := range node.Keys {
+		field := key.Name()
+
+		if first, exists := given[field]; exists {
+			print2.Error(
+				"SEMANTIC",
+				print2.DuplicateFieldError,
+				key.Span(),
+				"field \"%s\" has already been given a value!",
+				field,
+			)
+			print2.Note(node.Keys[first].Span(), "\"%s\" is set here", field)
+			ok = false
+			continue
+		}
+		given[field] = i
+
+		index := fieldIndex(sym, field)
+		if index < 0 {
+			print2.Error(
+				"SEMANTIC",
+				print2.UnknownFieldError,
+				key.Span(),
+				"struct \"%s\" doesn't have a field called \"%s\"!",
+				name,
+				field,
+			)
+			ok = false
+			continue
+		}
+
+		values[index] = node.LiteralValues[i]
+	}
+
+	return sym, values, ok
+}
+
+// fieldIndex finds the position of a field in a struct, -1 if it doesn't exist
+func fieldIndex(sym objects.StructObject, name string) int {
+	for i, field := range sym.Fields {
+		if field.ObjectName() == name {
+			return i
+		}
+	}
+	return -1
+}
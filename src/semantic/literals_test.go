@@ -0,0 +1,51 @@
+package semantic
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// TestStructLiterals checks which fields get a value and which keep their zero value
+func TestStructLiterals(t *testing.T) {
+	declarations := "struct Point {\n\tx int\n\ty int\n\tz int\n}\n"
+
+	cases := []struct {
+		literal string
+		set     []bool // which fields have a value
+		errors  []print2.ErrorType
+	}{
+		{"make Point{1, 2, 3}", []bool{true, true, true}, nil},
+		{"make Point{}", []bool{false, false, false}, nil},
+		{"make Point{y: 2}", []bool{false, true, false}, nil},
+		{"make Point{z: 3, x: 1}", []bool{true, false, true}, nil},
+		{"make Point{1, 2}", nil, []print2.ErrorType{print2.TooFewStructParametersError}},
+		{"make Point{1, 2, 3, 4}", nil, []print2.ErrorType{print2.TooManyStructParametersError}},
+		{"make Point{x: 1, x: 2}", nil, []print2.ErrorType{print2.DuplicateFieldError}},
+		{"make Point{w: 1}", nil, []print2.ErrorType{print2.UnknownFieldError}},
+		{"make Vector{}", nil, []print2.ErrorType{print2.UnknownStructError}},
+	}
+
+	for _, c := range cases {
+		scope, members := declare(t, declarations+c.literal)
+		_, values, ok := scope.BindStructLiteral(lastExpression(t, members).(ast.MakeStructExpressionNode))
+		expectErrors(t, c.literal, c.errors...)
+
+		if ok != (len(c.errors) == 0) {
+			t.Errorf("%s: bound is %t", c.literal, ok)
+		}
+		if !ok {
+			continue
+		}
+
+		set := make([]bool, 0, len(values))
+		for _, value := range values {
+			set = append(set, value != nil)
+		}
+		if !slices.Equal(set, c.set) {
+			t.Errorf("%s: fields with a value are %v, expected %v", c.literal, set, c.set)
+		}
+	}
+}
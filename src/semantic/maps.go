@@ -0,0 +1,192 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// lookupMapType resolves map[K]V, K has to be hashable (see isHashable)
+func (s *Scope) lookupMapType(clause ast.TypeClauseNode) (objects.TypeObject, bool) {
+	key, ok := s.LookupType(clause.SubClauses[0])
+	value, found := s.LookupType(clause.SubClauses[1])
+	typ := objects.CreateMapType(key, value)
+
+	if !ok || !found {
+		return typ, false
+	}
+
+	if !s.isHashable(key, make(map[string]bool)) {
+		print2.Error(
+			"SEMANTIC",
+			print2.InvalidMapKeyError,
+			clause.SubClauses[0].Span(),
+			"\"%s\" can't be used as a map key! Keys have to be an int, uint, string, bool, char or a struct of those",
+			key.TypeName(),
+		)
+		return typ, false
+	}
+	return typ, true
+}
+
+// isHashable checks if values of a type can be map keys: the built-in value types
+// and structs made of them. seen stops structs that contain themselves
+func (s *Scope) isHashable(typ objects.TypeObject, seen map[string]bool) bool {
+	if !typ.IsUserDefined {
+		switch typ.Name {
+		case objects.IntType.Name, objects.UIntType.Name, objects.StringType.Name, objects.BoolType.Name, objects.CharType.Name:
+			return true
+		}
+		return false
+	}
+
+	sym, isStruct := s.structOf(typ)
+	if !isStruct {
+		return false
+	}
+
+	if seen[sym.Name] {
+		return true
+	}
+	seen[sym.Name] = true
+
+	for _, field := range sym.Fields {
+		if !s.isHashable(field.VarType(), seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// BindMapLiteral checks make map[K]V{key: value, ...} and returns the type of the map
+func (s *Scope) BindMapLiteral(node ast.MakeMapExpressionNode) (objects.TypeObject, bool) {
+	typ, ok := s.LookupType(node.Type)
+	if !ok {
+		return typ, false
+	}
+
+	for i, key := range node.Keys {
+		ok = s.bindMapKey(typ, key) && ok
+
+		value, found := s.typeOf(node.Values[i])
+		if !found {
+			ok = false
+			continue
+		}
+
+		if value.FingerPrint() != typ.SubTypes[1].FingerPrint() {
+			print2.Error(
+				"SEMANTIC",
+				print2.ConversionError,
+				node.Values[i].Span(),
+				"can't use a \"%s\" as a value of \"%s\"!",
+				value.TypeName(),
+				typ.TypeName(),
+			)
+			ok = false
+		}
+	}
+
+	return typ, ok
+}
+
+// BindCommaOkDeclaration checks v, ok := m[k] and declares v (the value type of the map) and ok (a bool)
+func (s *Scope) BindCommaOkDeclaration(node ast.CommaOkDeclarationStatementNode) (objects.VariableObjects, objects.VariableObjects, bool) {
+	value, ok := s.bindMapLookup(node.Initializer)
+
+	valueVariable := objects.CreateLocalVariableObject(node.Value.Name(), false, value)
+	okVariable := objects.CreateLocalVariableObject(node.Ok.Name(), false, objects.BoolType)
+
+	identifiers := []token.Token{node.Value, node.Ok}
+	for i, variable := range []objects.LocalVariableObject{valueVariable, okVariable} {
+		if s.TryDeclareVariable(variable, identifiers[i]) {
+			continue
+		}
+
+		print2.Error(
+			"SEMANTIC",
+			print2.DuplicateVariableDeclarationError,
+			identifiers[i].Span(),
+			"a variable called \"%s\" already exists!",
+			variable.Name,
+		)
+		ok = false
+	}
+
+	return valueVariable, okVariable, ok
+}
+
+// bindMapLookup checks that the initializer of v, ok := m[k] is a map lookup and returns the value type
+func (s *Scope) bindMapLookup(initializer ast.Expression) (objects.TypeObject, bool) {
+	lookup, isIndex := initializer.(ast.ArrayAccessExpressionNode)
+	if !isIndex {
+		print2.Error(
+			"SEMANTIC",
+			print2.UnexpectedNonMapValueError,
+			initializer.Span(),
+			"\"v, ok :=\" only works on map lookups like m[k]!",
+		)
+		return objects.TypeObject{}, false
+	}
+
+	typ, ok := s.typeOf(lookup.Base)
+	if !ok {
+		return objects.TypeObject{}, false
+	}
+
+	if !objects.IsMap(typ) {
+		print2.Error(
+			"SEMANTIC",
+			print2.UnexpectedNonMapValueError,
+			lookup.Base.Span(),
+			"\"v, ok :=\" only works on map lookups, \"%s\" isn't a map!",
+			typ.TypeName(),
+		)
+		return objects.TypeObject{}, false
+	}
+
+	return typ.SubTypes[1], s.bindMapKey(typ, lookup.Index)
+}
+
+// bindDelete checks delete(m, k), it does nothing if k isn't in m
+func (s *Scope) bindDelete(node ast.CallExpressionNode) bool {
+	typ, ok := s.typeOf(node.Arguments[0])
+	if !ok {
+		return false
+	}
+
+	if !objects.IsMap(typ) {
+		print2.Error(
+			"SEMANTIC",
+			print2.UnexpectedNonMapValueError,
+			node.Arguments[0].Span(),
+			"delete() needs a map, got \"%s\"!",
+			typ.TypeName(),
+		)
+		return false
+	}
+
+	return s.bindMapKey(typ, node.Arguments[1])
+}
+
+// bindMapKey checks that a key fits a map
+func (s *Scope) bindMapKey(typ objects.TypeObject, key ast.Expression) bool {
+	keyType, ok := s.typeOf(key)
+	if !ok {
+		return false
+	}
+
+	if keyType.FingerPrint() != typ.SubTypes[0].FingerPrint() {
+		print2.Error(
+			"SEMANTIC",
+			print2.ConversionError,
+			key.Span(),
+			"can't use a \"%s\" as a key of \"%s\"!",
+			keyType.TypeName(),
+			typ.TypeName(),
+		)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+const maps = `
+struct Key {
+	id int
+	name string
+}
+struct Bad {
+	values []int
+}
+var map[string]int ages
+var map[Key]bool seen
+var []int list
+`
+
+// TestMapLiterals checks the keys and values of make map[K]V{...} and which types can be keys
+func TestMapLiterals(t *testing.T) {
+	cases := []struct {
+		literal string
+		errors  []print2.ErrorType
+	}{
+		{`make map[string]int{"a": 1, "b": 2}`, nil},
+		{`make map[string]int{}`, nil},
+		{`make map[Key]string{make Key{1, "x"}: "x"}`, nil},
+		{`make map[int][]int{1: list}`, nil},
+		{`make map[string]int{1: 1}`, []print2.ErrorType{print2.ConversionError}},
+		{`make map[string]int{"a": "b"}`, []print2.ErrorType{print2.ConversionError}},
+		{`make map[[]int]int{}`, []print2.ErrorType{print2.InvalidMapKeyError}},
+		{`make map[Bad]int{}`, []print2.ErrorType{print2.InvalidMapKeyError}},
+		{`make map[float]int{}`, []print2.ErrorType{print2.InvalidMapKeyError}},
+	}
+
+	for _, c := range cases {
+		scope, members := declare(t, maps+c.literal)
+		typ, ok := scope.BindMapLiteral(lastExpression(t, members).(ast.MakeMapExpressionNode))
+
+		expectErrors(t, c.literal, c.errors...)
+		if ok != (len(c.errors) == 0) {
+			t.Errorf("%s: bound is %t", c.literal, ok)
+		}
+		if ok && !objects.IsMap(typ) {
+			t.Errorf("%s: type is %s, expected a map", c.literal, typ.TypeName())
+		}
+	}
+}
+
+// TestCommaOk checks v, ok := m[k], v gets the value type of the map and ok is a bool
+func TestCommaOk(t *testing.T) {
+	cases := []struct {
+		declaration string
+		value       objects.TypeObject
+		errors      []print2.ErrorType
+	}{
+		{`age, found := ages["bob"]`, objects.IntType, nil},
+		{`was, found := seen[make Key{1, "x"}]`, objects.BoolType, nil},
+		{`age, found := ages[1]`, objects.IntType, []print2.ErrorType{print2.ConversionError}},
+		{`item, found := list[0]`, objects.TypeObject{}, []print2.ErrorType{print2.UnexpectedNonMapValueError}},
+		{`age, found := len(ages)`, objects.TypeObject{}, []print2.ErrorType{print2.UnexpectedNonMapValueError}},
+		{`ages, found := ages["bob"]`, objects.IntType, []print2.ErrorType{print2.DuplicateVariableDeclarationError}},
+	}
+
+	for _, c := range cases {
+		scope, members := declare(t, maps+c.declaration)
+		statement := members[len(members)-1].(ast.GlobalStatementMember).Statement.(ast.CommaOkDeclarationStatementNode)
+		value, found, ok := scope.BindCommaOkDeclaration(statement)
+
+		expectErrors(t, c.declaration, c.errors...)
+		if ok != (len(c.errors) == 0) {
+			t.Errorf("%s: bound is %t", c.declaration, ok)
+		}
+		if value.VarType().FingerPrint() != c.value.FingerPrint() || found.VarType().Name != objects.BoolType.Name {
+			t.Errorf("%s: declared %s and %s", c.declaration, value.VarType().TypeName(), found.VarType().TypeName())
+		}
+	}
+
+	// both variables are declared, and can be used afterwards
+	scope, members := declare(t, maps+`age, found := ages["bob"]`+"\nage + 1\n")
+	statement := members[len(members)-2].(ast.GlobalStatementMember).Statement.(ast.CommaOkDeclarationStatementNode)
+	scope.BindCommaOkDeclaration(statement)
+	if typ, ok := scope.typeOf(lastExpression(t, members)); !ok || typ.Name != objects.IntType.Name {
+		t.Errorf("age + 1: type is %s (%t)", typ.Name, ok)
+	}
+	if _, isVariable := scope.TryLookupObject("found").(objects.VariableObjects); !isVariable {
+		t.Errorf("found hasn't been declared")
+	}
+}
@@ -0,0 +1,185 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// DeclareMembers declares the top level names of a package: structs, functions, methods, external
+// functions and global variables with a type. All files of a package go through here together, so the order
+// of files and declarations doesn't matter. Structs come first since everything else may use them
+func (s *Scope) DeclareMembers(members []ast.MemberNode) bool {
+	ok := true
+
+	// struct names first, fields may use any struct (even their own)
+	structs := make([]ast.StructDeclarationMember, 0)
+	for _, member := range members {
+		if member, isStruct := member.(ast.StructDeclarationMember); isStruct {
+			if s.declareStruct(member) {
+				structs = append(structs, member)
+			} else {
+				ok = false
+			}
+		}
+	}
+
+	for _, member := range structs {
+		ok = s.bindStructFields(member) && ok
+	}
+
+	for _, member := range members {
+		switch member := member.(type) {
+		case ast.FunctionDeclarationMember:
+			if member.Receiver != nil {
+				_, bound := s.BindMethod(member)
+				ok = bound && ok
+				continue
+			}
+
+			_, bound := s.BindFunctionSignature(member)
+			ok = bound && ok
+		case ast.ExternalFunctionDeclarationMember:
+			_, bound := s.BindExternalFunction(member)
+			ok = bound && ok
+		case ast.GlobalStatementMember:
+			ok = s.declareGlobal(member) && ok
+		}
+	}
+
+	return ok
+}
+
+// BindFunctionSignature turns a function declaration into a function object and declares it,
+// the body is bound later (see BindFunctionBodies)
+func (s *Scope) BindFunctionSignature(member ast.FunctionDeclarationMember) (objects.FunctionObject, bool) {
+	name := member.Identifier.Name()
+	params, ok := s.bindParameters(member.Parameters, name)
+
+	returnType, found := s.LookupType(member.TypeClause)
+	ok = ok && found
+
+	function := objects.CreateFunctionObject(name, params, returnType, member, member.IsPublic)
+
+	if !s.TryDeclareObject(function) {
+		print2.Error(
+			"SEMANTIC",
+			print2.DuplicateFunctionError,
+			member.Identifier.Span(),
+			"a function called \"%s\" already exists!",
+			name,
+		)
+		return function, false
+	}
+
+	return function, ok
+}
+
+// declareStruct declares a struct without its fields
+func (s *Scope) declareStruct(member ast.StructDeclarationMember) bool {
+	name := member.Identifier.Name()
+
+	if !s.TryDeclareObject(objects.CreateStructObject(name, member, nil)) {
+		print2.Error(
+			"SEMANTIC",
+			print2.DuplicateVariableDeclarationError,
+			member.Identifier.Span(),
+			"something called \"%s\" already exists, can't declare a struct with that name!",
+			name,
+		)
+		return false
+	}
+	return true
+}
+
+// bindStructFields resolves the fields of a declared struct
+func (s *Scope) bindStructFields(member ast.StructDeclarationMember) bool {
+	name := member.Identifier.Name()
+	ok := true
+
+	fields := make([]objects.VariableObjects, 0, len(member.Fields))
+	seen := make(map[string]bool)
+
+	for _, field := range member.Fields {
+		fieldName := field.Identifier.Name()
+
+		if seen[fieldName] {
+			print2.Error(
+				"SEMANTIC",
+				print2.DuplicateVariableDeclarationError,
+				field.Identifier.Span(),
+				"a field called \"%s\" already exists in struct \"%s\"!",
+				fieldName,
+				name,
+			)
+			ok = false
+			continue
+		}
+		seen[fieldName] = true
+
+		typ, found := s.LookupType(field.TypeClause)
+		ok = ok && found
+
+		fields = append(fields, objects.CreateLocalVariableObject(fieldName, false, typ))
+	}
+
+	s.Objects[name] = objects.CreateStructObject(name, member, fields)
+	return ok
+}
+
+// declareGlobal declares a global variable if its type is written out,
+// the type of "var x; 5" comes from its initializer and that's up to the binder
+func (s *Scope) declareGlobal(member ast.GlobalStatementMember) bool {
+	declaration, isDeclaration := member.Statement.(ast.VariableDeclarationStatementNode)
+	if !isDeclaration || !declaration.TypeClause.ClauseIsSet {
+		return true
+	}
+
+	name := declaration.Identifier.Name()
+	typ, ok := s.LookupType(declaration.TypeClause)
+
+	if !s.TryDeclareVariable(objects.CreateGlobalVariableObject(name, false, typ), declaration.Identifier) {
+		print2.Error(
+			"SEMANTIC",
+			print2.DuplicateVariableDeclarationError,
+			declaration.Identifier.Span(),
+			"a variable called \"%s\" already exists!",
+			name,
+		)
+		return false
+	}
+	return ok
+}
+
+// IsExported checks if a member has been declared with "set", those can be used by other packages
+func IsExported(member ast.MemberNode) bool {
+	switch member := member.(type) {
+	case ast.FunctionDeclarationMember:
+		return member.IsPublic
+	case ast.StructDeclarationMember:
+		return member.IsPublic
+	case ast.GlobalStatementMember:
+		declaration, isDeclaration := member.Statement.(ast.VariableDeclarationStatementNode)
+		return isDeclaration && declaration.Keyword.Type == token.SET
+	}
+	return false
+}
+
+// MemberName returns the name a member declares in the package scope, if it declares one
+// (methods don't, they belong to their type)
+func MemberName(member ast.MemberNode) (token.Token, bool) {
+	switch member := member.(type) {
+	case ast.FunctionDeclarationMember:
+		return member.Identifier, member.Receiver == nil
+	case ast.ExternalFunctionDeclarationMember:
+		return member.Identifier, true
+	case ast.StructDeclarationMember:
+		return member.Identifier, true
+	case ast.GlobalStatementMember:
+		if declaration, isDeclaration := member.Statement.(ast.VariableDeclarationStatementNode); isDeclaration {
+			return declaration.Identifier, true
+		}
+	}
+	return token.Token{}, false
+}
@@ -0,0 +1,177 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// BindMethod turns a method declaration (fn (p *Point) Len() float) into a type function
+// and adds it to the method set of its receiver's type.
+// Methods can only be declared on structs declared in the same scope
+func (s *Scope) BindMethod(member ast.FunctionDeclarationMember) (objects.TypeFunctionObject, bool) {
+	name := member.Identifier.Name()
+	receiver := member.Receiver
+
+	origin, ok := s.LookupType(receiver.TypeClause)
+	if !ok {
+		return objects.TypeFunctionObject{}, false
+	}
+
+	sym, isStruct := s.Objects[origin.Name].(objects.StructObject)
+	if !isStruct || !origin.IsUserDefined {
+		print2.Error(
+			"SEMANTIC",
+			print2.IllegalFunctionSignatureError,
+			receiver.TypeClause.Span(),
+			"methods can only be declared on structs of this package, \"%s\" isn't one!",
+			origin.Name,
+		)
+		return objects.TypeFunctionObject{}, false
+	}
+
+	// the receiver is passed in front of the other parameters
+	params, ok := s.bindParameters(member.Parameters, name)
+	for i := range params {
+		params[i].Ordinal++
+
+		if params[i].Name == receiver.Identifier.Name() {
+			print2.Error(
+				"SEMANTIC",
+				print2.DuplicateParameterError,
+				member.Parameters[i].Identifier.Span(),
+				"a parameter called \"%s\" already exists in function \"%s\"!",
+				params[i].Name,
+				name,
+			)
+			ok = false
+		}
+	}
+
+	receiverType := origin
+	if receiver.IsPointer() {
+		receiverType = objects.CreatePointerType(origin)
+	}
+	receiverParam := objects.CreateParameterObject(receiver.Identifier.Name(), 0, receiverType, 0)
+
+	returnType, found := s.LookupType(member.TypeClause)
+	ok = ok && found
+
+	method := objects.CreateMethodObject(name, receiverParam, receiver.IsPointer(), params, returnType, member, origin)
+
+	// a method can't share its name with a field or another method
+	for _, field := range sym.Fields {
+		if field.ObjectName() == name {
+			print2.Error(
+				"SEMANTIC",
+				print2.DuplicateFunctionError,
+				member.Identifier.Span(),
+				"struct \"%s\" already has a field called \"%s\"!",
+				origin.Name,
+				name,
+			)
+			return method, false
+		}
+	}
+
+	if _, exists := s.Methods[origin.Name][name]; exists {
+		print2.Error(
+			"SEMANTIC",
+			print2.DuplicateFunctionError,
+			member.Identifier.Span(),
+			"struct \"%s\" already has a method called \"%s\"!",
+			origin.Name,
+			name,
+		)
+		return method, false
+	}
+
+	if s.Methods[origin.Name] == nil {
+		s.Methods[origin.Name] = make(map[string]objects.TypeFunctionObject)
+	}
+	s.Methods[origin.Name][name] = method
+
+	return method, ok
+}
+
+// LookupMethod finds a method in the method set of a type (or the type a pointer points to)
+func (s *Scope) LookupMethod(typ objects.TypeObject, name string) (objects.TypeFunctionObject, bool) {
+	if objects.IsPointer(typ) {
+		typ = typ.SubTypes[0]
+	}
+
+	for scope := s; scope != nil; scope = scope.Parent {
+		if method, ok := scope.Methods[typ.Name][name]; ok && typ.IsUserDefined {
+			return method, true
+		}
+	}
+	return objects.TypeFunctionObject{}, false
+}
+
+// BindMethodCall finds the method called by p.Len() and checks the receiver rules:
+// value receivers can be called on values and pointers (the value is copied),
+// pointer receivers on pointers and on values that have an address (variables and their fields)
+func (s *Scope) BindMethodCall(node ast.TypeCallExpressionNode) (objects.TypeFunctionObject, bool) {
+	name := node.CallIdentifier.Name()
+
+	typ, ok := s.typeOf(node.Base)
+	if !ok {
+		return objects.TypeFunctionObject{}, false
+	}
+
+	method, found := s.LookupMethod(typ, name)
+	if !found {
+		print2.Error(
+			"SEMANTIC",
+			print2.TypeFunctionDoesNotExistError,
+			node.CallIdentifier.Span(),
+			"type \"%s\" doesn't have a method called \"%s\"!",
+			typ.Name,
+			name,
+		)
+		return method, false
+	}
+
+	if method.PointerReceiver && !objects.IsPointer(typ) && !isAddressable(node.Base) {
+		print2.Error(
+			"SEMANTIC",
+			print2.IncorrectTypeFunctionCallError,
+			node.Span(),
+			"method \"%s\" needs a pointer to a \"%s\", but this value doesn't have an address! Store it in a variable first",
+			name,
+			method.OriginType.Name,
+		)
+		return method, false
+	}
+
+	if len(node.Arguments) != len(method.Parameters) {
+		print2.Error(
+			"SEMANTIC",
+			print2.BadNumberOfParametersError,
+			node.Span(),
+			"method \"%s\" expects %d arguments, got %d!",
+			name,
+			len(method.Parameters),
+			len(node.Arguments),
+		)
+		return method, false
+	}
+
+	return method, s.bindArguments(node.Arguments, method.Parameters, name)
+}
+
+// isAddressable checks if we can take the address of a value, for calling pointer methods on it
+func isAddressable(expression ast.Expression) bool {
+	switch expression := expression.(type) {
+	case ast.NameExpressNode:
+		return true
+	case ast.ClassFieldAccessExpressionNode:
+		return isAddressable(expression.Base)
+	case ast.ParanthesisedExpressionNode:
+		return isAddressable(expression.ExpressionNode)
+	case ast.DereferenceExpressionNode:
+		// *p is whatever p points at
+		return true
+	}
+	return false
+}
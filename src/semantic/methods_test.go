@@ -0,0 +1,60 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+const counter = `
+struct Counter {
+	n int
+}
+fn (c Counter) Get() int {}
+fn (c *Counter) Add(by int) {}
+fn fresh() Counter {}
+var Counter v
+var pointer[Counter] p
+`
+
+// TestMethodCalls checks the receiver rules: value methods can be called on anything,
+// pointer methods on pointers and on values that have an address
+func TestMethodCalls(t *testing.T) {
+	cases := []struct {
+		call    string
+		pointer bool
+		errors  []print2.ErrorType
+	}{
+		{"v.Get()", false, nil},
+		{"p.Get()", false, nil},
+		{"fresh().Get()", false, nil},
+		{"v.Add(1)", true, nil},
+		{"p.Add(1)", true, nil},
+		{"(*p).Add(1)", true, nil},
+		{"fresh().Add(1)", true, []print2.ErrorType{print2.IncorrectTypeFunctionCallError}},
+		{"v.Add()", true, []print2.ErrorType{print2.BadNumberOfParametersError}},
+		{"v.Reset()", false, []print2.ErrorType{print2.TypeFunctionDoesNotExistError}},
+	}
+
+	for _, c := range cases {
+		scope, members := declare(t, counter+c.call)
+		method, ok := scope.BindMethodCall(lastExpression(t, members).(ast.TypeCallExpressionNode))
+
+		if ok != (len(c.errors) == 0) {
+			t.Errorf("%s: bound is %t", c.call, ok)
+		}
+		if ok && method.PointerReceiver != c.pointer {
+			t.Errorf("%s: pointer receiver is %t, expected %t", c.call, method.PointerReceiver, c.pointer)
+		}
+		expectErrors(t, c.call, c.errors...)
+	}
+}
+
+// TestMethodDeclarations checks that every type has its own method set
+func TestMethodDeclarations(t *testing.T) {
+	declare(t, counter+"struct Timer {\n\tn int\n}\nfn (t Timer) Get() int {}\nfn (c *Counter) Get() int {}\n")
+
+	// Timer can have a Get too, Counter can't have two
+	expectErrors(t, "methods", print2.DuplicateFunctionError)
+}
@@ -0,0 +1,96 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// packageOf checks if an expression names an imported package, like math in math.Sqrt(x).
+// A variable called like a package hides it, the way any other name would
+func (s *Scope) packageOf(expression ast.Expression) (objects.PackageObject, bool) {
+	name, ok := expression.(ast.NameExpressNode)
+	if !ok {
+		return objects.PackageObject{}, false
+	}
+
+	pkg, ok := s.TryLookupObject(name.Identifier.Name()).(objects.PackageObject)
+	return pkg, ok
+}
+
+// LookupPackageMember finds what pkg.name refers to, only what the package exports can be used
+func (s *Scope) LookupPackageMember(pkg objects.PackageObject, name token.Token) (objects.Objects, bool) {
+	if sym, ok := pkg.Exports[name.Name()]; ok {
+		return sym, true
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.UndefinedVariableReferenceError,
+		name.Span(),
+		"package \"%s\" doesn't export anything called \"%s\"! Only what's declared with \"set\" can be used",
+		pkg.Name,
+		name.Name(),
+	)
+	return nil, false
+}
+
+// BindPackageVariable finds the global variable read or written by pkg.name
+func (s *Scope) BindPackageVariable(pkg objects.PackageObject, name token.Token) (objects.VariableObjects, bool) {
+	sym, ok := s.LookupPackageMember(pkg, name)
+	if !ok {
+		return nil, false
+	}
+
+	variable, ok := sym.(objects.VariableObjects)
+	if !ok {
+		print2.Error(
+			"SEMANTIC",
+			print2.UndefinedVariableReferenceError,
+			name.Span(),
+			"\"%s.%s\" isn't a variable!",
+			pkg.Name,
+			name.Name(),
+		)
+		return nil, false
+	}
+	return variable, true
+}
+
+// BindPackageCall finds the function called by pkg.Name(...)
+func (s *Scope) BindPackageCall(pkg objects.PackageObject, node ast.TypeCallExpressionNode) (objects.FunctionObject, bool) {
+	sym, ok := s.LookupPackageMember(pkg, node.CallIdentifier)
+	if !ok {
+		return objects.FunctionObject{}, false
+	}
+
+	function, ok := sym.(objects.FunctionObject)
+	if !ok {
+		print2.Error(
+			"SEMANTIC",
+			print2.UndefinedFunctionCallError,
+			node.CallIdentifier.Span(),
+			"\"%s.%s\" isn't a function!",
+			pkg.Name,
+			node.CallIdentifier.Name(),
+		)
+		return function, false
+	}
+
+	if len(node.Arguments) != len(function.Parameters) {
+		print2.Error(
+			"SEMANTIC",
+			print2.BadNumberOfParametersError,
+			node.Span(),
+			"function \"%s.%s\" expects %d arguments, got %d!",
+			pkg.Name,
+			function.Name,
+			len(function.Parameters),
+			len(node.Arguments),
+		)
+		return function, false
+	}
+
+	return function, s.bindArguments(node.Arguments, function.Parameters, pkg.Name+"."+function.Name)
+}
@@ -0,0 +1,153 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// BindForRange decides what a for range loop walks over and returns the loop node for it:
+//   - int                  FromToStatementNode, i goes from 0 to n-1
+//   - slices and arrays    RangeArrayStatementNode, index and element
+//   - strings              RangeStringStatementNode, byte offset and rune (an int)
+//   - maps                 RangeMapStatementNode, key and value
+//
+// each of them lowers to a for or while loop, so break and continue work like they do there
+func (s *Scope) BindForRange(node ast.ForRangeStatementNode) (ast.Statement, bool) {
+	typ, ok := s.typeOf(node.Expression)
+	if !ok {
+		return node, false
+	}
+
+	switch {
+	case objects.IsSlice(typ) || objects.IsArray(typ):
+		return ast.CreateRangeArrayStatementNode(node), true
+	case objects.IsMap(typ):
+		return ast.CreateRangeMapStatementNode(node), true
+	case typ.IsUserDefined:
+		break
+	case typ.Name == objects.StringType.Name:
+		return ast.CreateRangeStringStatementNode(node), true
+	case typ.Name == objects.IntType.Name:
+		if node.Value.Type == token.IDENT {
+			print2.Error(
+				"SEMANTIC",
+				print2.InvalidRangeError,
+				node.Value.Span(),
+				"a range over an integer only has one variable!",
+			)
+			return node, false
+		}
+		return ast.CreateFromToStatementNode(node), true
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.InvalidRangeError,
+		node.Expression.Span(),
+		"can't range over \"%s\"! Only slices, arrays, strings, maps and ints can be ranged over",
+		typ.TypeName(),
+	)
+	return node, false
+}
+
+// LowerForRange binds a for range loop and returns the for or while loop it lowers to,
+// the lowered loop still has to be bound
+func (s *Scope) LowerForRange(node ast.ForRangeStatementNode) (ast.Statement, bool) {
+	loop, ok := s.BindForRange(node)
+	if !ok {
+		return node, false
+	}
+
+	return loop.(interface{ Lower() ast.Statement }).Lower(), true
+}
+
+// bindRangeCall checks the calls lowered range loops make (see ast.DecodeRuneFunction and the others),
+// they can't be written in source code so a bad one is a bug in the lowering
+func (s *Scope) bindRangeCall(node ast.CallExpressionNode) (objects.TypeObject, bool) {
+	name := node.Identifier.Name()
+
+	switch name {
+	case ast.DecodeRuneFunction, ast.RuneLengthFunction:
+		if !checkArgumentCount(node, 2, 2) {
+			return objects.IntType, false
+		}
+
+		typ, ok := s.typeOf(node.Arguments[0])
+		if ok && (typ.IsUserDefined || typ.Name != objects.StringType.Name) {
+			print2.Error(
+				"SEMANTIC",
+				print2.ConversionError,
+				node.Arguments[0].Span(),
+				"%s() needs a string, got \"%s\"!",
+				name,
+				typ.TypeName(),
+			)
+			ok = false
+		}
+		return objects.IntType, s.bindIndex(node.Arguments[1]) && ok
+
+	case ast.MapIteratorFunction:
+		if !checkArgumentCount(node, 1, 1) {
+			return objects.TypeObject{}, false
+		}
+
+		typ, ok := s.typeOf(node.Arguments[0])
+		if !ok {
+			return objects.TypeObject{}, false
+		}
+
+		if !objects.IsMap(typ) {
+			print2.Error(
+				"SEMANTIC",
+				print2.UnexpectedNonMapValueError,
+				node.Arguments[0].Span(),
+				"%s() needs a map, got \"%s\"!",
+				name,
+				typ.TypeName(),
+			)
+			return objects.TypeObject{}, false
+		}
+		return objects.CreateMapIteratorType(typ.SubTypes[0], typ.SubTypes[1]), true
+
+	case ast.MapNextFunction, ast.MapKeyFunction, ast.MapValueFunction:
+		if !checkArgumentCount(node, 1, 1) {
+			return objects.TypeObject{}, false
+		}
+
+		typ, ok := s.typeOf(node.Arguments[0])
+		if !ok {
+			return objects.TypeObject{}, false
+		}
+
+		if !objects.IsMapIterator(typ) {
+			print2.Error(
+				"SEMANTIC",
+				print2.UnexpectedNonMapValueError,
+				node.Arguments[0].Span(),
+				"%s() needs a map iterator, got \"%s\"!",
+				name,
+				typ.TypeName(),
+			)
+			return objects.TypeObject{}, false
+		}
+
+		switch name {
+		case ast.MapKeyFunction:
+			return typ.SubTypes[0], true
+		case ast.MapValueFunction:
+			return typ.SubTypes[1], true
+		}
+		return objects.BoolType, true
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.UndefinedFunctionCallError,
+		node.Identifier.Span(),
+		"\"%s\" isn't a built-in function!",
+		name,
+	)
+	return objects.TypeObject{}, false
+}
@@ -0,0 +1,114 @@
+package semantic
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// jumps is the body every range loop below gets, with a continue and a break
+const jumps = ` {
+	if (i == 1) {
+		continue
+	}
+	break
+}`
+
+// TestRangeLowering checks that every kind of range loop in a function body is lowered
+// to a loop that still holds the original body, break and continue included
+func TestRangeLowering(t *testing.T) {
+	cases := []struct {
+		name      string
+		code      string
+		loop      reflect.Type
+		variables int // the range variables the loop body declares in front of the original body
+	}{
+		{"slice", "fn f(s []int) {\nfor i, v := range s" + jumps + "\n}", reflect.TypeOf(ast.ForStatementNode{}), 2},
+		{"array", "fn f(a [3]int) {\nfor i := range a" + jumps + "\n}", reflect.TypeOf(ast.ForStatementNode{}), 1},
+		{"map", "fn f(m map[int]string) {\nfor i, v := range m" + jumps + "\n}", reflect.TypeOf(ast.WhileStatementNode{}), 2},
+		{"string", "fn f(s string) {\nfor i, r := range s" + jumps + "\n}", reflect.TypeOf(ast.ForStatementNode{}), 2},
+		{"int", "fn f(n int) {\nfor i := range n" + jumps + "\n}", reflect.TypeOf(ast.ForStatementNode{}), 1},
+	}
+
+	for _, c := range cases {
+		members, ok := bind(t, c.code)
+		expectErrors(t, c.name)
+		if !ok {
+			t.Errorf("%s: isn't bound", c.name)
+			continue
+		}
+
+		// { hidden variables...; loop }
+		lowered, isBlock := body(t, members, "f").Statements[0].(ast.BlockStatementNode)
+		if !isBlock {
+			t.Errorf("%s: lowered to %T, expected a block", c.name, body(t, members, "f").Statements[0])
+			continue
+		}
+
+		loop := lowered.Statements[len(lowered.Statements)-1]
+		if reflect.TypeOf(loop) != c.loop {
+			t.Errorf("%s: loops with %T, expected %s", c.name, loop, c.loop)
+			continue
+		}
+
+		var inner ast.Statement
+		switch loop := loop.(type) {
+		case ast.ForStatementNode:
+			inner = loop.StatementNode
+		case ast.WhileStatementNode:
+			inner = loop.StatementNode
+		}
+
+		statements := inner.(ast.BlockStatementNode).Statements
+		if len(statements) != c.variables+1 {
+			t.Errorf("%s: the loop body has %d statements, expected %d", c.name, len(statements), c.variables+1)
+			continue
+		}
+
+		original := statements[c.variables].(ast.BlockStatementNode).Statements
+		branch := original[0].(ast.IfStatementNode).ThenStatement.(ast.BlockStatementNode).Statements
+		if _, isContinue := branch[0].(ast.ContinueStatementNode); !isContinue {
+			t.Errorf("%s: expected a continue in the if, got %T", c.name, branch[0])
+		}
+		if _, isBreak := original[1].(ast.BreakStatementNode); !isBreak {
+			t.Errorf("%s: expected a break after the if, got %T", c.name, original[1])
+		}
+	}
+}
+
+// TestRangeErrors checks what can't be ranged over and that break and continue
+// only work inside of the loop
+func TestRangeErrors(t *testing.T) {
+	cases := []struct {
+		code   string
+		errors []print2.ErrorType
+	}{
+		{"fn f(b bool) {\nfor i := range b {\n}\n}", []print2.ErrorType{print2.InvalidRangeError}},
+		{"fn f(n int) {\nfor i, v := range n {\n}\n}", []print2.ErrorType{print2.InvalidRangeError}},
+		{"fn f(s []int) {\nfor i := range s {\n}\nbreak\n}", []print2.ErrorType{print2.OutsideBreakError}},
+		{"fn f(s string) {\nfor i := range s {\n}\ncontinue\n}", []print2.ErrorType{print2.OutsideContinueError}},
+
+		// the range variables are locals of the loop
+		{"fn f(i int) {\nfor i := range 3 {\n}\n}", []print2.ErrorType{print2.DuplicateVariableDeclarationError}},
+		{"fn f(s []int) int {\nfor i, v := range s {\n}\nreturn v\n}", []print2.ErrorType{print2.UndefinedVariableReferenceError}},
+		{"fn f(s []int) {\nfor i := range s {\n}\nfor i := range s {\n}\n}", nil},
+
+		// the values have the types of the elements, runes are ints
+		{"fn f(m map[string]bool) string {\nfor k, v := range m {\nif (v) {\nreturn k\n}\n}\nreturn \"\"\n}", nil},
+		{"fn f(s string) string {\nfor i, r := range s {\nreturn r\n}\nreturn s\n}", []print2.ErrorType{print2.ConversionError}},
+
+		// nested loops keep their hidden variables apart
+		{"fn f(s [][]int) {\nfor i, row := range s {\nfor j, v := range row {\ncontinue\n}\nbreak\n}\n}", nil},
+	}
+
+	for _, c := range cases {
+		_, ok := bind(t, c.code)
+
+		expectErrors(t, c.code, c.errors...)
+		if ok != (len(c.errors) == 0) {
+			t.Errorf("%q: bound is %t", c.code, ok)
+		}
+	}
+}
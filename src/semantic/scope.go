@@ -1,21 +1,48 @@
 package semantic
 
-import "github.com/NikoMalik/Tod-go-compiler/src/objects"
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
 
 type Scope struct {
 	Parent  *Scope
 	Objects map[string]objects.Objects
+
+	// the methods of the types declared in this scope, by type name and then method name
+	Methods map[string]map[string]objects.TypeFunctionObject
+
+	// the names declared in this scope by their skeleton, see confusableWith()
+	skeletons map[string][]string
 }
 
 func (s *Scope) TryDeclareObject(sym objects.Objects) bool {
-	lookup := s.TryLookupObject(sym.ObjectName())
+	return s.declare(sym, declarationSpan(sym))
+}
+
+// TryDeclareVariable declares a variable or parameter, they don't know where
+// they've been declared so the warnings point at their identifier
+func (s *Scope) TryDeclareVariable(variable objects.VariableObjects, identifier token.Token) bool {
+	return s.declare(variable, identifier.Span())
+}
+
+func (s *Scope) declare(sym objects.Objects, span print2.TextSpan) bool {
+	name := sym.ObjectName()
+
+	lookup := s.TryLookupObject(name)
 
 	if lookup != nil {
 		return false // symbol already exists
-	} else {
-		s.Objects[sym.ObjectName()] = sym
-		return true
 	}
+
+	key := skeleton(name)
+	s.warnConfusable(name, key, span)
+	s.skeletons[key] = append(s.skeletons[key], name)
+
+	s.Objects[name] = sym
+	return true
 }
 
 func (s *Scope) TryLookupObject(name string) objects.Objects {
@@ -129,9 +156,60 @@ func (s *Scope) GetAllPackage() []objects.PackageObject {
 	return packages
 }
 
+// LookupType finds the type a type clause is talking about, a missing clause means void.
+// Types are either built in or structs declared in this scope (or a parent)
+func (s *Scope) LookupType(clause ast.TypeClauseNode) (objects.TypeObject, bool) {
+	if !clause.ClauseIsSet {
+		return objects.VoidType, true
+	}
+
+	name := clause.TypeIdentifier.Name()
+
+	// []T and [N]T
+	if clause.IsSlice() || clause.IsArray() {
+		element, ok := s.LookupType(clause.SubClauses[0])
+		if clause.IsSlice() {
+			return objects.CreateSliceType(element), ok
+		}
+
+		length, _ := clause.Length.RealValue.(int)
+		return objects.CreateArrayType(element, length), ok
+	}
+
+	if clause.IsMap() {
+		return s.lookupMapType(clause)
+	}
+
+	// pointer[Point]
+	if name == "pointer" && len(clause.SubClauses) == 1 {
+		base, ok := s.LookupType(clause.SubClauses[0])
+		return objects.CreatePointerType(base), ok
+	}
+
+	if typ, ok := objects.LookupBuiltInType(name); ok {
+		return typ, true
+	}
+
+	if sym, ok := s.TryLookupObject(name).(objects.StructObject); ok {
+		return sym.Type, true
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.UnknownDataTypeError,
+		clause.Span(),
+		"couldn't find a type called \"%s\"!",
+		name,
+	)
+	return objects.TypeObject{}, false
+}
+
 func CreateScope(parent *Scope) Scope {
 	return Scope{
 		Parent:  parent,
 		Objects: make(map[string]objects.Objects),
+		Methods: make(map[string]map[string]objects.TypeFunctionObject),
+
+		skeletons: make(map[string][]string),
 	}
 }
@@ -0,0 +1,63 @@
+package semantic
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/lexer"
+	"github.com/NikoMalik/Tod-go-compiler/src/parser"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// declare parses code and declares its members in a new scope, the code has to parse
+func declare(t *testing.T, code string) (*Scope, []ast.MemberNode) {
+	t.Helper()
+	print2.OutputErrorMessages = false
+	print2.ErrorList = print2.ErrorList[:0]
+
+	lxr := lexer.New(strings.NewReader(code), t.Name()+".tod")
+	lxr.InsertSemicolons = true
+	members := parser.ParseFrom(lxr)
+	if len(print2.ErrorList) != 0 {
+		t.Fatalf("syntax errors: %v", print2.ErrorList)
+	}
+
+	scope := CreateScope(nil)
+	scope.DeclareMembers(members)
+	return &scope, members
+}
+
+// lastExpression is the expression of the last expression statement in members
+func lastExpression(t *testing.T, members []ast.MemberNode) ast.Expression {
+	t.Helper()
+	for i := len(members) - 1; i >= 0; i-- {
+		global, ok := members[i].(ast.GlobalStatementMember)
+		if !ok {
+			continue
+		}
+		if statement, ok := global.Statement.(ast.ExpressionStatementNode); ok {
+			return statement.Expression
+		}
+	}
+	t.Fatalf("there is no expression statement")
+	return nil
+}
+
+// errorTypes lists the types of the errors reported so far
+func errorTypes() []print2.ErrorType {
+	types := make([]print2.ErrorType, 0, len(print2.ErrorList))
+	for _, report := range print2.ErrorList {
+		types = append(types, report.ErrType)
+	}
+	return types
+}
+
+// expectErrors checks that exactly the expected errors have been reported, in that order
+func expectErrors(t *testing.T, what string, expected ...print2.ErrorType) {
+	t.Helper()
+	if types := errorTypes(); !slices.Equal(types, expected) {
+		t.Errorf("%s: reported %v, expected %v", what, types, expected)
+	}
+}
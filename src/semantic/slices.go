@@ -0,0 +1,322 @@
+package semantic
+
+import (
+	"fmt"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// built-in functions working on slices, arrays, strings and maps
+var builtInFunctions = map[string]bool{
+	"len":    true,
+	"cap":    true,
+	"append": true,
+	"copy":   true,
+	"delete": true,
+
+	// only called by lowered range loops
+	ast.DecodeRuneFunction:  true,
+	ast.RuneLengthFunction:  true,
+	ast.MapIteratorFunction: true,
+	ast.MapNextFunction:     true,
+	ast.MapKeyFunction:      true,
+	ast.MapValueFunction:    true,
+}
+
+// IsBuiltInFunction tells us if a call goes to one of len, cap, append, copy or delete
+// (or one of the functions lowered range loops call)
+func IsBuiltInFunction(name string) bool {
+	return builtInFunctions[name]
+}
+
+// BindIndexExpression checks a[i] (or m[k]) and returns the type of the element.
+// Bounds are checked at runtime
+func (s *Scope) BindIndexExpression(node ast.ArrayAccessExpressionNode) (objects.TypeObject, bool) {
+	_, element, ok := s.bindElement(node.Base, node.Index)
+	return element, ok
+}
+
+// BindIndexAssignment checks a[i] = value (or m[k] = value) and returns the type of the element
+func (s *Scope) BindIndexAssignment(node ast.ArrayAssignmentExpressionNode) (objects.TypeObject, bool) {
+	base, element, ok := s.bindElement(node.Base, node.Index)
+	if !ok {
+		return element, false
+	}
+
+	if base.Name == objects.StringType.Name && !base.IsUserDefined {
+		print2.Error(
+			"SEMANTIC",
+			print2.UnexpectedNonArrayValueError,
+			node.Base.Span(),
+			"strings can't be changed, only slices, arrays and maps can be assigned to!",
+		)
+		return element, false
+	}
+
+	typ, ok := s.typeOf(node.Value)
+	if !ok {
+		return element, false
+	}
+
+	if typ.FingerPrint() != element.FingerPrint() {
+		print2.Error(
+			"SEMANTIC",
+			print2.ConversionError,
+			node.Value.Span(),
+			"can't assign a \"%s\" to an element of type \"%s\"!",
+			typ.TypeName(),
+			element.TypeName(),
+		)
+		return element, false
+	}
+	return element, true
+}
+
+// bindElement checks base[index] and returns the type of base and of the element
+func (s *Scope) bindElement(baseExpression ast.Expression, index ast.Expression) (objects.TypeObject, objects.TypeObject, bool) {
+	base, ok := s.typeOf(baseExpression)
+	if !ok {
+		return base, objects.TypeObject{}, false
+	}
+
+	if objects.IsMap(base) {
+		return base, base.SubTypes[1], s.bindMapKey(base, index)
+	}
+
+	element, isIndexable := objects.ElementType(base)
+	if !isIndexable {
+		print2.Error(
+			"SEMANTIC",
+			print2.UnexpectedNonArrayValueError,
+			baseExpression.Span(),
+			"can only index slices, arrays, strings and maps, not \"%s\"!",
+			base.TypeName(),
+		)
+		return base, objects.TypeObject{}, false
+	}
+
+	return base, element, s.bindIndex(index)
+}
+
+// BindSliceExpression checks a[low:high] and a[low:high:max].
+// Slicing a slice or an array gives a slice sharing its buffer, slicing a string gives a string
+func (s *Scope) BindSliceExpression(node ast.SliceExpressionNode) (objects.TypeObject, bool) {
+	base, ok := s.typeOf(node.Base)
+	if !ok {
+		return objects.TypeObject{}, false
+	}
+
+	for _, index := range []ast.Expression{node.Low, node.High, node.Max} {
+		if index != nil {
+			ok = s.bindIndex(index) && ok
+		}
+	}
+
+	switch {
+	case objects.IsSlice(base):
+		return base, ok
+	case objects.IsArray(base):
+		return objects.CreateSliceType(base.SubTypes[0]), ok
+	case base.Name == objects.StringType.Name && !base.IsUserDefined:
+		// strings can't grow, so there's no capacity to limit
+		if node.IsFull() {
+			print2.Error(
+				"SEMANTIC",
+				print2.UnexpectedNonArrayValueError,
+				node.Span(),
+				"a[low:high:max] can't be used on strings!",
+			)
+			return base, false
+		}
+		return base, ok
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.UnexpectedNonArrayValueError,
+		node.Base.Span(),
+		"can only slice slices, arrays and strings, not \"%s\"!",
+		base.TypeName(),
+	)
+	return objects.TypeObject{}, false
+}
+
+// BindBuiltInCall checks the arguments of len, cap, append, copy and delete and returns the type of the result:
+//   - len(x)           int, x is a slice, array, string or map
+//   - cap(x)           int, x is a slice or array
+//   - append(s, v...)  the type of s, every v has to be an element of s
+//   - copy(dst, src)   int (the number of elements copied), src is a slice of the same elements
+//     (or a string if dst is a []char)
+//   - delete(m, k)     void, see bindDelete
+func (s *Scope) BindBuiltInCall(node ast.CallExpressionNode) (objects.TypeObject, bool) {
+	name := node.Identifier.Name()
+
+	switch name {
+	case "len", "cap":
+		if !checkArgumentCount(node, 1, 1) {
+			return objects.IntType, false
+		}
+
+		typ, ok := s.typeOf(node.Arguments[0])
+		if !ok {
+			return objects.IntType, false
+		}
+
+		_, hasLength := objects.ElementType(typ)
+		hasLength = hasLength || objects.IsMap(typ)
+		if name == "cap" {
+			hasLength = objects.IsSlice(typ) || objects.IsArray(typ)
+		}
+
+		if !hasLength {
+			print2.Error(
+				"SEMANTIC",
+				print2.UnexpectedNonArrayValueError,
+				node.Arguments[0].Span(),
+				"%s() doesn't work on \"%s\"!",
+				name,
+				typ.TypeName(),
+			)
+			return objects.IntType, false
+		}
+		return objects.IntType, true
+
+	case "append":
+		if !checkArgumentCount(node, 1, -1) {
+			return objects.TypeObject{}, false
+		}
+
+		slice, ok := s.sliceArgument(node.Arguments[0], name)
+		if !ok {
+			return slice, false
+		}
+
+		element := slice.SubTypes[0]
+		for _, argument := range node.Arguments[1:] {
+			typ, found := s.typeOf(argument)
+			if !found {
+				ok = false
+				continue
+			}
+
+			if typ.FingerPrint() != element.FingerPrint() {
+				print2.Error(
+					"SEMANTIC",
+					print2.ConversionError,
+					argument.Span(),
+					"can't append a \"%s\" to a slice of \"%s\"!",
+					typ.TypeName(),
+					element.TypeName(),
+				)
+				ok = false
+			}
+		}
+		return slice, ok
+
+	case "copy":
+		if !checkArgumentCount(node, 2, 2) {
+			return objects.IntType, false
+		}
+
+		dst, ok := s.sliceArgument(node.Arguments[0], name)
+		if !ok {
+			return objects.IntType, false
+		}
+
+		src, ok := s.typeOf(node.Arguments[1])
+		if !ok {
+			return objects.IntType, false
+		}
+
+		element := dst.SubTypes[0]
+		fromString := src.Name == objects.StringType.Name && !src.IsUserDefined && element.Name == objects.CharType.Name
+		if !fromString && (!objects.IsSlice(src) || src.SubTypes[0].FingerPrint() != element.FingerPrint()) {
+			print2.Error(
+				"SEMANTIC",
+				print2.ConversionError,
+				node.Arguments[1].Span(),
+				"can't copy from \"%s\" into a slice of \"%s\"!",
+				src.TypeName(),
+				element.TypeName(),
+			)
+			return objects.IntType, false
+		}
+		return objects.IntType, true
+
+	case "delete":
+		if !checkArgumentCount(node, 2, 2) {
+			return objects.VoidType, false
+		}
+		return objects.VoidType, s.bindDelete(node)
+	}
+
+	return s.bindRangeCall(node)
+}
+
+// sliceArgument checks that the argument of a built-in is a slice
+func (s *Scope) sliceArgument(argument ast.Expression, function string) (objects.TypeObject, bool) {
+	typ, ok := s.typeOf(argument)
+	if !ok {
+		return typ, false
+	}
+
+	if !objects.IsSlice(typ) {
+		print2.Error(
+			"SEMANTIC",
+			print2.UnexpectedNonArrayValueError,
+			argument.Span(),
+			"%s() needs a slice, got \"%s\"!",
+			function,
+			typ.TypeName(),
+		)
+		return typ, false
+	}
+	return typ, true
+}
+
+// bindIndex checks that an index (or slice bound) is an integer
+func (s *Scope) bindIndex(index ast.Expression) bool {
+	typ, ok := s.typeOf(index)
+	if !ok {
+		return false
+	}
+
+	if typ.IsUserDefined || (typ.Name != objects.IntType.Name && typ.Name != objects.UIntType.Name) {
+		print2.Error(
+			"SEMANTIC",
+			print2.UnexpectedNonIntegerValueError,
+			index.Span(),
+			"indices have to be integers, got \"%s\"!",
+			typ.TypeName(),
+		)
+		return false
+	}
+	return true
+}
+
+// checkArgumentCount reports BadNumberOfParametersError if a built-in call doesn't have
+// between min and max arguments (max < 0 means there's no limit)
+func checkArgumentCount(node ast.CallExpressionNode, min int, max int) bool {
+	count := len(node.Arguments)
+	if count >= min && (max < 0 || count <= max) {
+		return true
+	}
+
+	expected := fmt.Sprint(min)
+	if max < 0 {
+		expected = "at least " + expected
+	}
+
+	print2.Error(
+		"SEMANTIC",
+		print2.BadNumberOfParametersError,
+		node.Span(),
+		"%s() expects %s arguments, got %d!",
+		node.Identifier.Name(),
+		expected,
+		count,
+	)
+	return false
+}
@@ -0,0 +1,69 @@
+package semantic
+
+import (
+	"testing"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+const collections = `
+var []int s
+var [3]int a
+var string str
+var int n
+var map[string]int m
+`
+
+// TestSliceExpressions checks the types of indexing, slicing and the built-ins working on slices
+func TestSliceExpressions(t *testing.T) {
+	ints := objects.CreateSliceType(objects.IntType)
+
+	cases := []struct {
+		expression string
+		expected   objects.TypeObject
+		errors     []print2.ErrorType
+	}{
+		{"s[1]", objects.IntType, nil},
+		{"a[n]", objects.IntType, nil},
+		{"str[0]", objects.CharType, nil},
+		{"s[1:2]", ints, nil},
+		{"s[1:2:3]", ints, nil},
+		{"a[:2]", ints, nil},
+		{"str[1:]", objects.StringType, nil},
+		{"str[1:2:3]", objects.StringType, []print2.ErrorType{print2.UnexpectedNonArrayValueError}},
+		{"n[1:2]", objects.TypeObject{}, []print2.ErrorType{print2.UnexpectedNonArrayValueError}},
+		{"s[\"x\"]", objects.IntType, []print2.ErrorType{print2.UnexpectedNonIntegerValueError}},
+		{"s[1:str]", ints, []print2.ErrorType{print2.UnexpectedNonIntegerValueError}},
+
+		{"len(s)", objects.IntType, nil},
+		{"len(str)", objects.IntType, nil},
+		{"len(m)", objects.IntType, nil},
+		{"cap(a)", objects.IntType, nil},
+		{"cap(s[1:])", objects.IntType, nil},
+		{"cap(str)", objects.IntType, []print2.ErrorType{print2.UnexpectedNonArrayValueError}},
+		{"cap(m)", objects.IntType, []print2.ErrorType{print2.UnexpectedNonArrayValueError}},
+		{"len(n)", objects.IntType, []print2.ErrorType{print2.UnexpectedNonArrayValueError}},
+		{"len(s, s)", objects.IntType, []print2.ErrorType{print2.BadNumberOfParametersError}},
+
+		{"append(s, 1, n)", ints, nil},
+		{"append(s)", ints, nil},
+		{"append(s, str)", ints, []print2.ErrorType{print2.ConversionError}},
+		{"append(a, 1)", objects.CreateArrayType(objects.IntType, 3), []print2.ErrorType{print2.UnexpectedNonArrayValueError}},
+		{"copy(s, a[:])", objects.IntType, nil},
+		{"copy(s, str)", objects.IntType, []print2.ErrorType{print2.ConversionError}},
+	}
+
+	for _, c := range cases {
+		scope, members := declare(t, collections+c.expression)
+		typ, ok := scope.typeOf(lastExpression(t, members))
+
+		expectErrors(t, c.expression, c.errors...)
+		if ok != (len(c.errors) == 0) {
+			t.Errorf("%s: bound is %t", c.expression, ok)
+		}
+		if typ.FingerPrint() != c.expected.FingerPrint() {
+			t.Errorf("%s: type is %s, expected %s", c.expression, typ.TypeName(), c.expected.TypeName())
+		}
+	}
+}
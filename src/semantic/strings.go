@@ -0,0 +1,163 @@
+package semantic
+
+import (
+	"github.com/NikoMalik/Tod-go-compiler/src/ast"
+	"github.com/NikoMalik/Tod-go-compiler/src/objects"
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+	"github.com/NikoMalik/Tod-go-compiler/src/token"
+)
+
+// values are converted to strings by interpolated strings ("${x}") and by string(x).
+// bool, int, uint, float, double, char and string convert like they're printed, a struct converts
+// to its name and fields in the order they're declared: Point{x: 1, y: 2}
+
+// BindInterpolatedString checks that every expression embedded in an interpolated string can be converted to a string
+func (s *Scope) BindInterpolatedString(node ast.InterpolatedStringExpressionNode) bool {
+	ok := true
+	for _, expression := range node.Expressions {
+		ok = s.bindStringConversion(expression) && ok
+	}
+	return ok
+}
+
+// bindStringCast checks string(x)
+func (s *Scope) bindStringCast(node ast.CallExpressionNode) bool {
+	if !checkArgumentCount(node, 1, 1) {
+		return false
+	}
+	return s.bindStringConversion(node.Arguments[0])
+}
+
+// bindStringConversion checks that a value can be converted to a string, reports ConversionError if it can't
+func (s *Scope) bindStringConversion(value ast.Expression) bool {
+	typ, ok := s.typeOf(value)
+	if !ok {
+		return false
+	}
+
+	if !s.isStringConvertible(typ, make(map[string]bool)) {
+		print2.Error(
+			"SEMANTIC",
+			print2.ConversionError,
+			value.Span(),
+			"can't convert a \"%s\" to a string! Only bool, int, uint, float, double, char, string and structs of those can be",
+			typ.TypeName(),
+		)
+		return false
+	}
+	return true
+}
+
+// isStringConvertible checks if values of a type can be converted to a string: the built-in value types
+// and structs made of them. seen stops structs that contain themselves
+func (s *Scope) isStringConvertible(typ objects.TypeObject, seen map[string]bool) bool {
+	if !typ.IsUserDefined {
+		return objects.IsStringConvertible(typ)
+	}
+
+	sym, isStruct := s.structOf(typ)
+	if !isStruct || objects.IsPointer(typ) {
+		return false
+	}
+
+	if seen[sym.Name] {
+		return true
+	}
+	seen[sym.Name] = true
+
+	for _, field := range sym.Fields {
+		if !s.isStringConvertible(field.VarType(), seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// LowerStringCast rewrites string(x) for a struct x into a call of the function converting
+// that struct (see StructStringFunction), so x is only evaluated once. Other casts are left alone
+func (s *Scope) LowerStringCast(node ast.CallExpressionNode) ast.Expression {
+	if node.Identifier.Name() != objects.StringType.Name || len(node.Arguments) != 1 {
+		return node
+	}
+
+	typ, ok := s.typeOf(node.Arguments[0])
+	if !ok {
+		return node
+	}
+
+	sym, isStruct := s.structOf(typ)
+	if !isStruct || objects.IsPointer(typ) {
+		return node
+	}
+
+	function := s.StructStringFunction(sym)
+	identifier := ast.CreateLoweredToken(function.Name, token.IDENT, node.Identifier)
+	return ast.CreateCallExpressionNode(identifier, node.Arguments, ast.TypeClauseNode{}, node.ClosingParenthesis)
+}
+
+// StructStringFunction returns the function converting a struct to a string, it's declared in the
+// top scope the first time a struct is converted. For struct Point { x int, y int } it's
+//
+//	fn $stringPoint(Point value) string { return "Point{x: " + string(value.x) + ", y: " + string(value.y) + "}" }
+//
+// Like the names range loops are lowered to, it starts with "$" (see ast.hiddenToken)
+func (s *Scope) StructStringFunction(sym objects.StructObject) objects.FunctionObject {
+	top := s
+	for top.Parent != nil {
+		top = top.Parent
+	}
+
+	name := "$string" + sym.Name
+	if function, ok := top.TryLookupObject(name).(objects.FunctionObject); ok {
+		return function
+	}
+
+	at := sym.Declaration.Identifier
+	value := ast.CreateLoweredToken("value", token.IDENT, at)
+	plus := ast.CreateLoweredToken("+", token.ADD, at)
+
+	text := sym.Name + "{"
+	var result ast.Expression
+	for i, field := range sym.Fields {
+		if i > 0 {
+			text += ", "
+		}
+		text += field.ObjectName() + ": "
+
+		access := ast.CreateClassFieldAccessExpressionNode(ast.CreateNameExpressionNode(value), ast.CreateLoweredToken(field.ObjectName(), token.IDENT, at))
+		cast := ast.CreateCallExpressionNode(ast.CreateLoweredToken(objects.StringType.Name, token.IDENT, at), []ast.Expression{access}, ast.TypeClauseNode{}, ast.CreateLoweredToken(")", token.RPAREN, at))
+
+		result = concatenate(result, ast.CreateLoweredString(text, at), plus)
+		result = ast.CreateBinaryExpressionNode(plus, result, cast)
+		text = ""
+	}
+	result = concatenate(result, ast.CreateLoweredString(text+"}", at), plus)
+
+	stringClause := ast.CreateTypeClauseNode(nil, ast.CreateLoweredToken(objects.StringType.Name, token.IDENT, at), nil, token.Token{})
+	body := ast.CreateBlockStatementNode(
+		ast.CreateLoweredToken("{", token.LBRACE, at),
+		[]ast.Statement{ast.CreateReturnStatementNode(ast.CreateLoweredToken("return", token.RETURN, at), result)},
+		ast.CreateLoweredToken("}", token.RBRACE, at),
+	)
+
+	member := ast.CreateFunctionDeclarationMember(
+		ast.CreateLoweredToken("fn", token.FN, at),
+		ast.CreateLoweredToken(name, token.IDENT, at),
+		[]ast.ParameterNode{ast.CreateParameterNode(value, ast.CreateTypeClauseNode(nil, at, nil, token.Token{}))},
+		stringClause,
+		body,
+		false,
+		nil,
+	)
+
+	function, _ := top.BindFunctionSignature(member)
+	return function
+}
+
+// concatenate is left + right, or just right if there's nothing on the left yet
+func concatenate(left ast.Expression, right ast.Expression, plus token.Token) ast.Expression {
+	if left == nil {
+		return right
+	}
+	return ast.CreateBinaryExpressionNode(plus, left, right)
+}
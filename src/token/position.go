@@ -0,0 +1,308 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/NikoMalik/Tod-go-compiler/src/print2"
+)
+
+// Pos is a compact position in a FileSet.
+// It's the byte offset of the spot in its file plus the base of that file,
+// so a single int is enough to find the file, line and column again (see FileSet.Position())
+type Pos int
+
+// NoPos is the zero value for Pos, it doesn't point anywhere
+const NoPos Pos = 0
+
+// IsValid tells us if the position points anywhere
+func (p Pos) IsValid() bool {
+	return p != NoPos
+}
+
+// Position is a decoded Pos
+type Position struct {
+	Filename string
+	Offset   int // byte offset, starting at 0
+	Line     int // starting at 1
+	Column   int // byte column, starting at 1
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column)
+}
+
+// File is a file known to a FileSet.
+// It covers the positions of its bytes plus one for the end of the file. Files are
+// read while lexing, so their size isn't known up front: a file that grows after
+// other files have been added gets another range of positions (a segment) at the end of the set
+type File struct {
+	set  *FileSet
+	name string
+
+	mutex    sync.Mutex
+	size     int
+	lines    []int     // offsets of the first byte of every line
+	segments []segment // in the order of their offsets (and positions)
+}
+
+// segment is a range of positions of a file: the offsets from offset on get
+// the positions from base on, up to where the next segment of the file starts
+type segment struct {
+	file   *File
+	base   int
+	offset int
+}
+
+// Name returns the file name of the file
+func (f *File) Name() string {
+	return f.name
+}
+
+// Base returns the position of the first byte in the file
+func (f *File) Base() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.segments[0].base
+}
+
+// Size returns the size of the file (or what has been read of it so far)
+func (f *File) Size() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.size
+}
+
+// LineCount returns the number of lines known so far
+func (f *File) LineCount() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.lines)
+}
+
+// AddLine adds the offset of the first byte of a new line.
+// Offsets that aren't past the last known line (or past the file) are ignored
+func (f *File) AddLine(offset int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if offset > f.lines[len(f.lines)-1] && offset <= f.size {
+		f.lines = append(f.lines, offset)
+	}
+}
+
+// Grow makes the file cover size bytes. If no other file has been added since the
+// file last grew its positions just go on, otherwise it gets a new segment
+func (f *File) Grow(size int) {
+	f.set.mutex.Lock()
+	defer f.set.mutex.Unlock()
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if size <= f.size {
+		return
+	}
+
+	last := f.segments[len(f.segments)-1]
+	if f.set.segments[len(f.set.segments)-1].file != f {
+		// the end position of the file so far stays where it is
+		last = segment{file: f, base: f.set.base, offset: f.size + 1}
+		f.segments = append(f.segments, last)
+		f.set.segments = append(f.set.segments, last)
+	}
+
+	f.size = size
+	f.set.base = last.base + size - last.offset + 1
+}
+
+// Pos returns the position of the given byte offset in the file
+func (f *File) Pos(offset int) Pos {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	// the lexer works at the end of the file, so that's where to look first
+	index := len(f.segments) - 1
+	if offset < f.segments[index].offset {
+		index = sort.Search(len(f.segments), func(i int) bool { return f.segments[i].offset > offset }) - 1
+	}
+
+	segment := f.segments[max(index, 0)]
+	return Pos(segment.base + offset - segment.offset)
+}
+
+// Offset returns the byte offset of the given position in the file
+func (f *File) Offset(p Pos) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	offset, _ := f.offset(p)
+	return offset
+}
+
+// offset finds the byte offset of a position, ok is false if the position isn't in the file.
+// The file has to be locked
+func (f *File) offset(p Pos) (offset int, ok bool) {
+	index := len(f.segments) - 1
+	if int(p) < f.segments[index].base {
+		index = sort.Search(len(f.segments), func(i int) bool { return f.segments[i].base > int(p) }) - 1
+	}
+
+	if index < 0 {
+		return int(p) - f.segments[0].base, false
+	}
+
+	segment := f.segments[index]
+	offset = segment.offset + int(p) - segment.base
+
+	end := f.size
+	if index+1 < len(f.segments) {
+		end = f.segments[index+1].offset - 1
+	}
+	return offset, offset <= end
+}
+
+// Line returns the line number of the given position
+func (f *File) Line(p Pos) int {
+	return f.Position(p).Line
+}
+
+// Position decodes the given position into a file name, line and column
+func (f *File) Position(p Pos) Position {
+	if !p.IsValid() {
+		return Position{}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	offset, _ := f.offset(p)
+	line := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset }) - 1
+
+	return Position{
+		Filename: f.name,
+		Offset:   offset,
+		Line:     line + 1,
+		Column:   offset - f.lines[line] + 1,
+	}
+}
+
+// Span decodes the text between pos and end into a text span for error messages
+func (f *File) Span(pos Pos, end Pos) print2.TextSpan {
+	if !pos.IsValid() {
+		return print2.TextSpan{}
+	}
+
+	start, stop := f.Position(pos), f.Position(end)
+	if !end.IsValid() {
+		stop = start
+	}
+
+	return print2.TextSpan{
+		File: f.name,
+
+		StartIndex: start.Offset,
+		EndIndex:   stop.Offset,
+
+		StartLine: start.Line,
+		EndLine:   stop.Line,
+
+		StartColumn: start.Column,
+		EndColumn:   stop.Column,
+	}
+}
+
+// FileSet is a set of files, every file gets its own range (or ranges) of positions
+type FileSet struct {
+	mutex    sync.RWMutex
+	base     int // first position that hasn't been handed out
+	files    []*File
+	segments []segment // of all files, in the order of their positions
+	last     *File     // file of the last lookup
+}
+
+// Files is the default file set, the lexer adds every file it reads to it
+var Files = NewFileSet()
+
+// NewFileSet creates an empty file set
+func NewFileSet() *FileSet {
+	return &FileSet{
+		base:     1, // 0 is NoPos
+		files:    make([]*File, 0),
+		segments: make([]segment, 0),
+	}
+}
+
+// AddFile adds a new empty file to the set, use File.Grow() to make it cover the code
+func (s *FileSet) AddFile(filename string) *File {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file := &File{
+		set:   s,
+		name:  filename,
+		lines: []int{0},
+	}
+	file.segments = []segment{{file: file, base: s.base}}
+
+	s.files = append(s.files, file)
+	s.segments = append(s.segments, file.segments[0])
+	s.base++ // even an empty file has an end position
+	return file
+}
+
+// File returns the file the given position belongs to, or nil
+func (s *FileSet) File(p Pos) *File {
+	if !p.IsValid() {
+		return nil
+	}
+
+	s.mutex.RLock()
+	if last := s.last; last != nil && last.contains(p) {
+		s.mutex.RUnlock()
+		return last
+	}
+
+	index := sort.Search(len(s.segments), func(i int) bool { return s.segments[i].base > int(p) }) - 1
+	var file *File
+	if index >= 0 {
+		file = s.segments[index].file
+	}
+	s.mutex.RUnlock()
+
+	if file == nil || !file.contains(p) {
+		return nil
+	}
+
+	s.mutex.Lock()
+	s.last = file
+	s.mutex.Unlock()
+
+	return file
+}
+
+// contains checks if the given position is in the file
+func (f *File) contains(p Pos) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, ok := f.offset(p)
+	return ok
+}
+
+// Position decodes the given position, positions outside of the set decode to an empty position
+func (s *FileSet) Position(p Pos) Position {
+	if file := s.File(p); file != nil {
+		return file.Position(p)
+	}
+	return Position{}
+}
+
+// Span decodes the text between pos and end into a text span for error messages
+func (s *FileSet) Span(pos Pos, end Pos) print2.TextSpan {
+	if file := s.File(pos); file != nil {
+		return file.Span(pos, end)
+	}
+	return print2.TextSpan{}
+}
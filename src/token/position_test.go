@@ -0,0 +1,58 @@
+package token
+
+import "testing"
+
+// TestGrowInterleaved reads two files a bit at a time, one after the other,
+// every offset of both has to decode back to its file, line and column
+func TestGrowInterleaved(t *testing.T) {
+	set := NewFileSet()
+	a := set.AddFile("a.tod")
+	b := set.AddFile("b.tod")
+
+	codes := map[*File]string{
+		a: "fn a() {\n\treturn 1\n}\n",
+		b: "var int x; 2\nvar int y; 3\n",
+	}
+
+	// grow them in turns, 5 bytes at a time
+	for size := 5; size < 35; size += 5 {
+		for _, file := range []*File{a, b} {
+			code := codes[file]
+			file.Grow(min(size, len(code)))
+			for offset := 0; offset < file.Size(); offset++ {
+				if code[offset] == '\n' {
+					file.AddLine(offset + 1)
+				}
+			}
+		}
+	}
+
+	for file, code := range codes {
+		if file.Size() != len(code) {
+			t.Fatalf("%s: size is %d, expected %d", file.Name(), file.Size(), len(code))
+		}
+
+		line, column := 1, 1
+		for offset := 0; offset <= len(code); offset++ {
+			p := file.Pos(offset)
+			if set.File(p) != file {
+				t.Fatalf("%s: offset %d (position %d) is found in another file", file.Name(), offset, p)
+			}
+
+			expected := Position{Filename: file.Name(), Offset: offset, Line: line, Column: column}
+			if got := set.Position(p); got != expected {
+				t.Fatalf("%s: offset %d decodes to %+v, expected %+v", file.Name(), offset, got, expected)
+			}
+
+			column++
+			if offset < len(code) && code[offset] == '\n' {
+				line, column = line+1, 1
+			}
+		}
+	}
+
+	// positions past the end of both files aren't in the set
+	if file := set.File(Pos(set.base)); file != nil {
+		t.Fatalf("position %d is past every file, but was found in %s", set.base, file.Name())
+	}
+}
@@ -3,6 +3,7 @@ package token
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/NikoMalik/Tod-go-compiler/src/print2"
 )
@@ -14,7 +15,29 @@ type Token struct {
 	Literal    string
 	RealValue  interface{}
 	SpaceAfter bool
-	Span       print2.TextSpan
+	Pos        Pos // position of the first character
+	End        Pos // position right after the last character
+
+	// whitespace and comments around the token, see FullText()
+	LeadingTrivia  []Trivia // everything between the previous token's trivia and this token
+	TrailingTrivia []Trivia // everything after this token up to (and including) the end of its line
+}
+
+type TriviaKind int
+
+const (
+	WhitespaceTrivia   TriviaKind = iota // spaces, tabs, ...
+	NewlineTrivia                        // a single '\n'
+	LineCommentTrivia                    // "// ..." or "# ..."
+	BlockCommentTrivia                   // "/* ... */"
+	DirectiveTrivia                      // a preprocessor directive like "#if DEBUG"
+	SkippedTrivia                        // code skipped by #if/#elif/#else
+)
+
+// Trivia is a piece of source code that doesn't make up a token
+type Trivia struct {
+	Kind TriviaKind
+	Text string
 }
 
 const (
@@ -29,7 +52,14 @@ const (
 	FLOAT32
 	FLOAT64
 	STRING
+	CHAR
 	BOOLEAN
+
+	// an interpolated string like "a ${x} b ${y} c" is lexed into
+	// INTERPOLATION_START ("a ${"), x, INTERPOLATION_MIDDLE ("} b ${"), y, INTERPOLATION_END ("} c")
+	INTERPOLATION_START
+	INTERPOLATION_MIDDLE
+	INTERPOLATION_END
 	literal_end
 
 	operator_beg
@@ -77,6 +107,7 @@ const (
 	RBRACK         // ]
 	SEMICOLON      // ;
 	DEFINE         // :=
+	COLON          // :
 	POINTER        // *
 	ADDRESS        // &
 	operator_end
@@ -113,9 +144,11 @@ var tokens = map[TokenType]string{
 	IDENT:      "IDENT",
 	COMMENT:    "COMMENT",
 	INT:        "INT",
+	UINT:       "UINT",
 	FLOAT32:    "FLOAT32",
 	FLOAT64:    "FLOAT64",
 	STRING:     "STRING",
+	CHAR:       "CHAR",
 	BOOLEAN:    "BOOLEAN",
 	ASSIGN:     "=",
 	ADD:        "+",
@@ -145,6 +178,12 @@ var tokens = map[TokenType]string{
 	MUL_ASSIGN: "*=",
 	QUO_ASSIGN: "/=",
 	REM_ASSIGN: "%=",
+	AND_ASSIGN: "&=",
+	OR_ASSIGN:  "|=",
+	XOR_ASSIGN: "^=",
+	SHL_ASSIGN: "<<=",
+	SHR_ASSIGN: ">>=",
+	SPACESHIP:  "<=>",
 	IMPORT:     "import",
 	LOR:        "||",
 	LAND:       "&&",
@@ -157,6 +196,7 @@ var tokens = map[TokenType]string{
 	FN:         "fn",
 	VAR:        "var",
 	IF:         "if",
+	FOR:        "for",
 	ELSE:       "else",
 	WHILE:      "while",
 	RETURN:     "return",
@@ -176,32 +216,72 @@ var tokens = map[TokenType]string{
 	MAIN:       "main",
 	BREAK:      "break",
 	REM:        "%",
+
+	AND_NOT_ASSIGN: "&^=",
+	COLON:          ":",
+
+	INTERPOLATION_START:  "INTERPOLATION_START",
+	INTERPOLATION_MIDDLE: "INTERPOLATION_MIDDLE",
+	INTERPOLATION_END:    "INTERPOLATION_END",
 }
 
 func GetUnaryOperatorPrecedence(tok Token) int {
 	switch tokens[tok.Type] {
-	case "+", "-", "!":
+	case "+", "-", "!", "^", "*", "&":
 		return 6 // always one higher than the highest binary operator
 	default:
 		return 0
 	}
 }
 
+// Associativity tells us how a chain of operators with the same precedence is grouped
+type Associativity int
+
+const (
+	LeftAssociative  Associativity = iota // a - b - c is (a - b) - c
+	RightAssociative                      // a op b op c is a op (b op c)
+)
+
+// binaryOperator is how tightly a binary operator binds and which way it groups
+type binaryOperator struct {
+	precedence    int
+	associativity Associativity
+}
+
+// the binary operators, Go's five levels plus our own (<=> compares like ==)
+var binaryOperators = map[string]binaryOperator{
+	"*":  {5, LeftAssociative},
+	"/":  {5, LeftAssociative},
+	"%":  {5, LeftAssociative},
+	"<<": {5, LeftAssociative},
+	">>": {5, LeftAssociative},
+	"&":  {5, LeftAssociative},
+	"&^": {5, LeftAssociative},
+
+	"+": {4, LeftAssociative},
+	"-": {4, LeftAssociative},
+	"|": {4, LeftAssociative},
+	"^": {4, LeftAssociative},
+
+	"==":  {3, LeftAssociative},
+	"!=":  {3, LeftAssociative},
+	"<":   {3, LeftAssociative},
+	"<=":  {3, LeftAssociative},
+	">":   {3, LeftAssociative},
+	">=":  {3, LeftAssociative},
+	"<=>": {3, LeftAssociative},
+
+	"&&": {2, LeftAssociative},
+
+	"||": {1, LeftAssociative},
+}
+
 func GetBinaryOperatorPrecedence(tok Token) int {
-	switch tokens[tok.Type] {
-	case "*", "/", "%":
-		return 5
-	case "+", "-":
-		return 4
-	case "==", "!=", "<", ">", "<=", ">=", "<<", ">>":
-		return 3
-	case "&":
-		return 2
-	case "|", "^":
-		return 1
-	default:
-		return 0
-	}
+	return binaryOperators[tokens[tok.Type]].precedence // 0 if it's not a binary operator
+}
+
+func GetBinaryOperatorAssociativity(tok Token) Associativity {
+	return binaryOperators[tokens[tok.Type]].associativity
 }
 
 const (
@@ -212,9 +292,9 @@ const (
 
 func (t Token) String(pretty bool) string {
 	if !pretty {
-		return fmt.Sprintf("Token { value: %s, kind: %s, position: (%d, %d), real: %v}", t.Literal, t.Type, t.Span.StartLine, t.Span.StartColumn, t.RealValue)
+		return fmt.Sprintf("Token { value: %s, kind: %s, position: (%d, %d), real: %v}", t.Literal, t.Type, t.Span().StartLine, t.Span().StartColumn, t.RealValue)
 	} else {
-		return fmt.Sprintf("Token { \n\tvalue: %s, \n\tkind: %s, \n\tposition: (L%d, SC%d, EC%d, Len %d)\n}", t.Literal, t.Type, t.Span.StartLine, t.Span.StartColumn, t.Span.EndColumn, t.Span.EndIndex-t.Span.StartIndex)
+		return fmt.Sprintf("Token { \n\tvalue: %s, \n\tkind: %s, \n\tposition: (L%d, SC%d, EC%d, Len %d)\n}", t.Literal, t.Type, t.Span().StartLine, t.Span().StartColumn, t.Span().EndColumn, t.End-t.Pos)
 	}
 }
 
@@ -223,10 +303,7 @@ func (t Token) String(pretty bool) string {
 // is LowestPrecedence.
 
 func (tok TokenType) String() string {
-	s := ""
-	if 0 <= tok && tok < TokenType(len(tokens)) {
-		s = tokens[tok]
-	}
+	s := tokens[tok]
 	if s == "" {
 		s = "token(" + strconv.Itoa(int(tok)) + ")"
 	}
@@ -235,11 +312,36 @@ func (tok TokenType) String() string {
 
 var keywords map[string]TokenType
 
+var operators map[string]TokenType
+
+// MaxOperatorLength is the length of the longest operator in characters
+var MaxOperatorLength int
+
 func init() {
 	keywords = make(map[string]TokenType)
 	for tok := keyword_beg + 1; tok < keyword_end; tok++ {
 		keywords[tokens[tok]] = tok
 	}
+
+	operators = make(map[string]TokenType)
+	for tok := operator_beg + 1; tok < operator_end; tok++ {
+		op := tokens[tok]
+
+		// some operators share their text (* is MUL and POINTER), the first one wins
+		// and the parser decides what it means
+		if _, exists := operators[op]; exists || op == "" {
+			continue
+		}
+
+		operators[op] = tok
+		MaxOperatorLength = max(MaxOperatorLength, len(op))
+	}
+}
+
+// LookupOperator finds the operator (or delimiter) spelled exactly like op
+func LookupOperator(op string) (TokenType, bool) {
+	tok, isOperator := operators[op]
+	return tok, isOperator
 }
 
 func LookupIdent(ident string) TokenType {
@@ -254,29 +356,69 @@ func CreateToken(literal string, Type TokenType) Token {
 		Type:      Type,
 		Literal:   literal,
 		RealValue: nil,
-		Span:      print2.TextSpan{},
+		Pos:       NoPos,
+		End:       NoPos,
 	}
 }
 
-func CreateTokenSpaced(literal string, Type TokenType, spaced bool, span print2.TextSpan) Token {
+func CreateTokenSpaced(literal string, Type TokenType, spaced bool, pos Pos, end Pos) Token {
 	return Token{
 		Type:       Type,
 		Literal:    literal,
 		RealValue:  nil,
 		SpaceAfter: spaced,
-		Span:       span,
+		Pos:        pos,
+		End:        end,
 	}
 }
 
-func CreateTokenReal(buffer string, real interface{}, Type TokenType, span print2.TextSpan) Token {
+func CreateTokenReal(buffer string, real interface{}, Type TokenType, pos Pos, end Pos) Token {
 	return Token{
 		Type:      Type,
 		Literal:   buffer,
 		RealValue: real,
-		Span:      span,
+		Pos:       pos,
+		End:       end,
 	}
 }
 
+// Text returns the source text of the token.
+// Tokens that aren't in the source (inserted semicolons and EOF) don't have any
+func (t Token) Text() string {
+	if t.Type == EOF || (t.Type == SEMICOLON && t.Literal == "\n") {
+		return ""
+	}
+	return t.Literal
+}
+
+// Name returns the name of an identifier in NFC, two identifiers are the same
+// if their names are equal even if they're written differently (see the Literal)
+func (t Token) Name() string {
+	if name, ok := t.RealValue.(string); ok && t.Type == IDENT {
+		return name
+	}
+	return t.Literal
+}
+
+// FullText returns the source text of the token together with its trivia,
+// putting the full text of all tokens together gives back the source code byte for byte
+func (t Token) FullText() string {
+	var text strings.Builder
+	for _, trivia := range t.LeadingTrivia {
+		text.WriteString(trivia.Text)
+	}
+	text.WriteString(t.Text())
+	for _, trivia := range t.TrailingTrivia {
+		text.WriteString(trivia.Text)
+	}
+	return text.String()
+}
+
+// Span decodes the position of the token (using the default file set)
+func (t Token) Span() print2.TextSpan {
+	return Files.Span(t.Pos, t.End)
+}
+
 // IsLiteral returns true for tokens corresponding to identifiers
 // and basic type literals; it returns false otherwise.
 func (tok TokenType) IsLiteral() bool { return literal_beg < tok && tok < literal_end }